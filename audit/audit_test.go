@@ -0,0 +1,240 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	contractconfig "github.com/herhe-com/framework/contracts/config"
+	"github.com/herhe-com/framework/contracts/database"
+	"github.com/herhe-com/framework/contracts/http/request"
+	"github.com/herhe-com/framework/facades"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type fakeConfig struct {
+	values map[string]any
+}
+
+func (f fakeConfig) Env(key string, defaultValue ...any) any {
+	return f.Get(key, defaultValue...)
+}
+
+func (f fakeConfig) Add(name string, configuration map[string]any) {}
+
+func (f fakeConfig) Set(key string, configuration any) {
+	f.values[key] = configuration
+}
+
+func (f fakeConfig) Get(key string, defaultValue ...any) any {
+	if value, ok := f.values[key]; ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetString(key string, defaultValue ...string) string {
+	if value, ok := f.values[key]; ok {
+		return fmt.Sprint(value)
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return ""
+}
+
+func (f fakeConfig) GetStrings(key string, defaultValue ...[]string) []string {
+	if value, ok := f.values[key].([]string); ok {
+		return value
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetMaps(key string, defaultValue ...map[string]any) map[string]any {
+	return nil
+}
+
+func (f fakeConfig) GetInt(key string, defaultValue ...int) int {
+	return 0
+}
+
+func (f fakeConfig) GetInt64(key string, defaultValue ...int64) int64 {
+	return 0
+}
+
+func (f fakeConfig) GetBool(key string, defaultValue ...bool) bool {
+	return false
+}
+
+func (f fakeConfig) IsSet(key string) bool {
+	_, ok := f.values[key]
+	return ok
+}
+
+type fakeDatabase struct {
+	db *gorm.DB
+}
+
+func (f fakeDatabase) Default() *gorm.DB {
+	return f.db
+}
+
+func (f fakeDatabase) Drivers(driver string, names ...string) (*gorm.DB, error) {
+	return f.db, nil
+}
+
+type memorySink struct {
+	events []*Event
+}
+
+func (m *memorySink) Write(c context.Context, event *Event) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func setup(t *testing.T, values map[string]any) {
+	original := facades.Container()
+	facades.SetContainer(&facades.Services{})
+	t.Cleanup(func() {
+		facades.SetContainer(original)
+	})
+
+	facades.Register[contractconfig.Application](fakeConfig{values: values})
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "audit.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	facades.Register[database.DB](fakeDatabase{db: db})
+}
+
+func TestRecordWithoutSinksIsNoop(t *testing.T) {
+	setup(t, map[string]any{})
+
+	event := &Event{Type: TypeOfLogin}
+
+	if err := Record(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	if Enabled() || event.ID != "" {
+		t.Fatal("expected audit to be disabled without sinks")
+	}
+}
+
+func TestRecordWritesFileAndCustomSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.log")
+
+	setup(t, map[string]any{
+		"audit.sinks": []string{SinkOfFile, "memory"},
+		"audit.file":  path,
+	})
+
+	memory := &memorySink{}
+	Extend("memory", memory)
+	t.Cleanup(func() {
+		sinks.Forget("memory")
+	})
+
+	if err := Record(context.Background(), &Event{Type: TypeOfLoginFailed, Subject: "alice", Metadata: map[string]any{"attempts": 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(memory.events) != 1 || memory.events[0].ID == "" || memory.events[0].CreatedAt.IsZero() {
+		t.Fatalf("expected event with generated id and time, got %+v", memory.events)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	if !scanner.Scan() {
+		t.Fatal("expected one json line in audit file")
+	}
+
+	var event Event
+
+	if err = json.Unmarshal(scanner.Bytes(), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	if event.Type != TypeOfLoginFailed || event.Subject != "alice" || event.ID != memory.events[0].ID {
+		t.Fatalf("unexpected event in file: %+v", event)
+	}
+}
+
+func TestRecordReportsUnknownSink(t *testing.T) {
+	setup(t, map[string]any{"audit.sinks": []string{"unknown"}})
+
+	if err := Record(context.Background(), &Event{Type: TypeOfLogin}); err == nil {
+		t.Fatal("expected error for unknown sink")
+	}
+}
+
+func TestQueryFiltersAndPaginates(t *testing.T) {
+	setup(t, map[string]any{"audit.sinks": []string{SinkOfDatabase}})
+
+	if err := Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+
+	for index, event := range []*Event{
+		{Type: TypeOfLogin, Actor: "u1", Result: ResultOfSuccess, CreatedAt: now.Add(-3 * time.Hour)},
+		{Type: TypeOfPermissionDenied, Actor: "u1", Subject: "user.index", Result: ResultOfDenied, CreatedAt: now.Add(-2 * time.Hour), Metadata: map[string]any{"reason": "policy"}},
+		{Type: TypeOfPermissionDenied, Actor: "u2", Subject: "user.index", Result: ResultOfDenied, CreatedAt: now.Add(-1 * time.Hour)},
+		{Type: TypeOfPermissionDenied, Actor: "u1", Subject: "role.index", Result: ResultOfDenied, CreatedAt: now},
+	} {
+		event.ID = fmt.Sprintf("e%d", index)
+
+		if err := Record(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := Query(ctx, Filter{Type: TypeOfPermissionDenied, Actor: "u1"}, request.Paginate{Page: 1, Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Total != 2 || len(result.Data) != 1 || result.Data[0].ID != "e3" {
+		t.Fatalf("expected newest of two denials on first page, got %+v", result)
+	}
+
+	if result, _ = Query(ctx, Filter{Actor: "u1"}, request.Paginate{Page: 2, Size: 1}); len(result.Data) != 1 || result.Data[0].ID != "e1" {
+		t.Fatalf("expected second page to hold e1, got %+v", result.Data)
+	}
+
+	if result.Data[0].Metadata != `{"reason":"policy"}` {
+		t.Fatalf("expected metadata stored as json, got %q", result.Data[0].Metadata)
+	}
+
+	result, _ = Query(ctx, Filter{Start: now.Add(-150 * time.Minute), End: now.Add(-30 * time.Minute)}, request.Paginate{})
+
+	if result.Total != 2 {
+		t.Fatalf("expected two events in time range, got %d", result.Total)
+	}
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+const (
+	TypeOfLogin              = "auth.login"               // 登录成功
+	TypeOfLoginFailed        = "auth.login_failed"        // 登录失败
+	TypeOfLoginLocked        = "auth.login_locked"        // 账号被锁定期间尝试登录
+	TypeOfTokenRefreshed     = "auth.token_refreshed"     // 刷新 token
+	TypeOfTokenBlacklisted   = "auth.token_blacklisted"   // token 加入黑名单
+	TypeOfTokenRejected      = "auth.token_rejected"      // 已加入黑名单的 token 被拒绝
+	TypeOfPermissionDenied   = "permission.denied"        // 权限校验未通过
+	TypeOfPolicyChanged      = "permission.policy_change" // 权限策略变更
+	TypeOfRoleChanged        = "permission.role_change"   // 角色分配、组织层级变更
+	TypeOfImpersonationStart = "impersonation.start"      // 开始借位
+	TypeOfImpersonationEnd   = "impersonation.end"        // 结束借位
+)
+
+const (
+	ResultOfSuccess = "success"
+	ResultOfFailure = "failure"
+	ResultOfDenied  = "denied"
+)
+
+// Event 审计事件
+type Event struct {
+	ID           string         `json:"id"`
+	Type         string         `json:"type"`
+	Actor        string         `json:"actor,omitempty"`   // 操作人
+	Subject      string         `json:"subject,omitempty"` // 操作对象，如用户、角色、登录账号
+	Platform     uint16         `json:"platform,omitempty"`
+	Organization string         `json:"organization,omitempty"`
+	Result       string         `json:"result,omitempty"`
+	Message      string         `json:"message,omitempty"`
+	IP           string         `json:"ip,omitempty"`
+	Method       string         `json:"method,omitempty"`
+	Path         string         `json:"path,omitempty"`
+	Metadata     map[string]any `json:"metadata,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// Enabled 是否配置了审计日志的存储方式
+func Enabled() bool {
+	return sinks.Enabled()
+}
+
+// Record
+//
+//	@Description: 写入审计事件，按 audit.sinks 依次写入全部存储方式，没有配置时忽略
+//	@param event	审计事件，ID、CreatedAt 为空时自动生成
+func Record(c context.Context, event *Event) error {
+
+	if !sinks.Enabled() || event == nil {
+		return nil
+	}
+
+	if event.ID == "" {
+
+		id := make([]byte, 16)
+
+		if _, err := rand.Read(id); err != nil {
+			return err
+		}
+
+		event.ID = hex.EncodeToString(id)
+	}
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	return sinks.Each(func(sink Sink) error {
+		return sink.Write(c, event)
+	})
+}
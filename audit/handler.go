@@ -0,0 +1,31 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/contracts/http/request"
+	"github.com/herhe-com/framework/http"
+)
+
+// List 分页查询审计日志，挂载时需要自行加上权限校验
+func List(c context.Context, ctx *app.RequestContext) {
+
+	var req struct {
+		Filter
+		request.Paginate
+	}
+
+	if err := ctx.Bind(&req); err != nil {
+		http.BadRequest(ctx, err)
+		return
+	}
+
+	result, err := Query(c, req.Filter, req.Paginate)
+	if err != nil {
+		http.Fail(ctx, "%v", err)
+		return
+	}
+
+	http.Success(ctx, result)
+}
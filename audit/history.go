@@ -0,0 +1,395 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/herhe-com/framework/database/orm/datascope"
+	"github.com/herhe-com/framework/facades"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	ActionOfCreate = "create"
+	ActionOfUpdate = "update"
+	ActionOfDelete = "delete"
+)
+
+// Model 嵌入到 GORM 模型后，创建、更新、删除时自动写入变更历史
+//
+//	type User struct {
+//		ID   uint64
+//		Name string
+//		audit.Model
+//	}
+//
+// 只记录按主键定位的操作，如 db.Save(&user)、db.Model(&user).Updates(...)、db.Delete(&user)；
+// db.Model(&User{}).Where(...).Updates(...) 这类批量操作没有主键，不会记录。
+// 与 cache.Model 同时嵌入时钩子方法会冲突，需要在模型上自行定义钩子并分别调用。
+type Model struct {
+	before map[string]any
+}
+
+func (m *Model) AfterCreate(tx *gorm.DB) error {
+
+	value, ok := current(tx)
+	if !ok {
+		return nil
+	}
+
+	after, err := load(tx, value)
+	if err != nil || after == nil {
+		return err
+	}
+
+	return write(tx, value, ActionOfCreate, nil, after, after)
+}
+
+func (m *Model) BeforeUpdate(tx *gorm.DB) (err error) {
+
+	if value, ok := current(tx); ok {
+		m.before, err = load(tx, value)
+	}
+
+	return err
+}
+
+func (m *Model) AfterUpdate(tx *gorm.DB) error {
+
+	before := m.before
+	m.before = nil
+
+	value, ok := current(tx)
+	if !ok || before == nil {
+		return nil
+	}
+
+	after, err := load(tx, value)
+	if err != nil || after == nil {
+		return err
+	}
+
+	changed := make(map[string]any)
+	previous := make(map[string]any)
+
+	for _, field := range tx.Statement.Schema.Fields {
+
+		// 自动更新时间每次都会变化，不单独作为变更记录
+		if field.DBName == "" || field.AutoUpdateTime > 0 {
+			continue
+		}
+
+		if !equal(before[field.DBName], after[field.DBName]) {
+			previous[field.DBName] = before[field.DBName]
+			changed[field.DBName] = after[field.DBName]
+		}
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	return write(tx, value, ActionOfUpdate, previous, changed, after)
+}
+
+func (m *Model) BeforeDelete(tx *gorm.DB) (err error) {
+
+	if value, ok := current(tx); ok {
+		m.before, err = load(tx, value)
+	}
+
+	return err
+}
+
+func (m *Model) AfterDelete(tx *gorm.DB) error {
+
+	before := m.before
+	m.before = nil
+
+	value, ok := current(tx)
+	if !ok || before == nil {
+		return nil
+	}
+
+	// 删除记录的快照为删除前的数据，恢复时重新写入
+	return write(tx, value, ActionOfDelete, before, nil, before)
+}
+
+// History 模型变更历史
+type History struct {
+	ID        uint64    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Table     string    `gorm:"column:table_name;size:64;not null;index:idx_audit_history_record" json:"table"`
+	Key       string    `gorm:"column:record_key;size:128;not null;index:idx_audit_history_record" json:"key"`
+	Action    string    `gorm:"column:action;size:16;not null" json:"action"`
+	Actor     string    `gorm:"column:actor;size:64;index" json:"actor"`
+	Before    string    `gorm:"column:before_values;type:text" json:"before"` // 变更前的字段，JSON
+	After     string    `gorm:"column:after_values;type:text" json:"after"`   // 变更后的字段，JSON
+	Snapshot  string    `gorm:"column:snapshot;type:text" json:"snapshot"`    // 本次变更后的完整数据，删除时为删除前的数据，JSON
+	CreatedAt time.Time `gorm:"column:created_at;index" json:"created_at"`
+}
+
+func (h *History) TableName() string {
+	return facades.Config().GetString("audit.history.table", "sys_audit_history")
+}
+
+type actorKey struct{}
+
+// WithActor 将操作人写入 context，模型变更历史从 context 中读取操作人
+func WithActor(c context.Context, actor string) context.Context {
+	return context.WithValue(c, actorKey{}, actor)
+}
+
+// ActorOf 获取 context 中的操作人，没有通过 WithActor 设置时使用数据权限的主体
+func ActorOf(c context.Context) string {
+
+	if c == nil {
+		return ""
+	}
+
+	if actor, ok := c.Value(actorKey{}).(string); ok {
+		return actor
+	}
+
+	if subject, ok := datascope.SubjectOf(c); ok {
+		return subject.ID
+	}
+
+	return ""
+}
+
+// Timeline
+//
+//	@Description: 按时间顺序获取一条数据的变更历史
+//	@param db	模型所在的数据库连接，变更历史与模型写在同一个连接中，如 facades.Database().Default().WithContext(c)
+//	@param model	模型指针，用于确定数据表，如 &User{}
+//	@param key	主键，复合主键按字段顺序用 - 连接
+func Timeline(db *gorm.DB, model any, key any) (histories []History, err error) {
+
+	table, err := tableOf(db, model)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Session(&gorm.Session{NewDB: true}).
+		Where(&History{Table: table, Key: fmt.Sprint(key)}).
+		Order("id asc").
+		Find(&histories).Error
+
+	return histories, err
+}
+
+// Restore
+//
+//	@Description: 将数据恢复为某次变更后的版本（删除记录恢复为删除前的版本），已删除的数据会重新写入
+//	@param db	模型所在的数据库连接或事务
+//	@param model	模型指针，恢复后的数据写入其中，如 &User{}
+//	@param id	变更历史 ID
+func Restore(db *gorm.DB, model any, id uint64) error {
+
+	db = db.Session(&gorm.Session{NewDB: true})
+
+	c := db.Statement.Context
+
+	var history History
+
+	if err := db.First(&history, id).Error; err != nil {
+		return err
+	}
+
+	stmt := &gorm.Statement{DB: db}
+
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+
+	if history.Snapshot == "" {
+		return fmt.Errorf("history %d has no snapshot", id)
+	}
+
+	if history.Table != stmt.Schema.Table {
+		return fmt.Errorf("history %d belongs to %s, not %s", id, history.Table, stmt.Schema.Table)
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(history.Snapshot))
+	decoder.UseNumber()
+
+	var snapshot map[string]any
+
+	if err := decoder.Decode(&snapshot); err != nil {
+		return err
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(model))
+
+	for _, field := range stmt.Schema.Fields {
+
+		item, ok := snapshot[field.DBName]
+		if !ok || field.DBName == "" {
+			continue
+		}
+
+		// 大整数按字符串交给 GORM 转换，避免精度丢失
+		if number, ok := item.(json.Number); ok {
+			item = number.String()
+		}
+
+		if err := field.Set(c, value, item); err != nil {
+			return fmt.Errorf("restore %s: %w", field.DBName, err)
+		}
+	}
+
+	// 软删除的数据同样需要恢复，Save 在数据不存在时会重新创建
+	return db.Unscoped().Save(model).Error
+}
+
+// current 当前钩子对应的数据，批量操作时按 CurDestIndex 定位
+func current(tx *gorm.DB) (reflect.Value, bool) {
+
+	if tx.Statement.Schema == nil || len(tx.Statement.Schema.PrimaryFields) == 0 {
+		return reflect.Value{}, false
+	}
+
+	value := tx.Statement.ReflectValue
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		if tx.Statement.CurDestIndex >= value.Len() {
+			return reflect.Value{}, false
+		}
+		value = reflect.Indirect(value.Index(tx.Statement.CurDestIndex))
+	case reflect.Struct:
+	default:
+		return reflect.Value{}, false
+	}
+
+	for _, field := range tx.Statement.Schema.PrimaryFields {
+		if _, zero := field.ValueOf(tx.Statement.Context, value); zero {
+			return reflect.Value{}, false
+		}
+	}
+
+	return value, true
+}
+
+// load 按主键从数据库读取当前数据，包括已软删除的数据
+func load(tx *gorm.DB, value reflect.Value) (map[string]any, error) {
+
+	sch := tx.Statement.Schema
+
+	conditions := make([]clause.Expression, 0, len(sch.PrimaryFields))
+
+	for _, field := range sch.PrimaryFields {
+		item, _ := field.ValueOf(tx.Statement.Context, value)
+		conditions = append(conditions, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: item})
+	}
+
+	record := reflect.New(sch.ModelType)
+
+	err := tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+		Table(tx.Statement.Table).
+		Unscoped().
+		Clauses(clause.Where{Exprs: conditions}).
+		Take(record.Interface()).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return columns(tx.Statement.Context, sch, record.Elem()), nil
+}
+
+// columns 按字段名取出数据，实现 driver.Valuer 的字段转为数据库中的值
+func columns(c context.Context, sch *schema.Schema, value reflect.Value) map[string]any {
+
+	values := make(map[string]any, len(sch.DBNames))
+
+	for _, field := range sch.Fields {
+
+		if field.DBName == "" {
+			continue
+		}
+
+		item, _ := field.ValueOf(c, value)
+
+		if valuer, ok := item.(driver.Valuer); ok {
+			item, _ = valuer.Value()
+		}
+
+		if data, ok := item.([]byte); ok {
+			item = string(data)
+		}
+
+		values[field.DBName] = item
+	}
+
+	return values
+}
+
+func write(tx *gorm.DB, value reflect.Value, action string, before, after, snapshot map[string]any) error {
+
+	history := &History{
+		Table:  tx.Statement.Table,
+		Key:    key(tx, value),
+		Action: action,
+		Actor:  ActorOf(tx.Statement.Context),
+	}
+
+	for target, data := range map[*string]map[string]any{&history.Before: before, &history.After: after, &history.Snapshot: snapshot} {
+
+		if data == nil {
+			continue
+		}
+
+		body, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+
+		*target = string(body)
+	}
+
+	// 与模型使用同一个事务，模型操作回滚时变更历史一起回滚
+	return tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Create(history).Error
+}
+
+func key(tx *gorm.DB, value reflect.Value) string {
+
+	keys := make([]string, 0, len(tx.Statement.Schema.PrimaryFields))
+
+	for _, field := range tx.Statement.Schema.PrimaryFields {
+		item, _ := field.ValueOf(tx.Statement.Context, value)
+		keys = append(keys, fmt.Sprint(item))
+	}
+
+	return strings.Join(keys, "-")
+}
+
+func equal(a, b any) bool {
+
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+
+	return bytes.Equal(x, y)
+}
+
+func tableOf(db *gorm.DB, model any) (string, error) {
+
+	stmt := &gorm.Statement{DB: db}
+
+	if err := stmt.Parse(model); err != nil {
+		return "", err
+	}
+
+	return stmt.Schema.Table, nil
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type account struct {
+	ID      uint64 `gorm:"primaryKey"`
+	Name    string
+	Balance int64
+	Model
+	DeletedAt gorm.DeletedAt
+}
+
+func TestModelRecordsTimelineAndRestores(t *testing.T) {
+	setup(t, map[string]any{})
+
+	if err := DB().AutoMigrate(&account{}, &History{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithActor(context.Background(), "u1")
+	db := DB().WithContext(ctx)
+
+	item := account{Name: "alice", Balance: 10}
+
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Model(&item).Updates(map[string]any{"name": "bob", "balance": 20}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有变化的更新不记录
+	if err := db.Save(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Delete(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	histories, err := Timeline(db, &account{}, item.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(histories) != 3 {
+		t.Fatalf("expected create, update and delete, got %+v", histories)
+	}
+
+	for index, action := range []string{ActionOfCreate, ActionOfUpdate, ActionOfDelete} {
+		if histories[index].Action != action || histories[index].Actor != "u1" {
+			t.Fatalf("unexpected history %d: %+v", index, histories[index])
+		}
+	}
+
+	var before, after map[string]any
+
+	_ = json.Unmarshal([]byte(histories[1].Before), &before)
+	_ = json.Unmarshal([]byte(histories[1].After), &after)
+
+	if len(after) != 2 || before["name"] != "alice" || after["name"] != "bob" || after["balance"] != float64(20) {
+		t.Fatalf("expected only changed columns in diff, got %v -> %v", before, after)
+	}
+
+	// 恢复到创建时的版本，同时撤销软删除
+	var restored account
+
+	if err = Restore(db, &restored, histories[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var current account
+
+	if err = DB().First(&current, item.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if current.Name != "alice" || current.Balance != 10 {
+		t.Fatalf("expected first version restored, got %+v", current)
+	}
+
+	if histories, _ = Timeline(db, &account{}, item.ID); len(histories) != 4 || histories[3].Action != ActionOfUpdate {
+		t.Fatalf("expected restore to be recorded as an update, got %+v", histories)
+	}
+}
+
+func TestModelRollsBackHistoryWithTransaction(t *testing.T) {
+	setup(t, map[string]any{})
+
+	if err := DB().AutoMigrate(&account{}, &History{}); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&account{Name: "carol"}).Error; err != nil {
+			return err
+		}
+		return gorm.ErrInvalidTransaction
+	})
+
+	var count int64
+
+	DB().Model(&History{}).Count(&count)
+
+	if count != 0 {
+		t.Fatalf("expected history rolled back, got %d", count)
+	}
+}
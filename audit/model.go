@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/herhe-com/framework/contracts/http/request"
+	"github.com/herhe-com/framework/contracts/http/response"
+	"github.com/herhe-com/framework/facades"
+	"gorm.io/gorm"
+)
+
+// Log 审计日志数据表
+type Log struct {
+	ID           string    `gorm:"column:id;primaryKey;size:32" json:"id"`
+	Type         string    `gorm:"column:type;size:64;index;not null" json:"type"`
+	Actor        string    `gorm:"column:actor;size:64;index" json:"actor"`
+	Subject      string    `gorm:"column:subject;size:128;index" json:"subject"`
+	Platform     uint16    `gorm:"column:platform" json:"platform"`
+	Organization string    `gorm:"column:organization;size:64" json:"organization"`
+	Result       string    `gorm:"column:result;size:16" json:"result"`
+	Message      string    `gorm:"column:message;size:255" json:"message"`
+	IP           string    `gorm:"column:ip;size:64" json:"ip"`
+	Method       string    `gorm:"column:method;size:16" json:"method"`
+	Path         string    `gorm:"column:path;size:255" json:"path"`
+	Metadata     string    `gorm:"column:metadata;type:text" json:"metadata"` // JSON
+	CreatedAt    time.Time `gorm:"column:created_at;index" json:"created_at"`
+}
+
+func (l *Log) TableName() string {
+	return facades.Config().GetString("audit.table", "sys_audit_log")
+}
+
+// Migrate 创建审计日志表、模型变更历史表
+func Migrate() error {
+	return DB().AutoMigrate(&Log{}, &History{})
+}
+
+// DB 审计日志所在的数据库连接
+func DB() *gorm.DB {
+	return facades.Database().Default()
+}
+
+func toLog(event *Event) (*Log, error) {
+
+	log := &Log{
+		ID:           event.ID,
+		Type:         event.Type,
+		Actor:        event.Actor,
+		Subject:      event.Subject,
+		Platform:     event.Platform,
+		Organization: event.Organization,
+		Result:       event.Result,
+		Message:      event.Message,
+		IP:           event.IP,
+		Method:       event.Method,
+		Path:         event.Path,
+		CreatedAt:    event.CreatedAt,
+	}
+
+	if len(event.Metadata) > 0 {
+
+		metadata, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Metadata = string(metadata)
+	}
+
+	return log, nil
+}
+
+// Filter 审计日志查询条件，空值忽略
+type Filter struct {
+	Type    string    `form:"type" json:"type" query:"type"`
+	Actor   string    `form:"actor" json:"actor" query:"actor"`
+	Subject string    `form:"subject" json:"subject" query:"subject"`
+	Result  string    `form:"result" json:"result" query:"result"`
+	Start   time.Time `form:"start" json:"start" query:"start"`
+	End     time.Time `form:"end" json:"end" query:"end"`
+}
+
+// Query
+//
+//	@Description: 分页查询 database 存储方式写入的审计日志，按时间倒序
+//	@param filter	查询条件
+//	@param paginate	分页参数
+func Query(c context.Context, filter Filter, paginate request.Paginate) (*response.Paginate[Log], error) {
+
+	tx := DB().WithContext(c).Model(&Log{})
+
+	// 结构体条件会忽略空值
+	tx = tx.Where(&Log{Type: filter.Type, Actor: filter.Actor, Subject: filter.Subject, Result: filter.Result})
+
+	if !filter.Start.IsZero() {
+		tx = tx.Where("created_at >= ?", filter.Start)
+	}
+
+	if !filter.End.IsZero() {
+		tx = tx.Where("created_at < ?", filter.End)
+	}
+
+	result := &response.Paginate[Log]{
+		Page: paginate.GetPage(),
+		Size: paginate.GetSize(),
+		Data: make([]Log, 0),
+	}
+
+	if err := tx.Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+
+	if result.Total > 0 {
+		if err := tx.Order("created_at desc").Order("id desc").Limit(paginate.GetLimit()).Offset(paginate.GetOffset()).Find(&result.Data).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
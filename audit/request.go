@@ -0,0 +1,24 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// RecordRequest 补充请求的 IP、方法、路径后写入审计事件，写入失败只记录错误日志，不影响请求
+func RecordRequest(c context.Context, ctx *app.RequestContext, event *Event) {
+
+	if !Enabled() {
+		return
+	}
+
+	event.IP = ctx.ClientIP()
+	event.Method = string(ctx.Request.Header.Method())
+	event.Path = string(ctx.Request.URI().Path())
+
+	if err := Record(c, event); err != nil {
+		hlog.CtxErrorf(c, "failed to record audit event %s: %v", event.Type, err)
+	}
+}
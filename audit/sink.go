@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support/file"
+	"github.com/herhe-com/framework/support/registry"
+)
+
+const (
+	SinkOfDatabase = "database"
+	SinkOfFile     = "file"
+	SinkOfQueue    = "queue"
+)
+
+// Sink 审计事件的存储方式
+type Sink interface {
+	Write(c context.Context, event *Event) error
+}
+
+var sinks = registry.New[Sink]("audit.sinks", "audit sink", map[string]Sink{
+	SinkOfDatabase: DatabaseSink{},
+	SinkOfFile:     files,
+	SinkOfQueue:    QueueSink{},
+})
+
+// Extend 注册自定义存储方式，名称可以在 audit.sinks 中使用，同名时覆盖内置存储方式
+func Extend(name string, sink Sink) {
+	sinks.Extend(name, sink)
+}
+
+// SinkOf 按名称获取存储方式
+func SinkOf(name string) (Sink, error) {
+	return sinks.Of(name)
+}
+
+// DatabaseSink 写入 audit.table 数据表，Query 只能查询该存储方式
+type DatabaseSink struct {
+}
+
+func (DatabaseSink) Write(c context.Context, event *Event) error {
+
+	log, err := toLog(event)
+	if err != nil {
+		return err
+	}
+
+	return DB().WithContext(c).Create(log).Error
+}
+
+// FileSink 以 JSON Lines 格式追加到 audit.file，相对路径基于项目根目录
+type FileSink struct {
+	lines file.Lines
+}
+
+var files = &FileSink{}
+
+func (s *FileSink) Write(c context.Context, event *Event) error {
+	return s.lines.Append(facades.Config().GetString("audit.file", "storage/logs/audit.log"), event)
+}
+
+// QueueSink 通过 facades.Queue() 发送到 audit.queue.exchange / audit.queue.queue
+type QueueSink struct {
+}
+
+func (QueueSink) Write(c context.Context, event *Event) error {
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	exchange := facades.Config().GetString("audit.queue.exchange", "audit")
+	queue := facades.Config().GetString("audit.queue.queue", "audit")
+	route := facades.Config().GetString("audit.queue.route", "audit")
+
+	return facades.Queue().Producer(body, exchange, queue, []string{route}, 0, 0)
+}
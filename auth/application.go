@@ -2,6 +2,7 @@ package auth
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/casbin/casbin/v3"
 	adapter "github.com/casbin/gorm-adapter/v3"
@@ -24,12 +25,34 @@ func NewApplication() error {
 		return err
 	}
 
-	enforcer, err := casbin.NewEnforcer(facades.Root()+"/conf/casbin.conf", a)
+	if err = checkPolicies(facades.Database().Default(), tableOfCasbin(prefix, table)); err != nil {
+		return err
+	}
+
+	enforcer, err := NewEnforcer(a)
 	if err != nil {
 		return err
 	}
 
-	facades.Register[*casbin.Enforcer](enforcer)
+	facades.Register[*casbin.SyncedEnforcer](enforcer)
 
 	return toTrees()
 }
+
+// tableOfCasbin 与 gorm-adapter 生成表名的规则一致
+func tableOfCasbin(prefix, table string) string {
+
+	if table == "" {
+		table = "casbin_rule"
+	}
+
+	if prefix == "" {
+		return table
+	}
+
+	if strings.HasSuffix(prefix, "_") {
+		return prefix + table
+	}
+
+	return prefix + "_" + table
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/casbin/casbin/v3"
+	"github.com/casbin/casbin/v3/model"
+	"github.com/casbin/casbin/v3/persist"
+	"github.com/herhe-com/framework/facades"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// Model 框架默认的 Casbin 模型
+//
+//	p = sub, obj, dom		主体在作用域内拥有的权限，作用域见 Domain
+//	g = _, _, _			主体在作用域内拥有的角色，角色之间可以继承
+//	g2 = _, _			组织层级，下级组织继承上级组织的授权
+//
+//go:embed casbin.conf
+var Model string
+
+// DomainOfAll 适用于全部平台的作用域
+const DomainOfAll = "*"
+
+var (
+	ErrOutdatedModel  = errors.New("casbin model is outdated, p must be (sub, obj, dom) and g must be (_, _, _)")
+	ErrOutdatedPolicy = errors.New("casbin policies without domain are found")
+)
+
+// NewEnforcer
+//
+//	@Description: 创建 Casbin 执行器，优先使用 auth.casbin.model 指定的模型文件，其次是项目的 conf/casbin.conf，都不存在时使用框架默认模型；
+//	watcher 在其他 goroutine 中重新加载策略，因此使用带读写锁的 SyncedEnforcer
+//	@param adapter	策略存储
+func NewEnforcer(adapter persist.Adapter) (*casbin.SyncedEnforcer, error) {
+
+	m, err := loadModel()
+	if err != nil {
+		return nil, err
+	}
+
+	enforcer, err := casbin.NewSyncedEnforcer(m, adapter)
+	if err != nil {
+		return nil, err
+	}
+
+	enforcer.AddFunction("domainMatch", func(args ...any) (any, error) {
+
+		request, _ := lo.Nth(args, 0)
+		pattern, _ := lo.Nth(args, 1)
+
+		return DomainMatch(toString(request), toString(pattern)), nil
+	})
+
+	enforcer.AddNamedDomainMatchingFunc("g", "domainMatch", DomainMatch)
+
+	return enforcer, nil
+}
+
+// DomainMatch
+//
+//	@Description: 判断请求作用域是否落在策略作用域内：* 匹配全部，平台作用域（如 999）匹配该平台下的全部组织
+//	@param request	请求作用域，如 999:10001
+//	@param pattern	策略作用域
+func DomainMatch(request, pattern string) bool {
+
+	if pattern == DomainOfAll || request == pattern {
+		return true
+	}
+
+	return !strings.Contains(pattern, ":") && strings.HasPrefix(request, pattern+":")
+}
+
+// Domains 作用域及其全部上级组织的作用域，越靠前越具体
+func Domains(platform uint16, organization ...string) []string {
+
+	domain := Domain(platform, organization...)
+
+	domains := []string{domain}
+
+	if facades.Casbin().GetNamedRoleManager("g2") == nil {
+		return domains
+	}
+
+	// SyncedEnforcer 没有为该方法加锁，需要与 LoadPolicy 互斥
+	enforcer := facades.Casbin()
+
+	enforcer.GetLock().RLock()
+	parents, err := enforcer.GetNamedImplicitRolesForUser("g2", domain)
+	enforcer.GetLock().RUnlock()
+
+	if err == nil {
+		domains = append(domains, parents...)
+	}
+
+	return lo.Uniq(domains)
+}
+
+// Enforce
+//
+//	@Description: 校验主体在平台（或平台下某个组织）是否拥有权限，上级组织中的授权对下级组织同样有效；配置了 auth.casbin.cache 时缓存决策结果
+//	@param subject	已命名的主体，如 NameOfUser(id)
+//	@param permission	权限码
+func Enforce(subject, permission string, platform uint16, organization ...string) (bool, error) {
+
+	lifetime := decisionLifetime()
+
+	key := permission + "@" + Domain(platform, organization...)
+
+	cached, generation, ok := decisions.get(subject, key)
+
+	if lifetime > 0 && ok {
+		return cached, nil
+	}
+
+	allowed, err := enforce(subject, permission, platform, organization...)
+	if err != nil {
+		return false, err
+	}
+
+	if lifetime > 0 {
+		decisions.put(subject, key, allowed, generation, lifetime)
+	}
+
+	return allowed, nil
+}
+
+func enforce(subject, permission string, platform uint16, organization ...string) (bool, error) {
+
+	for _, domain := range Domains(platform, organization...) {
+
+		ok, err := facades.Casbin().Enforce(subject, permission, domain)
+
+		if err != nil {
+			return false, err
+		} else if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// IsDeveloper 主体是否在全部作用域拥有开发者角色
+func IsDeveloper(subject string) bool {
+
+	ok, _ := facades.Casbin().HasRoleForUser(subject, NameOfDeveloper(), DomainOfAll)
+
+	return ok
+}
+
+func loadModel() (model.Model, error) {
+
+	if path := facades.Config().GetString("auth.casbin.model"); path != "" {
+		return loadModelFromFile(path)
+	}
+
+	if root, ok := facades.Get[facades.RootPath](); ok {
+
+		path := string(root) + "/conf/casbin.conf"
+
+		if _, err := os.Stat(path); err == nil {
+			return loadModelFromFile(path)
+		}
+	}
+
+	return model.NewModelFromString(Model)
+}
+
+func loadModelFromFile(path string) (model.Model, error) {
+
+	m, err := model.NewModelFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkModel(m); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, path)
+	}
+
+	return m, nil
+}
+
+// checkModel 自定义模型必须与内置模型的策略格式一致，旧模型（不带作用域）继续使用时 Enforce、IsDeveloper 会全部失败，启动时直接报错
+func checkModel(m model.Model) error {
+
+	p, ok := m["p"]["p"]
+	if !ok || len(p.Tokens) != 3 {
+		return ErrOutdatedModel
+	}
+
+	g, ok := m["g"]["g"]
+	if !ok || strings.Count(g.Value, "_") != 3 {
+		return ErrOutdatedModel
+	}
+
+	return nil
+}
+
+// MigratePolicies
+//
+//	@Description: 将旧格式的策略 (sub, obj) 与角色 (sub, role) 迁移到全部作用域 *，与迁移前不区分平台的行为一致
+//	@param db	策略表所在的数据库连接
+//	@param table	策略表的完整表名
+//	@return count	迁移的记录数
+func MigratePolicies(db *gorm.DB, table string) (count int64, err error) {
+
+	tx := db.Table(table).Where("ptype IN ?", []string{"p", "g"}).Where("v2 = '' OR v2 IS NULL").Update("v2", DomainOfAll)
+
+	return tx.RowsAffected, tx.Error
+}
+
+// checkPolicies 存在旧格式的策略时，配置了 auth.casbin.migrate 则自动迁移，否则启动时直接报错
+func checkPolicies(db *gorm.DB, table string) error {
+
+	var count int64
+
+	if err := db.Table(table).Where("ptype IN ?", []string{"p", "g"}).Where("v2 = '' OR v2 IS NULL").Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	if !facades.Config().GetBool("auth.casbin.migrate") {
+		return fmt.Errorf("%w: %d rules in %s, set auth.casbin.migrate to true or call auth.MigratePolicies", ErrOutdatedPolicy, count, table)
+	}
+
+	_, err := MigratePolicies(db, table)
+
+	return err
+}
+
+func toString(value any) string {
+
+	if value, ok := value.(string); ok {
+		return value
+	}
+
+	return ""
+}
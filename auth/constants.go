@@ -4,6 +4,10 @@ const (
 	JwtOfAuthorization = "Authorization"
 )
 
+const (
+	TokenOfOAuth2 = "oauth2" // OAuth2 服务签发的访问令牌，不校验 jwt.required
+)
+
 const (
 	ContextOfID           = "ID"
 	ContextOfClaims       = "Claims"
@@ -77,7 +77,10 @@ func Clique(ctx *app.RequestContext) (id sql.NullString) {
 func Claims(ctx *app.RequestContext) (claims *contractauth.Claims) {
 
 	if value, exist := ctx.Get(ContextOfClaims); exist {
-		if tmp, ok := value.(contractauth.Claims); ok {
+		switch tmp := value.(type) {
+		case *contractauth.Claims:
+			claims = tmp
+		case contractauth.Claims:
 			claims = &tmp
 		}
 	}
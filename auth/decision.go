@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/herhe-com/framework/facades"
+)
+
+// 每个节点的 Casbin 策略都保存在内存中，决策缓存同样按节点保存，策略变更时由 Watcher 清空
+var decisions = &decisionCache{items: make(map[string]map[string]decision)}
+
+// decisionLimit 缓存的主体数量上限，超过后整体清空
+const decisionLimit = 10000
+
+type decision struct {
+	allowed bool
+	expired time.Time
+}
+
+type decisionCache struct {
+	mutex      sync.RWMutex
+	generation uint64
+	items      map[string]map[string]decision
+}
+
+// Forget 清除主体的权限决策缓存，不传主体时清除全部
+func Forget(subjects ...string) {
+
+	decisions.mutex.Lock()
+	defer decisions.mutex.Unlock()
+
+	decisions.generation++
+
+	if len(subjects) == 0 {
+		decisions.items = make(map[string]map[string]decision)
+		return
+	}
+
+	for _, item := range subjects {
+		delete(decisions.items, item)
+	}
+}
+
+// decisionLifetime 权限决策的缓存时间，auth.casbin.cache 为秒数，0 表示不缓存
+func decisionLifetime() time.Duration {
+	return time.Duration(facades.Config().GetInt("auth.casbin.cache")) * time.Second
+}
+
+func (d *decisionCache) get(subject, key string) (allowed bool, generation uint64, ok bool) {
+
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if item, exist := d.items[subject][key]; exist && time.Now().Before(item.expired) {
+		return item.allowed, d.generation, true
+	}
+
+	return false, d.generation, false
+}
+
+// put 缓存决策，计算期间策略发生过变更时放弃写入，避免缓存旧策略的结果
+func (d *decisionCache) put(subject, key string, allowed bool, generation uint64, lifetime time.Duration) {
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if generation != d.generation {
+		return
+	}
+
+	if _, ok := d.items[subject]; !ok {
+
+		if len(d.items) >= decisionLimit {
+			d.items = make(map[string]map[string]decision)
+		}
+
+		d.items[subject] = make(map[string]decision)
+	}
+
+	d.items[subject][key] = decision{allowed: allowed, expired: time.Now().Add(lifetime)}
+}
@@ -0,0 +1,38 @@
+package impersonation
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/http"
+)
+
+// List 正在进行的借位列表，挂载时需要自行加上权限校验
+func List(c context.Context, ctx *app.RequestContext) {
+
+	sessions, err := Active(c)
+	if err != nil {
+		http.Fail(ctx, "%v", err)
+		return
+	}
+
+	http.Success(ctx, sessions)
+}
+
+// Terminate 强制结束路由参数 actor 对应用户的借位，表单参数 reason 为原因
+func Terminate(c context.Context, ctx *app.RequestContext) {
+
+	actor := ctx.Param("actor")
+
+	if actor == "" {
+		http.BadRequest(ctx, "actor cannot be empty")
+		return
+	}
+
+	if err := ForceEnd(c, actor, Actor(ctx), ctx.PostForm("reason")); err != nil {
+		http.Fail(ctx, "%v", err)
+		return
+	}
+
+	http.Success[any](ctx)
+}
@@ -0,0 +1,232 @@
+package impersonation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/glebarez/sqlite"
+	"github.com/herhe-com/framework/auth"
+	contractauth "github.com/herhe-com/framework/contracts/auth"
+	contractconfig "github.com/herhe-com/framework/contracts/config"
+	"github.com/herhe-com/framework/contracts/database"
+	"github.com/herhe-com/framework/facades"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+type fakeConfig struct {
+	values map[string]any
+}
+
+func (f fakeConfig) Env(key string, defaultValue ...any) any {
+	return f.Get(key, defaultValue...)
+}
+
+func (f fakeConfig) Add(name string, configuration map[string]any) {}
+
+func (f fakeConfig) Set(key string, configuration any) {
+	f.values[key] = configuration
+}
+
+func (f fakeConfig) Get(key string, defaultValue ...any) any {
+	if value, ok := f.values[key]; ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetString(key string, defaultValue ...string) string {
+	if value, ok := f.values[key]; ok {
+		return fmt.Sprint(value)
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return ""
+}
+
+func (f fakeConfig) GetStrings(key string, defaultValue ...[]string) []string {
+	return nil
+}
+
+func (f fakeConfig) GetMaps(key string, defaultValue ...map[string]any) map[string]any {
+	return nil
+}
+
+func (f fakeConfig) GetInt(key string, defaultValue ...int) int {
+	if value, ok := f.values[key].(int); ok {
+		return value
+	}
+
+	return 0
+}
+
+func (f fakeConfig) GetInt64(key string, defaultValue ...int64) int64 {
+	return 0
+}
+
+func (f fakeConfig) GetBool(key string, defaultValue ...bool) bool {
+	return false
+}
+
+func (f fakeConfig) IsSet(key string) bool {
+	_, ok := f.values[key]
+	return ok
+}
+
+type fakeDatabase struct {
+	db *gorm.DB
+}
+
+func (f fakeDatabase) Default() *gorm.DB {
+	return f.db
+}
+
+func (f fakeDatabase) Drivers(driver string, names ...string) (*gorm.DB, error) {
+	return f.db, nil
+}
+
+type fakeRedis struct {
+	client *redis.Client
+}
+
+func (f fakeRedis) Default() *redis.Client {
+	return f.client
+}
+
+func (f fakeRedis) Channel(name string) (*redis.Client, error) {
+	return f.client, nil
+}
+
+func setup(t *testing.T) {
+	original := facades.Container()
+	facades.SetContainer(&facades.Services{})
+	t.Cleanup(func() {
+		facades.SetContainer(original)
+	})
+
+	facades.Register[contractconfig.Application](fakeConfig{values: map[string]any{
+		"app.name":     "framework",
+		"jwt.sub":      "api",
+		"jwt.secret":   "test-secret",
+		"jwt.lifetime": 60,
+	}})
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "impersonation.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	facades.Register[database.DB](fakeDatabase{db: db})
+
+	facades.Register[database.Redis](fakeRedis{client: redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})})
+}
+
+func TestIssueCarriesActorAndKeepsExtensions(t *testing.T) {
+	setup(t)
+
+	token, err := issue("u1", &contractauth.Claims{Ext: map[string]any{"mfa": 100}}, &Session{ID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var claims contractauth.Claims
+
+	if _, err = auth.CheckJWToken(&claims, token); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := app.NewContext(0)
+	ctx.Set(auth.ContextOfID, claims.Subject)
+	ctx.Set(auth.ContextOfClaims, &claims)
+
+	if Actor(ctx) != "u1" || SessionOf(ctx) != "s1" {
+		t.Fatalf("expected actor and session in claims, got %v", claims.Ext)
+	}
+
+	if _, ok := claims.Ext["mfa"]; !ok {
+		t.Fatal("expected existing extensions to be kept")
+	}
+
+	// 结束借位后签发的 token 不再携带借位信息
+	if token, err = issue("u1", &claims, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	claims = contractauth.Claims{}
+
+	if _, err = auth.CheckJWToken(&claims, token); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := claims.Ext[ClaimOfSession]; ok {
+		t.Fatal("expected session claim to be removed")
+	}
+}
+
+func TestStartDoesNotImpersonateWhenLogFails(t *testing.T) {
+	setup(t)
+
+	c := context.Background()
+
+	ctx := app.NewContext(0)
+	ctx.Set(auth.ContextOfID, "u1")
+
+	// 未迁移日志表，写入审计日志失败
+	if _, _, err := Start(c, ctx, auth.CodeOfStore, "s1", "门店", nil, "排查问题"); err == nil {
+		t.Fatal("expected start to fail without the log table")
+	}
+
+	if role, _ := auth.Temporary(c, ctx); role != nil {
+		t.Fatalf("expected no temporary role, got %+v", role)
+	}
+
+	if _, err := Find(c, "u1"); !errors.Is(err, ErrNotImpersonating) {
+		t.Fatalf("expected no session, got %v", err)
+	}
+}
+
+func TestLogsFilterBySessionAndActor(t *testing.T) {
+	setup(t)
+
+	if err := Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	for _, item := range []Log{
+		{Session: "s1", Actor: "u1", Event: EventOfStart},
+		{Session: "s1", Actor: "u1", Event: EventOfRequest, Method: "POST", Path: "/orders"},
+		{Session: "s2", Actor: "u2", Event: EventOfStart},
+	} {
+		if err := Record(ctx, &item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logs, err := Logs(ctx, "s1", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(logs) != 2 || logs[0].Event != EventOfRequest {
+		t.Fatalf("expected session logs in reverse order, got %+v", logs)
+	}
+
+	if logs, _ = Logs(ctx, "", "u2", 0); len(logs) != 1 {
+		t.Fatalf("expected one log for u2, got %+v", logs)
+	}
+}
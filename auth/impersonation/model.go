@@ -0,0 +1,94 @@
+package impersonation
+
+import (
+	"context"
+	"time"
+
+	"github.com/herhe-com/framework/audit"
+	"github.com/herhe-com/framework/facades"
+	"gorm.io/gorm"
+)
+
+const (
+	EventOfStart    = "start"     // 开始借位
+	EventOfEnd      = "end"       // 主动结束借位
+	EventOfForceEnd = "force_end" // 被强制结束
+	EventOfRequest  = "request"   // 借位期间的写请求
+)
+
+// Log 借位审计日志
+type Log struct {
+	ID           uint64    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Session      string    `gorm:"column:session;size:64;index" json:"session"`
+	Actor        string    `gorm:"column:actor;size:64;index" json:"actor"` // 真实操作人
+	Operator     string    `gorm:"column:operator;size:64" json:"operator"` // 强制结束的操作人
+	Event        string    `gorm:"column:event;size:32;not null" json:"event"`
+	Platform     uint16    `gorm:"column:platform" json:"platform"`     // 借位平台
+	Org          string    `gorm:"column:org;size:64;index" json:"org"` // 借位组织
+	Organization string    `gorm:"column:organization;size:64" json:"organization"`
+	Method       string    `gorm:"column:method;size:16" json:"method"`
+	Path         string    `gorm:"column:path;size:255" json:"path"`
+	Status       int       `gorm:"column:status" json:"status"`
+	IP           string    `gorm:"column:ip;size:64" json:"ip"`
+	Reason       string    `gorm:"column:reason;size:255" json:"reason"`
+	CreatedAt    time.Time `gorm:"column:created_at;index" json:"created_at"`
+}
+
+func (l *Log) TableName() string {
+	return facades.Config().GetString("auth.impersonation.table", "sys_impersonation_log")
+}
+
+// Migrate 创建借位审计日志表
+func Migrate() error {
+	return DB().AutoMigrate(&Log{})
+}
+
+// DB 借位审计日志所在的数据库连接
+func DB() *gorm.DB {
+	return facades.Database().Default()
+}
+
+// Record 写入借位审计日志，开始、结束借位同时写入 audit 审计日志
+func Record(c context.Context, log *Log) error {
+
+	if err := DB().WithContext(c).Create(log).Error; err != nil {
+		return err
+	}
+
+	event := &audit.Event{
+		Type:         audit.TypeOfImpersonationStart,
+		Actor:        log.Actor,
+		Subject:      log.Session,
+		Platform:     log.Platform,
+		Organization: log.Org,
+		Result:       audit.ResultOfSuccess,
+		Message:      log.Reason,
+		IP:           log.IP,
+		Metadata:     map[string]any{"event": log.Event},
+	}
+
+	switch log.Event {
+	case EventOfRequest:
+		return nil
+	case EventOfEnd, EventOfForceEnd:
+		event.Type = audit.TypeOfImpersonationEnd
+		if log.Operator != "" {
+			event.Metadata["operator"] = log.Operator
+		}
+	}
+
+	return audit.Record(c, event)
+}
+
+// Logs 按会话或操作人查询借位审计日志，按时间倒序
+func Logs(c context.Context, session, actor string, limit int) (logs []Log, err error) {
+
+	if limit <= 0 {
+		limit = 100
+	}
+
+	// 结构体条件会忽略空值
+	err = DB().WithContext(c).Where(&Log{Session: session, Actor: actor}).Order("id desc").Limit(limit).Find(&logs).Error
+
+	return logs, err
+}
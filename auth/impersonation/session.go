@@ -0,0 +1,373 @@
+package impersonation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/auth"
+	contractauth "github.com/herhe-com/framework/contracts/auth"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cast"
+)
+
+const (
+	ClaimOfActor   = "act" // 真实操作人
+	ClaimOfSession = "imp" // 借位会话
+)
+
+var ErrNotImpersonating = errors.New("not impersonating")
+
+// Session 正在进行的借位
+type Session struct {
+	ID           string    `json:"id"`
+	Actor        string    `json:"actor"`
+	Platform     uint16    `json:"platform"`
+	Org          string    `json:"org"`
+	Organization string    `json:"organization"`
+	Clique       *string   `json:"clique,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Start
+//
+//	@Description: 借用其他组织的角色，记录借位会话和审计日志，并签发带有真实操作人和会话的 token
+//	@param platform	借位平台
+//	@param org	借位组织
+//	@param organization	组织名称
+//	@param clique	所属集团
+//	@param reason	借位原因
+//	@return token	新的 token，后续请求需要使用该 token
+func Start(c context.Context, ctx *app.RequestContext, platform uint16, org, organization string, clique *string, reason string) (token string, session *Session, err error) {
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return "", nil, errors.New("please initialize Redis first")
+	}
+
+	actor := auth.ID(ctx)
+
+	if actor == "" {
+		return "", nil, errors.New("actor cannot be empty")
+	}
+
+	previous, err := auth.Temporary(c, ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	current, _ := Find(c, actor)
+
+	if session, err = create(actor, platform, org, organization, clique, reason); err != nil {
+		return "", nil, err
+	}
+
+	// 先写审计日志，日志写入失败时不借位
+	if err = Record(c, &Log{
+		Session:      session.ID,
+		Actor:        actor,
+		Event:        EventOfStart,
+		Platform:     platform,
+		Org:          org,
+		Organization: organization,
+		IP:           ctx.ClientIP(),
+		Reason:       reason,
+	}); err != nil {
+		return "", nil, err
+	}
+
+	if err = auth.SetTemporaryRole(c, ctx, platform, org, organization, clique, previous); err != nil {
+		return "", nil, err
+	}
+
+	if err = save(c, cache.Default(), session); err != nil {
+		rollback(c, ctx, cache.Default(), previous, current)
+		return "", nil, err
+	}
+
+	if token, err = issue(actor, auth.Claims(ctx), session); err != nil {
+		rollback(c, ctx, cache.Default(), previous, current)
+		return "", nil, err
+	}
+
+	return token, session, nil
+}
+
+// End
+//
+//	@Description: 结束当前借位，存在上一层借位（Bak）时回到上一层
+//	@return token	新的 token，回到上一层时仍然携带借位会话
+func End(c context.Context, ctx *app.RequestContext) (token string, err error) {
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return "", errors.New("please initialize Redis first")
+	}
+
+	actor := auth.ID(ctx)
+
+	role, err := auth.Temporary(c, ctx)
+	if err != nil {
+		return "", err
+	} else if role == nil {
+		return "", ErrNotImpersonating
+	}
+
+	current, _ := Find(c, actor)
+
+	log := &Log{
+		Actor:        actor,
+		Event:        EventOfEnd,
+		Platform:     role.Platform,
+		Org:          role.Org,
+		Organization: role.Organization,
+		IP:           ctx.ClientIP(),
+	}
+
+	if current != nil {
+		log.Session = current.ID
+	}
+
+	if err = Record(c, log); err != nil {
+		return "", err
+	}
+
+	var session *Session
+
+	if back := role.Bak; back != nil {
+
+		if session, err = create(actor, back.Platform, back.Org, back.Organization, back.Clique, ""); err != nil {
+			return "", err
+		}
+
+		if err = auth.SetTemporaryRole(c, ctx, back.Platform, back.Org, back.Organization, back.Clique, back.Bak); err != nil {
+			return "", err
+		}
+
+		err = save(c, cache.Default(), session)
+	} else {
+
+		if err = auth.DeleteTemporaryRole(c, ctx); err != nil {
+			return "", err
+		}
+
+		err = cache.Default().HDel(c, KeyOfSessions(), actor).Err()
+	}
+
+	if err == nil {
+		token, err = issue(actor, auth.Claims(ctx), session)
+	}
+
+	if err != nil {
+		rollback(c, ctx, cache.Default(), role, current)
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ForceEnd
+//
+//	@Description: 强制结束用户的借位，包括全部上层借位，携带该会话的 token 随之失效
+//	@param actor	借位的用户
+//	@param operator	执行操作的用户
+//	@param reason	原因
+func ForceEnd(c context.Context, actor, operator, reason string) error {
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return errors.New("please initialize Redis first")
+	}
+
+	session, err := Find(c, actor)
+	if err != nil {
+		return err
+	}
+
+	// 先写审计日志，日志写入失败时不结束借位
+	if err = Record(c, &Log{
+		Session:      session.ID,
+		Actor:        actor,
+		Operator:     operator,
+		Event:        EventOfForceEnd,
+		Platform:     session.Platform,
+		Org:          session.Org,
+		Organization: session.Organization,
+		Reason:       reason,
+	}); err != nil {
+		return err
+	}
+
+	if err = cache.Default().Del(c, auth.RoleOfName(actor)).Err(); err != nil {
+		return err
+	}
+
+	return cache.Default().HDel(c, KeyOfSessions(), actor).Err()
+}
+
+// Active 正在进行的借位，已过期的会话会被清理
+func Active(c context.Context) ([]Session, error) {
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return nil, errors.New("please initialize Redis first")
+	}
+
+	values, err := cache.Default().HGetAll(c, KeyOfSessions()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	sessions := make([]Session, 0, len(values))
+
+	for actor, value := range values {
+
+		var session Session
+
+		if err = json.Unmarshal([]byte(value), &session); err != nil || now.After(session.ExpiresAt) {
+			cache.Default().HDel(c, KeyOfSessions(), actor)
+			continue
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// Find 用户正在进行的借位
+func Find(c context.Context, actor string) (*Session, error) {
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return nil, errors.New("please initialize Redis first")
+	}
+
+	value, err := cache.Default().HGet(c, KeyOfSessions(), actor).Result()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotImpersonating
+	} else if err != nil {
+		return nil, err
+	}
+
+	var session Session
+
+	if err = json.Unmarshal([]byte(value), &session); err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrNotImpersonating
+	}
+
+	return &session, nil
+}
+
+// Actor token 中记录的真实操作人，没有借位时为当前用户
+func Actor(ctx *app.RequestContext) string {
+
+	if claims := auth.Claims(ctx); claims != nil && claims.Ext != nil {
+		if actor := cast.ToString(claims.Ext[ClaimOfActor]); actor != "" {
+			return actor
+		}
+	}
+
+	return auth.ID(ctx)
+}
+
+// SessionOf token 中记录的借位会话
+func SessionOf(ctx *app.RequestContext) string {
+
+	if claims := auth.Claims(ctx); claims != nil && claims.Ext != nil {
+		return cast.ToString(claims.Ext[ClaimOfSession])
+	}
+
+	return ""
+}
+
+// KeyOfSessions 正在进行的借位，哈希表字段为用户 ID
+func KeyOfSessions() string {
+	return util.Keys("impersonation", "sessions")
+}
+
+func create(actor string, platform uint16, org, organization string, clique *string, reason string) (*Session, error) {
+
+	id := make([]byte, 16)
+
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &Session{
+		ID:           hex.EncodeToString(id),
+		Actor:        actor,
+		Platform:     platform,
+		Org:          org,
+		Organization: organization,
+		Clique:       clique,
+		Reason:       reason,
+		StartedAt:    now,
+		ExpiresAt:    now.Add(auth.TemporaryExpires()),
+	}, nil
+}
+
+func save(c context.Context, client *redis.Client, session *Session) error {
+
+	value, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return client.HSet(c, KeyOfSessions(), session.Actor, value).Err()
+}
+
+// rollback 借位状态只写入了一部分时，恢复到操作前的临时角色和会话
+func rollback(c context.Context, ctx *app.RequestContext, client *redis.Client, role *contractauth.RoleOfTemporary, session *Session) {
+
+	if role != nil {
+		_ = auth.SetTemporaryRole(c, ctx, role.Platform, role.Org, role.Organization, role.Clique, role.Bak)
+	} else {
+		_ = auth.DeleteTemporaryRole(c, ctx)
+	}
+
+	if session != nil {
+		_ = save(c, client, session)
+	} else {
+		_ = client.HDel(c, KeyOfSessions(), auth.ID(ctx)).Err()
+	}
+}
+
+// issue 签发新的 token，保留原 token 的扩展变量，session 为空时移除借位信息
+func issue(actor string, claims *contractauth.Claims, session *Session) (string, error) {
+
+	ext := make(map[string]any)
+
+	if claims != nil {
+		for key, value := range claims.Ext {
+			ext[key] = value
+		}
+	}
+
+	delete(ext, ClaimOfActor)
+	delete(ext, ClaimOfSession)
+
+	if session != nil {
+		ext[ClaimOfActor] = actor
+		ext[ClaimOfSession] = session.ID
+	}
+
+	return auth.NewJWToken(actor, facades.Config().GetInt("jwt.lifetime"), true, ext)
+}
@@ -11,12 +11,16 @@ import (
 	"github.com/dromara/carbon/v2"
 	"github.com/dromara/dongle"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/herhe-com/framework/audit"
 	"github.com/herhe-com/framework/contracts/auth"
 	"github.com/herhe-com/framework/facades"
 	"github.com/redis/go-redis/v9"
 	"github.com/samber/lo"
 )
 
+// ErrScopeUnavailable OAuth2 访问令牌只能在注册了 contracts/auth.Scope 的服务中使用，否则会被当作普通用户令牌
+var ErrScopeUnavailable = errors.New("oauth2 token cannot be used without a scope checker")
+
 const (
 	// MaxBlacklistExpiry is the maximum expiry time for blacklist entries (7 days)
 	MaxBlacklistExpiry = 7 * 24 * time.Hour
@@ -49,24 +53,53 @@ const (
 //
 // NewJWToken
 func NewJWToken(id string, lifetime int, refresh bool, ext map[string]any) (token string, err error) {
+	return MakeJWToken(NewClaims(id, lifetime, refresh, ext))
+}
+
+// NewClaims 与 NewJWToken 相同的声明，需要设置 Type 等字段时修改后再通过 MakeJWToken 签发
+func NewClaims(id string, lifetime int, refresh bool, ext map[string]any) auth.Claims {
 
 	sub := facades.Config().GetString("jwt.sub")
 
 	now := carbon.Now()
 
-	claims := auth.Claims{
+	return auth.Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    Issuer(sub),
 			Subject:   id,
 			IssuedAt:  jwt.NewNumericDate(now.StdTime()),
 			NotBefore: jwt.NewNumericDate(now.StdTime()),
 			ExpiresAt: jwt.NewNumericDate(now.AddMinutes(lifetime).StdTime()),
+			Audience:  Audiences(),
 		},
 		Refresh: refresh,
 		Ext:     ext,
 	}
+}
+
+// Authenticate
+//
+//	@Description: 把校验通过的 claims 写入上下文，未设置平台时使用 auth.platform，Jwt 中间件和 WebSocket 共用
+//	@param claims	CheckJWToken 或 RefreshJWToken 校验通过的 claims
+func Authenticate(ctx *app.RequestContext, claims *auth.Claims) {
+
+	ctx.Set(ContextOfID, claims.Subject)
+	ctx.Set(ContextOfClaims, claims)
+
+	if platform := DefaultPlatform(); platform > 0 && Platform(ctx) == 0 {
+		ctx.Set(ContextOfPlatform, platform)
+	}
+}
+
+// Callback 执行 auth.callback 中配置的回调，如 jwt、refresh，未配置时忽略
+func Callback(c context.Context, ctx *app.RequestContext, name string) {
+
+	if callback := facades.Config().Get("auth.callback." + name); callback != nil {
 
-	return MakeJWToken(claims)
+		if function, ok := callback.(func(c context.Context, ctx *app.RequestContext)); ok {
+			function(c, ctx)
+		}
+	}
 }
 
 func BlacklistOfJwtName(ctx *app.RequestContext) string {
@@ -92,7 +125,19 @@ func BlacklistOfJwtValue(c context.Context, ctx *app.RequestContext) (bool, erro
 		expires = MaxBlacklistExpiry
 	}
 
-	return BlacklistWithRedis(cache, c, now.Timestamp(), expires, "jwt", Claims(ctx).ID), nil
+	ok = BlacklistWithRedis(cache, c, now.Timestamp(), expires, "jwt", Claims(ctx).ID)
+
+	if ok {
+		audit.RecordRequest(c, ctx, &audit.Event{
+			Type:     audit.TypeOfTokenBlacklisted,
+			Actor:    ID(ctx),
+			Subject:  Claims(ctx).ID,
+			Platform: Platform(ctx),
+			Result:   audit.ResultOfSuccess,
+		})
+	}
+
+	return ok, nil
 }
 
 func MakeJWToken(claims auth.Claims, secrets ...string) (token string, err error) {
@@ -162,6 +207,27 @@ func CheckJWToken(claims *auth.Claims, token string, secrets ...string) (refresh
 		return false, jwt.ErrTokenUsedBeforeIssued
 	}
 
+	// Verify audience
+	if audience := facades.Config().GetString("jwt.audience"); audience != "" && !lo.Contains(claims.Audience, audience) {
+		return false, jwt.ErrTokenInvalidAudience
+	}
+
+	// OAuth2 tokens are limited by scope, reject them where scopes cannot be checked
+	if claims.Type == TokenOfOAuth2 {
+		if _, ok := facades.Get[auth.Scope](); !ok {
+			return false, ErrScopeUnavailable
+		}
+	}
+
+	// Verify required custom claims, only for user login tokens
+	if claims.Type != TokenOfOAuth2 {
+		for _, item := range facades.Config().GetStrings("jwt.required") {
+			if value, ok := claims.Ext[item]; !ok || lo.IsNil(value) {
+				return false, fmt.Errorf("%w: %s", jwt.ErrTokenRequiredClaimMissing, item)
+			}
+		}
+	}
+
 	// Verify expiration
 	if claims.ExpiresAt != nil && now.StdTime().After(claims.ExpiresAt.Time) {
 
@@ -276,6 +342,24 @@ func Secret(secrets ...string) (secret string, err error) {
 	return secret, nil
 }
 
+// Audiences 签发 token 时写入的 aud，默认读取 jwt.audiences，未配置时使用当前服务的 jwt.audience
+func Audiences() jwt.ClaimStrings {
+
+	audiences := facades.Config().GetStrings("jwt.audiences")
+
+	if len(audiences) == 0 {
+		if audience := facades.Config().GetString("jwt.audience"); audience != "" {
+			audiences = []string{audience}
+		}
+	}
+
+	if len(audiences) == 0 {
+		return nil
+	}
+
+	return audiences
+}
+
 func Issuer(issuer string) string {
 
 	prefix := facades.Config().GetString("app.name") + ":"
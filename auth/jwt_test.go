@@ -1,9 +1,13 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/golang-jwt/jwt/v5"
+
 	contractauth "github.com/herhe-com/framework/contracts/auth"
 	contractconfig "github.com/herhe-com/framework/contracts/config"
 	"github.com/herhe-com/framework/facades"
@@ -46,6 +50,10 @@ func (f fakeConfig) GetString(key string, defaultValue ...string) string {
 }
 
 func (f fakeConfig) GetStrings(key string, defaultValue ...[]string) []string {
+	if value, ok := f.values[key].([]string); ok {
+		return value
+	}
+
 	return nil
 }
 
@@ -70,19 +78,28 @@ func (f fakeConfig) IsSet(key string) bool {
 	return ok
 }
 
-func TestNewJWTokenCanBeChecked(t *testing.T) {
+func useConfig(t *testing.T, values map[string]any) {
 	original := facades.Container()
 	facades.SetContainer(&facades.Services{})
-	facades.Register[contractconfig.Application](fakeConfig{
-		values: map[string]any{
-			"app.name":   "framework",
-			"jwt.sub":    "api",
-			"jwt.secret": "test-secret",
-		},
-	})
+
+	configuration := map[string]any{
+		"app.name":   "framework",
+		"jwt.sub":    "api",
+		"jwt.secret": "test-secret",
+	}
+
+	for key, value := range values {
+		configuration[key] = value
+	}
+
+	facades.Register[contractconfig.Application](fakeConfig{values: configuration})
 	t.Cleanup(func() {
 		facades.SetContainer(original)
 	})
+}
+
+func TestNewJWTokenCanBeChecked(t *testing.T) {
+	useConfig(t, nil)
 
 	token, err := NewJWToken("user-1", 5, true, map[string]any{"role": "admin"})
 	if err != nil {
@@ -107,3 +124,130 @@ func TestNewJWTokenCanBeChecked(t *testing.T) {
 		t.Fatalf("expected issuer framework:api, got %q", claims.Issuer)
 	}
 }
+
+type profile struct {
+	UserType   string `json:"user_type"`
+	ReviewerID int    `json:"reviewer_id"`
+}
+
+func TestTypedClaimsRoundTrip(t *testing.T) {
+	useConfig(t, nil)
+
+	token, err := NewTypedToken("user-1", 5, true, profile{UserType: "reviewer", ReviewerID: 10000})
+	if err != nil {
+		t.Fatalf("expected typed token to be created: %v", err)
+	}
+
+	var claims contractauth.Claims
+	if _, err = CheckJWToken(&claims, token); err != nil {
+		t.Fatalf("expected typed token to be valid: %v", err)
+	}
+
+	ctx := app.NewContext(0)
+	ctx.Set(ContextOfClaims, &claims)
+
+	data, ok := TypedClaims[profile](ctx)
+	if !ok {
+		t.Fatal("expected typed claims to be decoded")
+	}
+
+	if data.UserType != "reviewer" || data.ReviewerID != 10000 {
+		t.Fatalf("unexpected typed claims %#v", data)
+	}
+}
+
+func TestCheckJWTokenVerifiesAudience(t *testing.T) {
+	useConfig(t, map[string]any{"jwt.audiences": []string{"admin", "web"}})
+
+	token, err := NewJWToken("user-1", 5, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	useConfig(t, map[string]any{"jwt.audience": "web"})
+
+	var claims contractauth.Claims
+	if _, err = CheckJWToken(&claims, token); err != nil {
+		t.Fatalf("expected token to be accepted by web: %v", err)
+	}
+
+	useConfig(t, map[string]any{"jwt.audience": "partner"})
+
+	if _, err = CheckJWToken(&contractauth.Claims{}, token); !errors.Is(err, jwt.ErrTokenInvalidAudience) {
+		t.Fatalf("expected token to be rejected by partner, got %v", err)
+	}
+}
+
+func TestCheckJWTokenVerifiesRequiredClaims(t *testing.T) {
+	useConfig(t, map[string]any{"jwt.required": []string{"user_type"}})
+
+	token, err := NewJWToken("user-1", 5, false, map[string]any{"reviewer_id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = CheckJWToken(&contractauth.Claims{}, token); !errors.Is(err, jwt.ErrTokenRequiredClaimMissing) {
+		t.Fatalf("expected missing user_type to be rejected, got %v", err)
+	}
+}
+
+type fakeScope struct{}
+
+func (fakeScope) Restricted(claims *contractauth.Claims) bool { return claims.Type == TokenOfOAuth2 }
+
+func (fakeScope) Allowed(claims *contractauth.Claims, permission string) bool { return false }
+
+func (fakeScope) Subjectless(claims *contractauth.Claims) bool { return false }
+
+func oauth2Token(t *testing.T) string {
+
+	claims := NewClaims("user-1", 5, false, map[string]any{"client_id": "client-1"})
+	claims.Type = TokenOfOAuth2
+
+	token, err := MakeJWToken(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestCheckJWTokenRejectsOAuth2TokensWithoutScopeChecker(t *testing.T) {
+	useConfig(t, nil)
+
+	if _, err := CheckJWToken(&contractauth.Claims{}, oauth2Token(t)); !errors.Is(err, ErrScopeUnavailable) {
+		t.Fatalf("expected oauth2 token to be rejected without a scope checker, got %v", err)
+	}
+
+	facades.Register[contractauth.Scope](fakeScope{})
+
+	if _, err := CheckJWToken(&contractauth.Claims{}, oauth2Token(t)); err != nil {
+		t.Fatalf("expected oauth2 token to be accepted with a scope checker, got %v", err)
+	}
+}
+
+func TestCheckJWTokenSkipsRequiredClaimsOnlyForOAuth2Tokens(t *testing.T) {
+	useConfig(t, map[string]any{"jwt.required": []string{"user_type"}})
+
+	facades.Register[contractauth.Scope](fakeScope{})
+
+	token := oauth2Token(t)
+
+	var err error
+
+	if _, err = CheckJWToken(&contractauth.Claims{}, token); err != nil {
+		t.Fatalf("expected oauth2 token to skip required claims, got %v", err)
+	}
+
+	// 只有扩展声明、没有 typ 的 token 仍然需要必填声明
+	for _, ext := range []map[string]any{{"client_id": "client-1"}, {"mfa": 1}, {"imp": "session"}} {
+
+		if token, err = NewJWToken("user-1", 5, false, ext); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = CheckJWToken(&contractauth.Claims{}, token); !errors.Is(err, jwt.ErrTokenRequiredClaimMissing) {
+			t.Fatalf("expected %v to require user_type, got %v", ext, err)
+		}
+	}
+}
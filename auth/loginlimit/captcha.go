@@ -0,0 +1,106 @@
+package loginlimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/herhe-com/framework/captcha"
+	contractcaptcha "github.com/herhe-com/framework/contracts/captcha"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/wenlng/go-captcha/v2/click"
+)
+
+var ErrCaptcha = errors.New("captcha is invalid or expired")
+
+// Challenge 需要验证码时返回给前端的点击式验证码
+type Challenge struct {
+	Key    string `json:"key"`
+	Master string `json:"master"`
+	Thumb  string `json:"thumb"`
+}
+
+// NewChallenge 生成点击式验证码，答案保存 auth.login.captcha_lifetime 秒（默认 300），只能验证一次
+func NewChallenge(c context.Context) (*Challenge, error) {
+
+	client, err := redisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := captcha.Click()
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, 16)
+
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	dots, err := json.Marshal(result.Dots)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &Challenge{
+		Key:    hex.EncodeToString(key),
+		Master: result.Master,
+		Thumb:  result.Thumb,
+	}
+
+	if err = client.Set(c, keyOfCaptcha(challenge.Key), dots, captchaLifetime()).Err(); err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// VerifyChallenge 校验用户点击的坐标，无论是否通过都会删除答案
+func VerifyChallenge(c context.Context, key string, dots []contractcaptcha.Dot) error {
+
+	client, err := redisClient()
+	if err != nil {
+		return err
+	}
+
+	body, err := client.GetDel(c, keyOfCaptcha(key)).Bytes()
+
+	if errors.Is(err, redis.Nil) {
+		return ErrCaptcha
+	} else if err != nil {
+		return err
+	}
+
+	var answers map[int]*click.Dot
+
+	if err = json.Unmarshal(body, &answers); err != nil {
+		return err
+	}
+
+	targets := make([]click.Dot, 0, len(answers))
+
+	for _, item := range answers {
+		targets = append(targets, *item)
+	}
+
+	if err = captcha.ClickVerify(dots, targets); err != nil {
+		return ErrCaptcha
+	}
+
+	return nil
+}
+
+func captchaLifetime() time.Duration {
+	return time.Duration(facades.Config().GetInt("auth.login.captcha_lifetime", 300)) * time.Second
+}
+
+func keyOfCaptcha(key string) string {
+	return util.Keys("login", "captcha", key)
+}
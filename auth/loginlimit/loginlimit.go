@@ -0,0 +1,335 @@
+package loginlimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/herhe-com/framework/audit"
+	"github.com/herhe-com/framework/contracts/http/response"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/http"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cast"
+)
+
+// State 登录限制状态
+type State struct {
+	Failures   int64         // 账号在统计周期内的失败次数
+	IPFailures int64         // IP 在统计周期内的失败次数
+	Remaining  int64         // 账号锁定前剩余的尝试次数
+	Locked     bool          // 账号或 IP 已锁定
+	Captcha    bool          // 需要验证码
+	RetryAfter time.Duration // 锁定或渐进延迟的剩余时间
+}
+
+// Allowed 未锁定且不在渐进延迟中，可以尝试登录
+func (s *State) Allowed() bool {
+	return !s.Locked && s.RetryAfter <= 0
+}
+
+// 失败时原子性地增加账号、IP 的失败次数，达到上限后锁定并清空失败次数
+var failScript = redis.NewScript(`
+	local result = {}
+
+	for i = 0, 1 do
+		local attempts = KEYS[i * 2 + 1]
+		local lock = KEYS[i * 2 + 2]
+		local max = tonumber(ARGV[i + 1])
+
+		local count = redis.call("HINCRBY", attempts, "count", 1)
+		redis.call("HSET", attempts, "last", ARGV[4])
+		redis.call("EXPIRE", attempts, ARGV[3])
+
+		local locked = 0
+		if max > 0 and count >= max then
+			redis.call("SET", lock, "1", "EX", ARGV[3])
+			redis.call("DEL", attempts)
+			locked = 1
+		end
+
+		table.insert(result, count)
+		table.insert(result, locked)
+	end
+
+	return result
+`)
+
+// Check
+//
+//	@Description: 登录前检查账号和 IP 是否已锁定、是否在渐进延迟中、是否需要验证码
+//	@param identifier	登录账号，如用户名、邮箱、手机号
+func Check(c context.Context, ctx *app.RequestContext, identifier string) (*State, error) {
+
+	client, err := redisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ip := ctx.ClientIP()
+
+	pipe := client.Pipeline()
+
+	identifierLock := pipe.PTTL(c, keyOfLock(identifier))
+	ipLock := pipe.PTTL(c, keyOfIPLock(ip))
+	identifierAttempts := pipe.HMGet(c, keyOfAttempts(identifier), "count", "last")
+	ipAttempts := pipe.HMGet(c, keyOfIPAttempts(ip), "count", "last")
+
+	if _, err = pipe.Exec(c); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	state := &State{}
+
+	for _, ttl := range []time.Duration{identifierLock.Val(), ipLock.Val()} {
+		if ttl > 0 {
+			state.Locked = true
+			state.RetryAfter = max(state.RetryAfter, ttl)
+		}
+	}
+
+	values := identifierAttempts.Val()
+
+	state.Failures = cast.ToInt64(values[0])
+	state.IPFailures = cast.ToInt64(ipAttempts.Val()[0])
+	state.Remaining = max(maxAttempts()-state.Failures, 0)
+
+	if threshold := captchaAttempts(); threshold > 0 {
+		state.Captcha = state.Failures >= threshold || state.IPFailures >= threshold
+	}
+
+	// 渐进延迟：距离上次失败的时间不足 delay 时不能继续尝试
+	if last := cast.ToInt64(values[1]); !state.Locked && last > 0 {
+
+		wait := time.UnixMilli(last).Add(delay(state.Failures)).Sub(now())
+
+		if wait > 0 {
+			state.RetryAfter = wait
+		}
+	}
+
+	if state.Locked {
+		audit.RecordRequest(c, ctx, &audit.Event{
+			Type:     audit.TypeOfLoginLocked,
+			Subject:  identifier,
+			Result:   audit.ResultOfDenied,
+			Metadata: map[string]any{"retry_after": int64(state.RetryAfter.Seconds())},
+		})
+	}
+
+	return state, nil
+}
+
+// Fail
+//
+//	@Description: 登录失败后调用，增加账号和 IP 的失败次数，达到 auth.login.max_attempts、auth.login.ip_max_attempts 后锁定
+//	@param identifier	登录账号
+func Fail(c context.Context, ctx *app.RequestContext, identifier string) (*State, error) {
+
+	client, err := redisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ip := ctx.ClientIP()
+
+	values, err := failScript.Run(c, client,
+		[]string{keyOfAttempts(identifier), keyOfLock(identifier), keyOfIPAttempts(ip), keyOfIPLock(ip)},
+		maxAttempts(), ipMaxAttempts(), int64(lockDuration().Seconds()), now().UnixMilli(),
+	).Int64Slice()
+
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{
+		Failures:   values[0],
+		IPFailures: values[2],
+		Remaining:  max(maxAttempts()-values[0], 0),
+		Locked:     values[1] == 1 || values[3] == 1,
+	}
+
+	if state.Locked {
+		state.RetryAfter = lockDuration()
+	} else {
+		state.RetryAfter = delay(state.Failures)
+	}
+
+	if threshold := captchaAttempts(); threshold > 0 {
+		state.Captcha = state.Locked || state.Failures >= threshold || state.IPFailures >= threshold
+	}
+
+	audit.RecordRequest(c, ctx, &audit.Event{
+		Type:    audit.TypeOfLoginFailed,
+		Subject: identifier,
+		Result:  audit.ResultOfFailure,
+		Metadata: map[string]any{
+			"attempts":    state.Failures,
+			"ip_attempts": state.IPFailures,
+			"locked":      state.Locked,
+		},
+	})
+
+	return state, nil
+}
+
+// Succeed 登录成功后调用，清空账号的失败次数；IP 的失败次数保留到统计周期结束
+func Succeed(c context.Context, ctx *app.RequestContext, identifier string) error {
+
+	client, err := redisClient()
+	if err != nil {
+		return err
+	}
+
+	if err = client.Del(c, keyOfAttempts(identifier)).Err(); err != nil {
+		return err
+	}
+
+	audit.RecordRequest(c, ctx, &audit.Event{
+		Type:    audit.TypeOfLogin,
+		Subject: identifier,
+		Result:  audit.ResultOfSuccess,
+	})
+
+	return nil
+}
+
+// Unlock
+//
+//	@Description: 管理员解除账号的锁定并清空失败次数
+//	@param identifier	登录账号，为空时只解除 IP
+//	@param ips	同时解除的 IP
+func Unlock(c context.Context, identifier string, ips ...string) error {
+
+	client, err := redisClient()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(ips)*2+2)
+
+	if identifier != "" {
+		keys = append(keys, keyOfAttempts(identifier), keyOfLock(identifier))
+	}
+
+	for _, ip := range ips {
+		keys = append(keys, keyOfIPAttempts(ip), keyOfIPLock(ip))
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return client.Del(c, keys...).Err()
+}
+
+// Locked 账号锁定的剩余时间，未锁定时为 0，不检查 IP 锁定和渐进延迟
+func Locked(c context.Context, identifier string) (time.Duration, error) {
+
+	client, err := redisClient()
+	if err != nil {
+		return 0, err
+	}
+
+	ttl, err := client.PTTL(c, keyOfLock(identifier)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	return max(ttl, 0), nil
+}
+
+// Reject
+//
+//	@Description: 锁定或渐进延迟中拒绝登录，返回 429 和 Retry-After，账号或 IP 锁定时消息为 auth.login.lock_message
+//	@param state	Check 返回的状态
+func Reject(ctx *app.RequestContext, state *State) {
+
+	ctx.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(state.RetryAfter.Seconds())), 10))
+	ctx.Abort()
+
+	if !state.Locked {
+		http.TooManyRequests(ctx)
+		return
+	}
+
+	message := facades.Config().GetString("auth.login.lock_message", "Account is locked. Please try again in %d minutes.")
+
+	ctx.JSON(consts.StatusTooManyRequests, response.Response[any]{
+		Code:    42900,
+		Message: fmt.Sprintf(message, int64(math.Ceil(state.RetryAfter.Minutes()))),
+	})
+}
+
+// delay 第 n 次失败后的等待时间，从 auth.login.delay 秒开始每次翻倍，不超过 auth.login.max_delay 秒
+func delay(failures int64) time.Duration {
+
+	base := time.Duration(facades.Config().GetInt("auth.login.delay", 1)) * time.Second
+
+	if base <= 0 || failures <= 0 {
+		return 0
+	}
+
+	limit := time.Duration(facades.Config().GetInt("auth.login.max_delay", 30)) * time.Second
+
+	duration := base
+
+	for i := int64(1); i < failures && duration < limit; i++ {
+		duration *= 2
+	}
+
+	return min(duration, limit)
+}
+
+func maxAttempts() int64 {
+	return facades.Config().GetInt64("auth.login.max_attempts", 5)
+}
+
+func ipMaxAttempts() int64 {
+	return facades.Config().GetInt64("auth.login.ip_max_attempts", 20)
+}
+
+func captchaAttempts() int64 {
+	return facades.Config().GetInt64("auth.login.captcha_attempts", 3)
+}
+
+func lockDuration() time.Duration {
+	return time.Duration(facades.Config().GetInt("auth.login.lock_duration", 15)) * time.Minute
+}
+
+func redisClient() (*redis.Client, error) {
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return nil, errors.New("please initialize Redis first")
+	}
+
+	return cache.Default(), nil
+}
+
+// keyOfAttempts 账号的失败次数，旧版 LoginLimiter 的 login:attempts:* 是计数器，类型不同，升级后不再读取，统计周期结束后自动过期
+func keyOfAttempts(identifier string) string {
+	return util.Keys("login", "failures", identifier)
+}
+
+// keyOfLock 与旧版 LoginLimiter 的 login:lock:* 相同，升级前已锁定的账号继续锁定
+func keyOfLock(identifier string) string {
+	return util.Keys("login", "lock", identifier)
+}
+
+func keyOfIPAttempts(ip string) string {
+	return util.Keys("login", "ip", "failures", ip)
+}
+
+func keyOfIPLock(ip string) string {
+	return util.Keys("login", "ip", "lock", ip)
+}
+
+// now 当前时间，测试时替换
+var now = time.Now
@@ -0,0 +1,297 @@
+package loginlimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cloudwego/hertz/pkg/app"
+	contractcaptcha "github.com/herhe-com/framework/contracts/captcha"
+	contractconfig "github.com/herhe-com/framework/contracts/config"
+	"github.com/herhe-com/framework/contracts/database"
+	"github.com/herhe-com/framework/facades"
+	"github.com/redis/go-redis/v9"
+	"github.com/wenlng/go-captcha/v2/click"
+)
+
+type fakeConfig struct {
+	values map[string]any
+}
+
+func (f fakeConfig) Env(key string, defaultValue ...any) any {
+	return f.Get(key, defaultValue...)
+}
+
+func (f fakeConfig) Add(name string, configuration map[string]any) {}
+
+func (f fakeConfig) Set(key string, configuration any) {
+	f.values[key] = configuration
+}
+
+func (f fakeConfig) Get(key string, defaultValue ...any) any {
+	if value, ok := f.values[key]; ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetString(key string, defaultValue ...string) string {
+	if value, ok := f.values[key]; ok {
+		return fmt.Sprint(value)
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return ""
+}
+
+func (f fakeConfig) GetStrings(key string, defaultValue ...[]string) []string {
+	return nil
+}
+
+func (f fakeConfig) GetMaps(key string, defaultValue ...map[string]any) map[string]any {
+	return nil
+}
+
+func (f fakeConfig) GetInt(key string, defaultValue ...int) int {
+	if value, ok := f.values[key].(int); ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return 0
+}
+
+func (f fakeConfig) GetInt64(key string, defaultValue ...int64) int64 {
+	if value, ok := f.values[key].(int); ok {
+		return int64(value)
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return 0
+}
+
+func (f fakeConfig) GetBool(key string, defaultValue ...bool) bool {
+	return false
+}
+
+func (f fakeConfig) IsSet(key string) bool {
+	_, ok := f.values[key]
+	return ok
+}
+
+type fakeRedis struct {
+	client *redis.Client
+}
+
+func (f fakeRedis) Default() *redis.Client {
+	return f.client
+}
+
+func (f fakeRedis) Channel(name string) (*redis.Client, error) {
+	return f.client, nil
+}
+
+func setup(t *testing.T) *time.Time {
+	original := facades.Container()
+	facades.SetContainer(&facades.Services{})
+	t.Cleanup(func() {
+		facades.SetContainer(original)
+	})
+
+	facades.Register[contractconfig.Application](fakeConfig{values: map[string]any{
+		"app.name":                    "framework",
+		"auth.login.max_attempts":     4,
+		"auth.login.ip_max_attempts":  6,
+		"auth.login.captcha_attempts": 2,
+	}})
+
+	server := miniredis.RunT(t)
+
+	facades.Register[database.Redis](fakeRedis{client: redis.NewClient(&redis.Options{Addr: server.Addr()})})
+
+	current := time.Now()
+
+	now = func() time.Time {
+		return current
+	}
+
+	t.Cleanup(func() {
+		now = time.Now
+	})
+
+	return &current
+}
+
+func request(ip string) *app.RequestContext {
+
+	ctx := app.NewContext(0)
+	ctx.Request.Header.Set("X-Real-IP", ip)
+
+	return ctx
+}
+
+func TestFailRequiresCaptchaDelaysAndLocks(t *testing.T) {
+	current := setup(t)
+
+	c := context.Background()
+	ctx := request("10.0.0.1")
+
+	state, err := Fail(c, ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state.Captcha || state.Remaining != 3 || state.RetryAfter != time.Second {
+		t.Fatalf("unexpected state after first failure: %+v", state)
+	}
+
+	state, _ = Fail(c, ctx, "alice")
+
+	if !state.Captcha || state.RetryAfter != 2*time.Second {
+		t.Fatalf("expected captcha and doubled delay, got %+v", state)
+	}
+
+	// 渐进延迟期间不能继续尝试
+	if state, _ = Check(c, ctx, "alice"); state.Allowed() || !state.Captcha {
+		t.Fatalf("expected delay after failures, got %+v", state)
+	}
+
+	*current = current.Add(2 * time.Second)
+
+	if state, _ = Check(c, ctx, "alice"); !state.Allowed() {
+		t.Fatalf("expected attempt allowed after delay, got %+v", state)
+	}
+
+	_, _ = Fail(c, ctx, "alice")
+
+	if state, _ = Fail(c, ctx, "alice"); !state.Locked || state.RetryAfter != 15*time.Minute {
+		t.Fatalf("expected account locked, got %+v", state)
+	}
+
+	if state, _ = Check(c, ctx, "alice"); !state.Locked || state.Allowed() {
+		t.Fatalf("expected check to report lock, got %+v", state)
+	}
+
+	// 其他账号不受影响
+	if state, _ = Check(c, ctx, "bob"); state.Locked {
+		t.Fatalf("expected bob not locked, got %+v", state)
+	}
+
+	if err = Unlock(c, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if state, _ = Check(c, ctx, "alice"); state.Locked || state.Failures != 0 {
+		t.Fatalf("expected unlock to clear account, got %+v", state)
+	}
+}
+
+func TestIPLockAcrossAccountsAndSucceed(t *testing.T) {
+	setup(t)
+
+	c := context.Background()
+	ctx := request("10.0.0.2")
+
+	for _, identifier := range []string{"a", "b", "c", "d", "e"} {
+		_, _ = Fail(c, ctx, identifier)
+	}
+
+	if err := Succeed(c, ctx, "e"); err != nil {
+		t.Fatal(err)
+	}
+
+	state, _ := Check(c, ctx, "e")
+
+	if state.Failures != 0 || state.IPFailures != 5 || !state.Captcha {
+		t.Fatalf("expected success to keep ip failures, got %+v", state)
+	}
+
+	if state, _ = Fail(c, ctx, "f"); !state.Locked {
+		t.Fatalf("expected ip locked after sixth failure, got %+v", state)
+	}
+
+	if state, _ = Check(c, request("10.0.0.3"), "g"); state.Locked {
+		t.Fatal("expected other ip not locked")
+	}
+
+	if state, _ = Check(c, ctx, "g"); !state.Locked {
+		t.Fatal("expected locked ip to block every account")
+	}
+
+	_ = Unlock(c, "", "10.0.0.2")
+
+	if state, _ = Check(c, ctx, "g"); state.Locked {
+		t.Fatal("expected ip unlocked")
+	}
+}
+
+func TestRejectReturnsRetryAfter(t *testing.T) {
+	setup(t)
+
+	ctx := request("10.0.0.4")
+	Reject(ctx, &State{Locked: true, RetryAfter: 90 * time.Second})
+
+	if !ctx.IsAborted() || ctx.Response.StatusCode() != 429 || string(ctx.Response.Header.Peek("Retry-After")) != "90" {
+		t.Fatalf("expected locked login to be rejected with 429, got %d", ctx.Response.StatusCode())
+	}
+
+	if body := string(ctx.Response.Body()); !strings.Contains(body, `"code":42900`) || !strings.Contains(body, "Account is locked") {
+		t.Fatalf("unexpected body %s", body)
+	}
+
+	ctx = request("10.0.0.4")
+	Reject(ctx, &State{RetryAfter: 1500 * time.Millisecond})
+
+	if ctx.Response.StatusCode() != 429 || string(ctx.Response.Header.Peek("Retry-After")) != "2" || strings.Contains(string(ctx.Response.Body()), "Account is locked") {
+		t.Fatalf("expected delayed login to be rejected with 429, got %d %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+}
+
+func TestVerifyChallengeOnce(t *testing.T) {
+	setup(t)
+
+	c := context.Background()
+	client, _ := redisClient()
+
+	answers, _ := json.Marshal(map[int]*click.Dot{
+		0: {Index: 0, X: 10, Y: 10, Width: 20, Height: 20},
+		1: {Index: 1, X: 100, Y: 50, Width: 20, Height: 20},
+	})
+
+	client.Set(c, keyOfCaptcha("k1"), answers, time.Minute)
+
+	dots := []contractcaptcha.Dot{{Index: 0, X: 15, Y: 15}, {Index: 1, X: 105, Y: 60}}
+
+	if err := VerifyChallenge(c, "k1", dots); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyChallenge(c, "k1", dots); !errors.Is(err, ErrCaptcha) {
+		t.Fatalf("expected answer to be used only once, got %v", err)
+	}
+
+	client.Set(c, keyOfCaptcha("k2"), answers, time.Minute)
+
+	if err := VerifyChallenge(c, "k2", []contractcaptcha.Dot{{Index: 0, X: 15, Y: 15}, {Index: 1, X: 300, Y: 300}}); !errors.Is(err, ErrCaptcha) {
+		t.Fatalf("expected wrong click to fail, got %v", err)
+	}
+}
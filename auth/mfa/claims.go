@@ -0,0 +1,51 @@
+package mfa
+
+import (
+	"time"
+
+	contractauth "github.com/herhe-com/framework/contracts/auth"
+	"github.com/herhe-com/framework/facades"
+	"github.com/spf13/cast"
+)
+
+const ClaimOfMFA = "mfa"
+
+// Ext 在 token 扩展变量中记录二次验证通过的时间，用于 auth.NewJWToken
+func Ext(ext map[string]any) map[string]any {
+
+	if ext == nil {
+		ext = make(map[string]any)
+	}
+
+	ext[ClaimOfMFA] = time.Now().Unix()
+
+	return ext
+}
+
+// Fresh 判断 token 中的二次验证是否仍在有效期内，默认读取 auth.mfa.fresh（分钟）
+func Fresh(claims *contractauth.Claims, durations ...time.Duration) bool {
+
+	if claims == nil || claims.Ext == nil {
+		return false
+	}
+
+	value, ok := claims.Ext[ClaimOfMFA]
+	if !ok {
+		return false
+	}
+
+	at, err := cast.ToInt64E(value)
+	if err != nil || at <= 0 {
+		return false
+	}
+
+	var fresh time.Duration
+
+	if len(durations) > 0 && durations[0] > 0 {
+		fresh = durations[0]
+	} else {
+		fresh = time.Duration(facades.Config().GetInt("auth.mfa.fresh", 15)) * time.Minute
+	}
+
+	return time.Since(time.Unix(at, 0)) <= fresh
+}
@@ -0,0 +1,107 @@
+package mfa
+
+import (
+	"encoding/base32"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	contractauth "github.com/herhe-com/framework/contracts/auth"
+)
+
+// RFC 6238 附录 B 中 SHA1 的测试向量
+func TestCodeMatchesRFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	option := Option{Digits: 8, Period: 30}
+
+	vectors := map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	}
+
+	for at, expected := range vectors {
+		code, err := Code(secret, time.Unix(at, 0), option)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if code != expected {
+			t.Fatalf("expected code %s at %d, got %s", expected, at, code)
+		}
+	}
+}
+
+func TestMatchAllowsConfiguredDrift(t *testing.T) {
+	secret, err := Secret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	option := Option{Digits: 6, Period: 30, Window: 1}
+	now := time.Unix(1700000000, 0)
+
+	previous, _ := Code(secret, now.Add(-30*time.Second), option)
+	if _, ok, _ := Match(secret, previous, now, option); !ok {
+		t.Fatal("expected previous time step to be accepted")
+	}
+
+	stale, _ := Code(secret, now.Add(-90*time.Second), option)
+	if _, ok, _ := Match(secret, stale, now, option); ok {
+		t.Fatal("expected code outside the window to be rejected")
+	}
+}
+
+func TestCodeRejectsInvalidDigits(t *testing.T) {
+	for _, digits := range []int{0, 5, 9, 10} {
+		if _, err := Code("JBSWY3DPEHPK3PXP", time.Unix(59, 0), Option{Digits: digits, Period: 30}); !errors.Is(err, ErrInvalidDigits) {
+			t.Fatalf("expected digits %d to be rejected, got %v", digits, err)
+		}
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI(Option{Issuer: "Example", Digits: 6, Period: 30}, "user@example.com", "SECRET")
+
+	expected := "otpauth://totp/Example:user@example.com?algorithm=SHA1&digits=6&issuer=Example&period=30&secret=SECRET"
+	if uri != expected {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes := RecoveryCodes(3)
+	if len(codes) != 3 || len(hashes) != 3 {
+		t.Fatalf("expected 3 recovery codes, got %d/%d", len(codes), len(hashes))
+	}
+
+	if index := CheckRecoveryCode(codes[1], hashes); index != 1 {
+		t.Fatalf("expected recovery code to match index 1, got %d", index)
+	}
+
+	if index := CheckRecoveryCode("wrong-codes", hashes); index != -1 {
+		t.Fatalf("expected unknown recovery code to be rejected, got %d", index)
+	}
+}
+
+func TestFresh(t *testing.T) {
+	claims := &contractauth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "1"}}
+
+	if Fresh(claims, time.Minute) {
+		t.Fatal("expected claims without mfa to be rejected")
+	}
+
+	claims.Ext = map[string]any{ClaimOfMFA: float64(time.Now().Add(-2 * time.Minute).Unix())}
+
+	if Fresh(claims, time.Minute) {
+		t.Fatal("expected stale mfa claim to be rejected")
+	}
+
+	if !Fresh(claims, 5*time.Minute) {
+		t.Fatal("expected fresh mfa claim to be accepted")
+	}
+}
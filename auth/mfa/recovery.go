@@ -0,0 +1,79 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"strings"
+
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/facades"
+)
+
+// RecoveryCodes
+//
+//	@Description: 生成恢复码，明文只展示给用户一次，业务仅保存哈希
+//	@param counts 数量，默认读取 auth.mfa.recovery（10）
+//	@return codes	明文恢复码
+//	@return hashes	bcrypt 哈希
+func RecoveryCodes(counts ...int) (codes []string, hashes []string) {
+
+	var count int
+
+	if len(counts) > 0 && counts[0] > 0 {
+		count = counts[0]
+	} else {
+		count = facades.Config().GetInt("auth.mfa.recovery", 10)
+	}
+
+	codes = make([]string, 0, count)
+	hashes = make([]string, 0, count)
+
+	for i := 0; i < count; i++ {
+
+		code := random(5) + "-" + random(5)
+
+		codes = append(codes, code)
+		hashes = append(hashes, auth.Password(code))
+	}
+
+	return codes, hashes
+}
+
+// CheckRecoveryCode 校验恢复码，返回匹配的哈希下标，未匹配时返回 -1；恢复码只能使用一次，业务需删除对应哈希
+func CheckRecoveryCode(code string, hashes []string) int {
+
+	code = strings.ToLower(strings.TrimSpace(code))
+
+	for index, item := range hashes {
+		if auth.CheckPassword(code, item) {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// recoveryCharset 去掉了容易混淆的 i、l、o、0、1
+const recoveryCharset = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// random 恢复码等同于密码，使用 crypto/rand 生成；丢弃超出整倍数的字节，保证每个字符概率相同
+func random(length int) string {
+
+	limit := byte(256 - 256%len(recoveryCharset))
+
+	result := make([]byte, 0, length)
+	buf := make([]byte, length*2)
+
+	for len(result) < length {
+
+		// Go 1.24 起 crypto/rand.Read 不会返回错误
+		_, _ = rand.Read(buf)
+
+		for _, item := range buf {
+			if item < limit && len(result) < length {
+				result = append(result, recoveryCharset[int(item)%len(recoveryCharset)])
+			}
+		}
+	}
+
+	return string(result)
+}
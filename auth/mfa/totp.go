@@ -0,0 +1,236 @@
+package mfa
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	// luaUseCounter 原子性地记录已使用的时间步，拒绝小于等于上次使用的时间步（防重放）
+	luaUseCounter = `
+		local last = redis.call("GET", KEYS[1])
+		if last and tonumber(last) >= tonumber(ARGV[1]) then
+			return 0
+		end
+		redis.call("SET", KEYS[1], ARGV[1], "EX", ARGV[2])
+		return 1
+	`
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+var ErrInvalidDigits = errors.New("auth.mfa.digits must be between 6 and 8")
+
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QR     []byte `json:"qr"` // PNG 格式的二维码
+}
+
+type Option struct {
+	Issuer string
+	Digits int
+	Period int
+	Window int
+}
+
+// NewOption 从 auth.mfa.* 读取 TOTP 参数
+func NewOption() Option {
+	return Option{
+		Issuer: facades.Config().GetString("auth.mfa.issuer", facades.Config().GetString("app.name")),
+		Digits: facades.Config().GetInt("auth.mfa.digits", 6),
+		Period: facades.Config().GetInt("auth.mfa.period", 30),
+		Window: facades.Config().GetInt("auth.mfa.window", 1),
+	}
+}
+
+// Enroll
+//
+//	@Description: 生成 TOTP 密钥、otpauth URI 及二维码
+//	@param account 账号名称，展示在身份验证器中
+func Enroll(account string) (*Enrollment, error) {
+
+	option := NewOption()
+
+	if !validDigits(option.Digits) {
+		return nil, ErrInvalidDigits
+	}
+
+	secret, err := Secret()
+	if err != nil {
+		return nil, err
+	}
+
+	uri := URI(option, account, secret)
+
+	png, err := qrcode.Encode(uri, qrcode.Medium, facades.Config().GetInt("auth.mfa.qr_size", 256))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{
+		Secret: secret,
+		URI:    uri,
+		QR:     png,
+	}, nil
+}
+
+// Secret 生成 160 位的 base32 密钥
+func Secret() (string, error) {
+
+	buf := make([]byte, 20)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(buf), nil
+}
+
+// URI 生成身份验证器可识别的 otpauth URI
+func URI(option Option, account, secret string) string {
+
+	label := url.PathEscape(account)
+
+	if option.Issuer != "" {
+		label = url.PathEscape(option.Issuer) + ":" + label
+	}
+
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprintf("%d", option.Digits))
+	query.Set("period", fmt.Sprintf("%d", option.Period))
+
+	if option.Issuer != "" {
+		query.Set("issuer", option.Issuer)
+	}
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Code 计算指定时间的 TOTP 验证码（RFC 6238）
+func Code(secret string, at time.Time, option Option) (string, error) {
+	return hotp(secret, counter(at, option.Period), option.Digits)
+}
+
+// Verify
+//
+//	@Description: 校验 TOTP 验证码，允许前后 Window 个时间步的偏移，并在 Redis 中记录已使用的时间步防止重放
+//	@param id	用户
+//	@param secret	用户的 TOTP 密钥
+//	@param code	用户输入的验证码
+func Verify(ctx context.Context, id, secret, code string) (bool, error) {
+
+	option := NewOption()
+
+	step, ok, err := Match(secret, code, time.Now(), option)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	cache, has := facades.OptionalRedis()
+	if !has {
+		return false, errors.New("please initialize Redis first")
+	}
+
+	expires := option.Period * (2*option.Window + 1)
+
+	result, err := cache.Default().Eval(ctx, luaUseCounter, []string{KeyOfCounter(id)}, step, expires).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return result == 1, nil
+}
+
+// Match 在允许的偏移窗口内比对验证码，返回匹配的时间步
+func Match(secret, code string, at time.Time, option Option) (step uint64, ok bool, err error) {
+
+	if !validDigits(option.Digits) {
+		return 0, false, ErrInvalidDigits
+	}
+
+	code = strings.TrimSpace(code)
+
+	if len(code) != option.Digits {
+		return 0, false, nil
+	}
+
+	current := counter(at, option.Period)
+
+	for offset := -option.Window; offset <= option.Window; offset++ {
+
+		step = uint64(int64(current) + int64(offset))
+
+		expected, err := hotp(secret, step, option.Digits)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+func KeyOfCounter(id string) string {
+	return util.Keys("mfa", "counter", id)
+}
+
+func counter(at time.Time, period int) uint64 {
+
+	if period <= 0 {
+		period = 30
+	}
+
+	return uint64(at.Unix() / int64(period))
+}
+
+// validDigits RFC 4226 的验证码为 6 到 8 位，位数过大时取模会溢出
+func validDigits(digits int) bool {
+	return digits >= 6 && digits <= 8
+}
+
+func hotp(secret string, counter uint64, digits int) (string, error) {
+
+	if !validDigits(digits) {
+		return "", ErrInvalidDigits
+	}
+
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "=")))
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", digits, value%uint32(math.Pow10(digits))), nil
+}
@@ -0,0 +1,33 @@
+package oauth2
+
+import "net/http"
+
+// Error RFC 6749 第 5.2 节定义的错误响应
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	Status      int    `json:"-"`
+}
+
+func (e *Error) Error() string {
+
+	if e.Description != "" {
+		return e.Code + ": " + e.Description
+	}
+
+	return e.Code
+}
+
+var (
+	ErrInvalidRequest       = &Error{Code: "invalid_request", Status: http.StatusBadRequest}
+	ErrInvalidClient        = &Error{Code: "invalid_client", Status: http.StatusUnauthorized}
+	ErrInvalidGrant         = &Error{Code: "invalid_grant", Status: http.StatusBadRequest}
+	ErrInvalidScope         = &Error{Code: "invalid_scope", Status: http.StatusBadRequest}
+	ErrUnauthorizedClient   = &Error{Code: "unauthorized_client", Status: http.StatusBadRequest}
+	ErrUnsupportedGrantType = &Error{Code: "unsupported_grant_type", Status: http.StatusBadRequest}
+	ErrServerError          = &Error{Code: "server_error", Status: http.StatusInternalServerError}
+)
+
+func describe(err *Error, description string) *Error {
+	return &Error{Code: err.Code, Description: description, Status: err.Status}
+}
@@ -0,0 +1,148 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	nethttp "net/http"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/http"
+)
+
+// Register
+//
+//	@Description: 在 Hertz 路由上注册授权服务端点，前缀默认读取 auth.oauth2.prefix（/oauth）
+//	POST {prefix}/authorize	用户同意授权，需要已登录（middleware.Jwt），返回携带授权码的回调地址
+//	POST {prefix}/token	令牌端点
+//	POST {prefix}/introspect	令牌内省
+//	POST {prefix}/revoke	令牌吊销
+func Register(router route.IRouter, middlewares ...app.HandlerFunc) {
+
+	group := router.Group(facades.Config().GetString("auth.oauth2.prefix", "/oauth"), middlewares...)
+
+	group.POST("/authorize", HandleAuthorize)
+	group.POST("/token", HandleToken)
+	group.POST("/introspect", HandleIntrospect)
+	group.POST("/revoke", HandleRevoke)
+}
+
+func HandleAuthorize(c context.Context, ctx *app.RequestContext) {
+
+	if !auth.Check(ctx) {
+		http.Unauthorized(ctx)
+		return
+	}
+
+	var request AuthorizeRequest
+
+	if err := ctx.Bind(&request); err != nil {
+		http.BadRequest(ctx, err)
+		return
+	}
+
+	redirect, err := Authorize(c, request, auth.ID(ctx))
+
+	var e *Error
+
+	if errors.As(err, &e) {
+		http.BadRequest(ctx, e.Error())
+		return
+	} else if err != nil {
+		http.Fail(ctx, "%v", err)
+		return
+	}
+
+	http.Success(ctx, map[string]string{
+		"redirect": redirect,
+	})
+}
+
+func HandleToken(c context.Context, ctx *app.RequestContext) {
+
+	client, err := authenticate(c, ctx)
+	if err != nil {
+		failure(ctx, err)
+		return
+	}
+
+	var token *Token
+
+	switch ctx.PostForm("grant_type") {
+	case GrantOfAuthorizationCode:
+		token, err = ExchangeCode(c, client, ctx.PostForm("code"), ctx.PostForm("redirect_uri"), ctx.PostForm("code_verifier"))
+	case GrantOfClientCredentials:
+		token, err = ClientCredentials(c, client, ctx.PostForm("scope"))
+	case GrantOfRefreshToken:
+		token, err = Refresh(c, client, ctx.PostForm("refresh_token"), ctx.PostForm("scope"))
+	default:
+		err = ErrUnsupportedGrantType
+	}
+
+	if err != nil {
+		failure(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(nethttp.StatusOK, token)
+}
+
+func HandleIntrospect(c context.Context, ctx *app.RequestContext) {
+
+	client, err := authenticate(c, ctx)
+	if err != nil {
+		failure(ctx, err)
+		return
+	}
+
+	result, err := Introspect(c, client, ctx.PostForm("token"))
+	if err != nil {
+		failure(ctx, err)
+		return
+	}
+
+	ctx.JSON(nethttp.StatusOK, result)
+}
+
+func HandleRevoke(c context.Context, ctx *app.RequestContext) {
+
+	client, err := authenticate(c, ctx)
+	if err != nil {
+		failure(ctx, err)
+		return
+	}
+
+	if err = Revoke(c, client, ctx.PostForm("token")); err != nil {
+		failure(ctx, err)
+		return
+	}
+
+	ctx.Status(nethttp.StatusOK)
+}
+
+// authenticate 支持 HTTP Basic 与表单两种客户端认证方式
+func authenticate(c context.Context, ctx *app.RequestContext) (*Client, error) {
+
+	id, secret, ok := ctx.Request.BasicAuth()
+
+	if !ok {
+		id, secret = ctx.PostForm("client_id"), ctx.PostForm("client_secret")
+	}
+
+	return Authenticate(c, id, secret)
+}
+
+func failure(ctx *app.RequestContext, err error) {
+
+	var e *Error
+
+	if !errors.As(err, &e) {
+		e = ErrServerError
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(e.Status, e)
+}
@@ -0,0 +1,144 @@
+package oauth2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+const (
+	GrantOfAuthorizationCode = "authorization_code"
+	GrantOfClientCredentials = "client_credentials"
+	GrantOfRefreshToken      = "refresh_token"
+)
+
+// Client 第三方客户端，密钥仅保存 bcrypt 哈希
+type Client struct {
+	ID        string    `gorm:"column:id;primaryKey;size:64" json:"id"`
+	Name      string    `gorm:"column:name;size:120;not null" json:"name"`
+	Secret    string    `gorm:"column:secret;size:255;not null" json:"-"`
+	Redirects string    `gorm:"column:redirects;type:text" json:"redirects"` // 多个回调地址以空格分隔
+	Grants    string    `gorm:"column:grants;size:255" json:"grants"`        // 多个授权类型以空格分隔
+	Scopes    string    `gorm:"column:scopes;type:text" json:"scopes"`       // 多个 scope 以空格分隔
+	IsEnable  uint8     `gorm:"column:is_enable;default:1" json:"is_enable"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (c *Client) TableName() string {
+	return facades.Config().GetString("auth.oauth2.table", "sys_oauth_client")
+}
+
+func (c *Client) HasRedirect(redirect string) bool {
+	return lo.Contains(strings.Fields(c.Redirects), redirect)
+}
+
+func (c *Client) HasGrant(grant string) bool {
+	return lo.Contains(strings.Fields(c.Grants), grant)
+}
+
+func (c *Client) GetScopes() []string {
+	return Scopes(c.Scopes)
+}
+
+func (c *Client) CheckSecret(secret string) bool {
+	return secret != "" && auth.CheckPassword(secret, c.Secret)
+}
+
+// Migrate 创建客户端表
+func Migrate() error {
+	return DB().AutoMigrate(&Client{})
+}
+
+// DB 客户端表所在的数据库连接
+func DB() *gorm.DB {
+	return facades.Database().Default()
+}
+
+// CreateClient
+//
+//	@Description: 注册第三方客户端，scope 必须是 auth.permissions 中登记的权限码
+//	@param name	客户端名称
+//	@param redirects	允许的回调地址
+//	@param grants	允许的授权类型
+//	@param scopes	允许申请的 scope
+//	@return client
+//	@return secret	明文密钥，只在创建时返回一次
+func CreateClient(ctx context.Context, name string, redirects, grants, scopes []string) (client *Client, secret string, err error) {
+
+	if lo.IsEmpty(name) {
+		return nil, "", errors.New("client name cannot be empty")
+	}
+
+	if len(grants) == 0 {
+		return nil, "", errors.New("client grants cannot be empty")
+	}
+
+	if unknown, _ := lo.Difference(grants, []string{GrantOfAuthorizationCode, GrantOfClientCredentials, GrantOfRefreshToken}); len(unknown) > 0 {
+		return nil, "", errors.New("unsupported grant: " + strings.Join(unknown, ","))
+	}
+
+	if lo.Contains(grants, GrantOfAuthorizationCode) && len(redirects) == 0 {
+		return nil, "", errors.New("client redirects cannot be empty")
+	}
+
+	if !Registered(scopes) {
+		return nil, "", ErrInvalidScope
+	}
+
+	secret = random(36)
+
+	client = &Client{
+		ID:        random(18),
+		Name:      name,
+		Secret:    auth.Password(secret),
+		Redirects: strings.Join(redirects, " "),
+		Grants:    strings.Join(grants, " "),
+		Scopes:    strings.Join(lo.Uniq(scopes), " "),
+		IsEnable:  support.YES,
+	}
+
+	if err = DB().WithContext(ctx).Create(client).Error; err != nil {
+		return nil, "", err
+	}
+
+	return client, secret, nil
+}
+
+// ResetSecret 重置客户端密钥，返回新的明文密钥
+func ResetSecret(ctx context.Context, id string) (secret string, err error) {
+
+	secret = random(36)
+
+	tx := DB().WithContext(ctx).Model(&Client{}).Where("id = ?", id).Update("secret", auth.Password(secret))
+
+	if tx.Error != nil {
+		return "", tx.Error
+	} else if tx.RowsAffected == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+
+	return secret, nil
+}
+
+func FindClient(ctx context.Context, id string) (*Client, error) {
+
+	var client Client
+
+	if err := DB().WithContext(ctx).Where("id = ?", id).First(&client).Error; err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+func DeleteClient(ctx context.Context, id string) error {
+	return DB().WithContext(ctx).Where("id = ?", id).Delete(&Client{}).Error
+}
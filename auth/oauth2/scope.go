@@ -0,0 +1,85 @@
+package oauth2
+
+import (
+	"strings"
+
+	contractauth "github.com/herhe-com/framework/contracts/auth"
+	"github.com/herhe-com/framework/facades"
+	"github.com/samber/lo"
+)
+
+// Scopes 解析以空格分隔的 scope
+func Scopes(scope string) []string {
+	return lo.Uniq(strings.Fields(scope))
+}
+
+// Allowed 判断 scope 是否覆盖权限码：scope 与权限码相同，或者是权限码的上级目录（如 user 覆盖 user.list）
+func Allowed(scopes []string, permission string) bool {
+
+	for _, item := range scopes {
+		if item == permission || strings.HasPrefix(permission, item+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Checker 实现 contractauth.Scope，OAuth2 令牌只能访问 scope 覆盖的权限
+type Checker struct{}
+
+func (Checker) Restricted(claims *contractauth.Claims) bool {
+	return IsToken(claims)
+}
+
+func (Checker) Allowed(claims *contractauth.Claims, permission string) bool {
+	return Allowed(ClaimScopes(claims), permission)
+}
+
+func (Checker) Subjectless(claims *contractauth.Claims) bool {
+	return IsClient(claims)
+}
+
+// Registered 判断 scope 是否为 auth.permissions 中登记的权限码（含目录）
+func Registered(scopes []string) bool {
+
+	codes := make(map[string]struct{})
+
+	trees, _ := facades.Config().Get("auth.trees").([]contractauth.Tree)
+
+	collect(trees, codes)
+
+	if len(codes) == 0 {
+		return false
+	}
+
+	for _, item := range scopes {
+		if _, ok := codes[item]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Subset 判断 requested 是否都在 granted 的覆盖范围内
+func Subset(requested, granted []string) bool {
+
+	for _, item := range requested {
+		if !Allowed(granted, item) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func collect(trees []contractauth.Tree, codes map[string]struct{}) {
+
+	for _, item := range trees {
+
+		codes[item.Code] = struct{}{}
+
+		collect(item.Children, codes)
+	}
+}
@@ -0,0 +1,50 @@
+package oauth2
+
+import "testing"
+
+func TestAllowedCoversChildPermissions(t *testing.T) {
+	scopes := Scopes("user order.list user")
+
+	if len(scopes) != 2 {
+		t.Fatalf("expected duplicated scopes to be removed, got %v", scopes)
+	}
+
+	for _, permission := range []string{"user", "user.list", "user.detail.edit", "order.list"} {
+		if !Allowed(scopes, permission) {
+			t.Fatalf("expected %s to be allowed by %v", permission, scopes)
+		}
+	}
+
+	for _, permission := range []string{"users.list", "order", "order.detail"} {
+		if Allowed(scopes, permission) {
+			t.Fatalf("expected %s to be denied by %v", permission, scopes)
+		}
+	}
+}
+
+func TestSubset(t *testing.T) {
+	granted := []string{"user", "order.list"}
+
+	if !Subset([]string{"user.list", "order.list"}, granted) {
+		t.Fatal("expected narrower scopes to be accepted")
+	}
+
+	if Subset([]string{"order"}, granted) {
+		t.Fatal("expected wider scopes to be rejected")
+	}
+}
+
+func TestClientAttributes(t *testing.T) {
+	client := Client{
+		Redirects: "https://a.example.com/callback https://b.example.com/callback",
+		Grants:    GrantOfAuthorizationCode + " " + GrantOfRefreshToken,
+	}
+
+	if !client.HasRedirect("https://b.example.com/callback") || client.HasRedirect("https://b.example.com") {
+		t.Fatal("expected redirect uri to match exactly")
+	}
+
+	if !client.HasGrant(GrantOfRefreshToken) || client.HasGrant(GrantOfClientCredentials) {
+		t.Fatal("unexpected grant check result")
+	}
+}
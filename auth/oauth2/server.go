@@ -0,0 +1,477 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/herhe-com/framework/auth"
+	contractauth "github.com/herhe-com/framework/contracts/auth"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+)
+
+const (
+	ClaimOfClient = "client_id"
+	ClaimOfScope  = "scope"
+	ClaimOfGrant  = "grant"
+)
+
+func init() {
+	// 引入本包即启用 middleware.Permission 的 scope 校验，只校验令牌的资源服务也需要引入
+	facades.Register[contractauth.Scope](Checker{})
+}
+
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type" form:"response_type" query:"response_type"`
+	ClientID            string `json:"client_id" form:"client_id" query:"client_id"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri" query:"redirect_uri"`
+	Scope               string `json:"scope" form:"scope" query:"scope"`
+	State               string `json:"state" form:"state" query:"state"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge" query:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method" query:"code_challenge_method"`
+}
+
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// Introspection RFC 7662 令牌内省结果
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// grant 授权码与刷新令牌在 Redis 中保存的授权信息
+type grant struct {
+	ClientID    string `json:"client_id"`
+	Subject     string `json:"subject"`
+	Scope       string `json:"scope"`
+	RedirectURI string `json:"redirect_uri,omitempty"`
+	Challenge   string `json:"challenge,omitempty"`
+	Grant       string `json:"grant,omitempty"` // 刷新令牌对应的原始授权类型，刷新后的访问令牌沿用
+}
+
+// Authenticate 校验客户端身份
+func Authenticate(ctx context.Context, id, secret string) (*Client, error) {
+
+	if lo.IsEmpty(id) {
+		return nil, ErrInvalidClient
+	}
+
+	client, err := FindClient(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidClient
+	} else if err != nil {
+		return nil, err
+	}
+
+	if client.IsEnable != support.YES || !client.CheckSecret(secret) {
+		return nil, ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+// Authorize
+//
+//	@Description: 用户同意授权后签发授权码，授权码模式强制要求 PKCE（S256）
+//	@param request	授权请求
+//	@param subject	当前登录用户
+//	@return redirect	携带授权码的回调地址
+func Authorize(ctx context.Context, request AuthorizeRequest, subject string) (redirect string, err error) {
+
+	if request.ResponseType != "code" {
+		return "", describe(ErrInvalidRequest, "response_type must be code")
+	}
+
+	if request.CodeChallenge == "" || request.CodeChallengeMethod != "S256" {
+		return "", describe(ErrInvalidRequest, "PKCE with S256 is required")
+	}
+
+	client, err := FindClient(ctx, request.ClientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrInvalidClient
+	} else if err != nil {
+		return "", err
+	}
+
+	if client.IsEnable != support.YES || !client.HasGrant(GrantOfAuthorizationCode) {
+		return "", ErrUnauthorizedClient
+	}
+
+	if !client.HasRedirect(request.RedirectURI) {
+		return "", describe(ErrInvalidRequest, "redirect_uri is not registered")
+	}
+
+	scopes := Scopes(request.Scope)
+
+	if len(scopes) == 0 {
+		scopes = client.GetScopes()
+	} else if !Subset(scopes, client.GetScopes()) {
+		return "", ErrInvalidScope
+	}
+
+	code := random(36)
+
+	data := grant{
+		ClientID:    client.ID,
+		Subject:     subject,
+		Scope:       strings.Join(scopes, " "),
+		RedirectURI: request.RedirectURI,
+		Challenge:   request.CodeChallenge,
+	}
+
+	expires := time.Duration(facades.Config().GetInt("auth.oauth2.code_lifetime", 10)) * time.Minute
+
+	if err = put(ctx, keyOfCode(code), data, expires); err != nil {
+		return "", err
+	}
+
+	uri, err := url.Parse(request.RedirectURI)
+	if err != nil {
+		return "", describe(ErrInvalidRequest, "redirect_uri is invalid")
+	}
+
+	query := uri.Query()
+	query.Set("code", code)
+
+	if request.State != "" {
+		query.Set("state", request.State)
+	}
+
+	uri.RawQuery = query.Encode()
+
+	return uri.String(), nil
+}
+
+// ExchangeCode 使用授权码换取令牌
+func ExchangeCode(ctx context.Context, client *Client, code, redirect, verifier string) (*Token, error) {
+
+	if !client.HasGrant(GrantOfAuthorizationCode) {
+		return nil, ErrUnauthorizedClient
+	}
+
+	data, err := take(ctx, keyOfCode(code))
+	if err != nil {
+		return nil, err
+	}
+
+	if data.ClientID != client.ID || data.RedirectURI != redirect {
+		return nil, ErrInvalidGrant
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+
+	if subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(data.Challenge)) != 1 {
+		return nil, describe(ErrInvalidGrant, "code_verifier mismatch")
+	}
+
+	return issue(ctx, client, data.Subject, Scopes(data.Scope), GrantOfAuthorizationCode)
+}
+
+// ClientCredentials 客户端凭证模式，令牌主体为客户端本身
+func ClientCredentials(ctx context.Context, client *Client, scope string) (*Token, error) {
+
+	if !client.HasGrant(GrantOfClientCredentials) {
+		return nil, ErrUnauthorizedClient
+	}
+
+	scopes := Scopes(scope)
+
+	if len(scopes) == 0 {
+		scopes = client.GetScopes()
+	} else if !Subset(scopes, client.GetScopes()) {
+		return nil, ErrInvalidScope
+	}
+
+	return issue(ctx, client, SubjectOfClient(client.ID), scopes, GrantOfClientCredentials)
+}
+
+// Refresh 使用刷新令牌换取新令牌，旧的刷新令牌会被作废（轮换）
+func Refresh(ctx context.Context, client *Client, token, scope string) (*Token, error) {
+
+	if !client.HasGrant(GrantOfRefreshToken) {
+		return nil, ErrUnauthorizedClient
+	}
+
+	data, err := take(ctx, keyOfRefresh(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if data.ClientID != client.ID {
+		return nil, ErrInvalidGrant
+	}
+
+	scopes := Scopes(data.Scope)
+
+	if requested := Scopes(scope); len(requested) > 0 {
+
+		if !Subset(requested, scopes) {
+			return nil, ErrInvalidScope
+		}
+
+		scopes = requested
+	}
+
+	// 升级前签发的刷新令牌没有记录授权类型，只可能来自授权码模式
+	grantType := lo.Ternary(data.Grant == "", GrantOfAuthorizationCode, data.Grant)
+
+	return issue(ctx, client, data.Subject, scopes, grantType)
+}
+
+// Introspect 查询令牌状态，只能查询客户端自己签发的令牌
+func Introspect(ctx context.Context, client *Client, token string) (*Introspection, error) {
+
+	if data, err := find(ctx, keyOfRefresh(token)); err == nil {
+
+		if data.ClientID != client.ID {
+			return &Introspection{Active: false}, nil
+		}
+
+		return &Introspection{
+			Active:    true,
+			Scope:     data.Scope,
+			ClientID:  data.ClientID,
+			Subject:   data.Subject,
+			TokenType: GrantOfRefreshToken,
+		}, nil
+	}
+
+	claims, ok := access(ctx, token)
+
+	if !ok || ClaimOfClientID(claims) != client.ID {
+		return &Introspection{Active: false}, nil
+	}
+
+	return &Introspection{
+		Active:    true,
+		Scope:     strings.Join(ClaimScopes(claims), " "),
+		ClientID:  ClaimOfClientID(claims),
+		Subject:   claims.Subject,
+		TokenType: "access_token",
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		IssuedAt:  claims.IssuedAt.Unix(),
+	}, nil
+}
+
+// Revoke 吊销令牌（RFC 7009），访问令牌写入黑名单，middleware.Auth 会拒绝已吊销的令牌
+func Revoke(ctx context.Context, client *Client, token string) error {
+
+	if data, err := find(ctx, keyOfRefresh(token)); err == nil {
+
+		if data.ClientID == client.ID {
+			return facades.Redis().Default().Del(ctx, keyOfRefresh(token)).Err()
+		}
+
+		return nil
+	}
+
+	claims, ok := access(ctx, token)
+
+	if !ok || ClaimOfClientID(claims) != client.ID {
+		return nil
+	}
+
+	expires := time.Until(claims.ExpiresAt.Time)
+
+	if expires <= 0 {
+		return nil
+	}
+
+	if !auth.Blacklist(ctx, time.Now().Unix(), expires, auth.KeyBlacklist("jwt", claims.ID)) {
+		return ErrServerError
+	}
+
+	return nil
+}
+
+// IsToken 判断令牌是否由 OAuth2 服务签发，按签发时设置的 typ 判断，不按扩展声明判断
+func IsToken(claims *contractauth.Claims) bool {
+	return claims != nil && claims.Type == auth.TokenOfOAuth2
+}
+
+// IsClient 判断令牌是否为客户端凭证模式签发（无用户身份）
+func IsClient(claims *contractauth.Claims) bool {
+	return claims != nil && claims.Ext != nil && cast.ToString(claims.Ext[ClaimOfGrant]) == GrantOfClientCredentials
+}
+
+func ClaimOfClientID(claims *contractauth.Claims) string {
+
+	if claims == nil || claims.Ext == nil {
+		return ""
+	}
+
+	return cast.ToString(claims.Ext[ClaimOfClient])
+}
+
+func ClaimScopes(claims *contractauth.Claims) []string {
+
+	if claims == nil || claims.Ext == nil {
+		return nil
+	}
+
+	return Scopes(cast.ToString(claims.Ext[ClaimOfScope]))
+}
+
+func SubjectOfClient(id string) string {
+	return "client:" + id
+}
+
+func issue(ctx context.Context, client *Client, subject string, scopes []string, grantType string) (*Token, error) {
+
+	lifetime := facades.Config().GetInt("auth.oauth2.lifetime", 60)
+
+	scope := strings.Join(scopes, " ")
+
+	claims := auth.NewClaims(subject, lifetime, false, map[string]any{
+		ClaimOfClient: client.ID,
+		ClaimOfScope:  scope,
+		ClaimOfGrant:  grantType,
+	})
+
+	claims.Type = auth.TokenOfOAuth2
+
+	access, err := auth.MakeJWToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	token := Token{
+		AccessToken: access,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(lifetime * 60),
+		Scope:       scope,
+	}
+
+	if grantType != GrantOfClientCredentials && client.HasGrant(GrantOfRefreshToken) {
+
+		token.RefreshToken = random(36)
+
+		expires := time.Duration(facades.Config().GetInt("auth.oauth2.refresh_lifetime", 30)) * 24 * time.Hour
+
+		data := grant{
+			ClientID: client.ID,
+			Subject:  subject,
+			Scope:    scope,
+			Grant:    grantType,
+		}
+
+		if err = put(ctx, keyOfRefresh(token.RefreshToken), data, expires); err != nil {
+			return nil, err
+		}
+	}
+
+	return &token, nil
+}
+
+// access 校验访问令牌签名、有效期及黑名单
+func access(ctx context.Context, token string) (*contractauth.Claims, bool) {
+
+	var claims contractauth.Claims
+
+	if refresh, err := auth.CheckJWToken(&claims, token); err != nil || refresh {
+		return nil, false
+	}
+
+	if auth.CheckBlacklist(ctx, auth.KeyBlacklist("jwt", claims.ID)) {
+		return nil, false
+	}
+
+	return &claims, true
+}
+
+func put(ctx context.Context, key string, data grant, expires time.Duration) error {
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return errors.New("please initialize Redis first")
+	}
+
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return cache.Default().Set(ctx, key, value, expires).Err()
+}
+
+func find(ctx context.Context, key string) (*grant, error) {
+	return read(ctx, key, false)
+}
+
+func take(ctx context.Context, key string) (*grant, error) {
+	return read(ctx, key, true)
+}
+
+func read(ctx context.Context, key string, remove bool) (*grant, error) {
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return nil, errors.New("please initialize Redis first")
+	}
+
+	var value []byte
+	var err error
+
+	if remove {
+		value, err = cache.Default().GetDel(ctx, key).Bytes()
+	} else {
+		value, err = cache.Default().Get(ctx, key).Bytes()
+	}
+
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidGrant
+	} else if err != nil {
+		return nil, err
+	}
+
+	var data grant
+
+	if err = json.Unmarshal(value, &data); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// random 授权码、刷新令牌及客户端凭证使用 crypto/rand 生成，size 为随机字节数
+func random(size int) string {
+
+	buf := make([]byte, size)
+
+	// Go 1.24 起 crypto/rand.Read 不会返回错误
+	_, _ = rand.Read(buf)
+
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func keyOfCode(code string) string {
+	return util.Keys("oauth2", "code", code)
+}
+
+func keyOfRefresh(token string) string {
+	return util.Keys("oauth2", "refresh", token)
+}
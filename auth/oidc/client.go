@@ -0,0 +1,229 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/facades"
+	"github.com/samber/lo"
+	"golang.org/x/oauth2"
+)
+
+var clients sync.Map
+
+// Provider 外部身份提供方（IdP）配置
+type Provider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Identity 从 ID Token 中解析出的外部身份
+type Identity struct {
+	Provider      string         `json:"provider"`
+	Subject       string         `json:"sub"`
+	Email         string         `json:"email,omitempty"`
+	EmailVerified bool           `json:"email_verified,omitempty"`
+	Name          string         `json:"name,omitempty"`
+	Picture       string         `json:"picture,omitempty"`
+	Claims        map[string]any `json:"claims,omitempty"`
+}
+
+// Mapping 将外部身份映射为本地用户，返回的 subject 与 ext 会传给 auth.NewJWToken
+type Mapping func(ctx context.Context, identity *Identity) (subject string, ext map[string]any, err error)
+
+type Client struct {
+	provider Provider
+	oauth    *oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+
+	Store    Store
+	Mapping  Mapping
+	Expires  time.Duration // state 有效期
+	Lifetime int           // 本地 token 生存时间（分钟）
+	Refresh  bool          // 本地 token 是否可被刷新
+}
+
+// New
+//
+//	@Description: 根据 auth.oidc.providers.<name> 创建客户端，客户端会按名称缓存
+//	@param name	IdP 名称
+func New(ctx context.Context, name string) (*Client, error) {
+
+	if client, ok := clients.Load(name); ok {
+		return client.(*Client), nil
+	}
+
+	key := "auth.oidc.providers." + name
+
+	if !facades.Config().IsSet(key) {
+		return nil, fmt.Errorf("oidc provider %s is not configured", name)
+	}
+
+	provider := Provider{
+		Name:         name,
+		Issuer:       facades.Config().GetString(key + ".issuer"),
+		ClientID:     facades.Config().GetString(key + ".client_id"),
+		ClientSecret: facades.Config().GetString(key + ".client_secret"),
+		RedirectURL:  facades.Config().GetString(key + ".redirect_url"),
+		Scopes:       facades.Config().GetStrings(key + ".scopes"),
+	}
+
+	client, err := NewClient(ctx, provider, &RedisStore{})
+	if err != nil {
+		return nil, err
+	}
+
+	client.Expires = time.Duration(facades.Config().GetInt("auth.oidc.expires", 10)) * time.Minute
+	client.Lifetime = facades.Config().GetInt("auth.oidc.lifetime", facades.Config().GetInt("jwt.lifetime"))
+	client.Refresh = facades.Config().GetBool("auth.oidc.refresh", true)
+
+	switch mapping := facades.Config().Get("auth.oidc.mapping").(type) {
+	case Mapping:
+		client.Mapping = mapping
+	case func(ctx context.Context, identity *Identity) (string, map[string]any, error):
+		client.Mapping = mapping
+	}
+
+	actual, _ := clients.LoadOrStore(name, client)
+
+	return actual.(*Client), nil
+}
+
+// NewClient 通过 discovery 文档（/.well-known/openid-configuration）初始化客户端
+func NewClient(ctx context.Context, provider Provider, store Store) (*Client, error) {
+
+	if lo.IsEmpty(provider.Issuer) || lo.IsEmpty(provider.ClientID) {
+		return nil, errors.New("oidc issuer and client id cannot be empty")
+	}
+
+	discovery, err := gooidc.NewProvider(ctx, provider.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := provider.Scopes
+
+	if !lo.Contains(scopes, gooidc.ScopeOpenID) {
+		scopes = append([]string{gooidc.ScopeOpenID}, scopes...)
+	}
+
+	return &Client{
+		provider: provider,
+		oauth: &oauth2.Config{
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Endpoint:     discovery.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: discovery.Verifier(&gooidc.Config{ClientID: provider.ClientID}),
+		Store:    store,
+		Expires:  10 * time.Minute,
+		Refresh:  true,
+	}, nil
+}
+
+// AuthCodeURL 生成授权地址，state、nonce 与 PKCE verifier 会写入 Store，三者都由 crypto/rand 生成
+func (c *Client) AuthCodeURL(ctx context.Context) (string, error) {
+
+	state := oauth2.GenerateVerifier()
+
+	data := State{
+		Provider: c.provider.Name,
+		Nonce:    oauth2.GenerateVerifier(),
+		Verifier: oauth2.GenerateVerifier(),
+	}
+
+	if err := c.Store.Put(ctx, state, data, c.Expires); err != nil {
+		return "", err
+	}
+
+	return c.oauth.AuthCodeURL(state, gooidc.Nonce(data.Nonce), oauth2.S256ChallengeOption(data.Verifier)), nil
+}
+
+// Exchange
+//
+//	@Description: 回调时使用授权码换取 token，并校验 state、PKCE、ID Token 签名及 nonce
+//	@param state	回调中的 state
+//	@param code	回调中的授权码
+func (c *Client) Exchange(ctx context.Context, state, code string) (*Identity, error) {
+
+	data, err := c.Store.Take(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Provider != c.provider.Name {
+		return nil, ErrStateNotFound
+	}
+
+	token, err := c.oauth.Exchange(ctx, code, oauth2.VerifierOption(data.Verifier))
+	if err != nil {
+		return nil, err
+	}
+
+	raw, ok := token.Extra("id_token").(string)
+	if !ok || raw == "" {
+		return nil, errors.New("id token is missing in token response")
+	}
+
+	idToken, err := c.verifier.Verify(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if idToken.Nonce != data.Nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	var identity Identity
+
+	if err = idToken.Claims(&identity); err != nil {
+		return nil, err
+	}
+
+	if err = idToken.Claims(&identity.Claims); err != nil {
+		return nil, err
+	}
+
+	identity.Provider = c.provider.Name
+	identity.Subject = idToken.Subject
+
+	return &identity, nil
+}
+
+// Login 完成回调并通过 Mapping 签发本地 token
+func (c *Client) Login(ctx context.Context, state, code string) (token string, identity *Identity, err error) {
+
+	if c.Mapping == nil {
+		return "", nil, errors.New("oidc mapping is not configured")
+	}
+
+	if identity, err = c.Exchange(ctx, state, code); err != nil {
+		return "", nil, err
+	}
+
+	subject, ext, err := c.Mapping(ctx, identity)
+	if err != nil {
+		return "", identity, err
+	}
+
+	if lo.IsEmpty(subject) {
+		return "", identity, errors.New("oidc mapping returned an empty subject")
+	}
+
+	if token, err = auth.NewJWToken(subject, c.Lifetime, c.Refresh, ext); err != nil {
+		return "", identity, err
+	}
+
+	return token, identity, nil
+}
@@ -0,0 +1,254 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	contractauth "github.com/herhe-com/framework/contracts/auth"
+	contractconfig "github.com/herhe-com/framework/contracts/config"
+	"github.com/herhe-com/framework/facades"
+)
+
+type fakeConfig struct {
+	values map[string]any
+}
+
+func (f fakeConfig) Env(key string, defaultValue ...any) any {
+	return f.Get(key, defaultValue...)
+}
+
+func (f fakeConfig) Add(name string, configuration map[string]any) {}
+
+func (f fakeConfig) Set(key string, configuration any) {}
+
+func (f fakeConfig) Get(key string, defaultValue ...any) any {
+	if value, ok := f.values[key]; ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetString(key string, defaultValue ...string) string {
+	if value, ok := f.values[key]; ok {
+		return fmt.Sprint(value)
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return ""
+}
+
+func (f fakeConfig) GetStrings(key string, defaultValue ...[]string) []string {
+	return nil
+}
+
+func (f fakeConfig) GetMaps(key string, defaultValue ...map[string]any) map[string]any {
+	return nil
+}
+
+func (f fakeConfig) GetInt(key string, defaultValue ...int) int {
+	return 0
+}
+
+func (f fakeConfig) GetInt64(key string, defaultValue ...int64) int64 {
+	return 0
+}
+
+func (f fakeConfig) GetBool(key string, defaultValue ...bool) bool {
+	return false
+}
+
+func (f fakeConfig) IsSet(key string) bool {
+	_, ok := f.values[key]
+	return ok
+}
+
+// stubIdP 本地模拟的 OpenID Connect 身份提供方
+type stubIdP struct {
+	server     *httptest.Server
+	key        *rsa.PrivateKey
+	challenges map[string]string
+	nonces     map[string]string
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &stubIdP{key: key, challenges: map[string]string{}, nonces: map[string]string{}}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]any{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "stub",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+
+		code := r.PostForm.Get("code")
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenges[code] {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            idp.server.URL,
+			"aud":            "client",
+			"sub":            "external-1",
+			"email":          "user@example.com",
+			"email_verified": true,
+			"nonce":          idp.nonces[code],
+			"iat":            time.Now().Unix(),
+			"exp":            time.Now().Add(time.Minute).Unix(),
+		})
+		token.Header["kid"] = "stub"
+
+		signed, _ := token.SignedString(key)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   60,
+			"id_token":     signed,
+		})
+	})
+
+	idp.server = httptest.NewServer(mux)
+
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+// authorize 模拟用户在 IdP 完成授权，返回授权码
+func (idp *stubIdP) authorize(t *testing.T, location string) (state, code string) {
+	uri, err := url.Parse(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := uri.Query()
+
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected PKCE S256 challenge, got %q", query.Get("code_challenge_method"))
+	}
+
+	code = "code-" + query.Get("state")
+
+	idp.challenges[code] = query.Get("code_challenge")
+	idp.nonces[code] = query.Get("nonce")
+
+	return query.Get("state"), code
+}
+
+func TestLoginAgainstStubIdP(t *testing.T) {
+	original := facades.Container()
+	facades.SetContainer(&facades.Services{})
+	facades.Register[contractconfig.Application](fakeConfig{
+		values: map[string]any{
+			"app.name":   "framework",
+			"jwt.sub":    "api",
+			"jwt.secret": "test-secret",
+		},
+	})
+	t.Cleanup(func() {
+		facades.SetContainer(original)
+	})
+
+	idp := newStubIdP(t)
+
+	ctx := context.Background()
+
+	client, err := NewClient(ctx, Provider{
+		Name:        "stub",
+		Issuer:      idp.server.URL,
+		ClientID:    "client",
+		RedirectURL: "http://localhost/callback",
+		Scopes:      []string{"email"},
+	}, &MemoryStore{})
+	if err != nil {
+		t.Fatalf("expected discovery to succeed: %v", err)
+	}
+
+	client.Lifetime = 5
+	client.Mapping = func(ctx context.Context, identity *Identity) (string, map[string]any, error) {
+		return "local-" + identity.Subject, map[string]any{"email": identity.Email}, nil
+	}
+
+	location, err := client.AuthCodeURL(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, code := idp.authorize(t, location)
+
+	token, identity, err := client.Login(ctx, state, code)
+	if err != nil {
+		t.Fatalf("expected login to succeed: %v", err)
+	}
+
+	if identity.Subject != "external-1" || identity.Email != "user@example.com" || !identity.EmailVerified {
+		t.Fatalf("unexpected identity %#v", identity)
+	}
+
+	var claims contractauth.Claims
+
+	if _, err = jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		return []byte("test-secret"), nil
+	}); err != nil {
+		t.Fatalf("expected local token to be valid: %v", err)
+	}
+
+	if claims.Subject != "local-external-1" {
+		t.Fatalf("expected mapped subject, got %q", claims.Subject)
+	}
+
+	if _, _, err = client.Login(ctx, state, code); err != ErrStateNotFound {
+		t.Fatalf("expected state to be single use, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrStateNotFound = errors.New("oidc state is invalid or expired")
+
+// State 授权请求中需要在回调时校验的临时数据
+type State struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Redirect string `json:"redirect,omitempty"`
+}
+
+// Store state / nonce 的存储，回调时只能取出一次
+type Store interface {
+	Put(ctx context.Context, key string, state State, expires time.Duration) error
+	Take(ctx context.Context, key string) (*State, error)
+}
+
+type RedisStore struct {
+}
+
+func (s *RedisStore) Put(ctx context.Context, key string, state State, expires time.Duration) error {
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return errors.New("please initialize Redis first")
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return cache.Default().Set(ctx, KeyOfState(key), data, expires).Err()
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string) (*State, error) {
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return nil, errors.New("please initialize Redis first")
+	}
+
+	data, err := cache.Default().GetDel(ctx, KeyOfState(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrStateNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var state State
+
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// MemoryStore 进程内存储，仅适用于单实例或测试
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]memoryState
+}
+
+type memoryState struct {
+	state   State
+	expires time.Time
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, state State, expires time.Duration) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.states == nil {
+		s.states = make(map[string]memoryState)
+	}
+
+	s.states[key] = memoryState{state: state, expires: time.Now().Add(expires)}
+
+	return nil
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string) (*State, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.states[key]
+	if !ok {
+		return nil, ErrStateNotFound
+	}
+
+	delete(s.states, key)
+
+	if time.Now().After(item.expires) {
+		return nil, ErrStateNotFound
+	}
+
+	return &item.state, nil
+}
+
+func KeyOfState(state string) string {
+	return util.Keys("oidc", "state", state)
+}
@@ -0,0 +1,96 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Breached 使用配置中的泄露密码库检查密码
+func Breached(password string) (bool, error) {
+	return NewPolicy().IsBreached(password)
+}
+
+// IsBreached
+//
+//	@Description: 以 k-anonymity 的方式检查密码是否出现在泄露密码库中：
+//	只使用 SHA-1 前 5 位定位区间，再在区间内比对剩余后缀。
+//	泄露密码库支持两种格式：
+//	1. 目录：按前缀拆分的区间文件 <PREFIX>.txt，每行 SUFFIX[:COUNT]（与 HIBP range 接口一致）
+//	2. 文件：每行 HASH[:COUNT]
+//	@param password	明文密码
+func (p *Policy) IsBreached(password string) (bool, error) {
+
+	if p.Breached == "" {
+		return false, errors.New("breached password list is not configured")
+	}
+
+	sum := sha1.Sum([]byte(password))
+
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	prefix, suffix := hash[:5], hash[5:]
+
+	suffixes, err := p.ranges(prefix)
+	if err != nil {
+		return false, err
+	}
+
+	for _, item := range suffixes {
+		if item == suffix {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ranges 读取前缀对应区间内的所有哈希后缀
+func (p *Policy) ranges(prefix string) (suffixes []string, err error) {
+
+	info, err := os.Stat(p.Breached)
+	if err != nil {
+		return nil, err
+	}
+
+	file := p.Breached
+
+	if info.IsDir() {
+
+		file = filepath.Join(p.Breached, prefix+".txt")
+
+		if _, err = os.Stat(file); errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+	}
+
+	fp, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	defer fp.Close()
+
+	scanner := bufio.NewScanner(fp)
+
+	for scanner.Scan() {
+
+		line := strings.ToUpper(strings.TrimSpace(scanner.Text()))
+
+		if index := strings.Index(line, ":"); index >= 0 {
+			line = line[:index]
+		}
+
+		if info.IsDir() {
+			suffixes = append(suffixes, line)
+		} else if len(line) == 40 && strings.HasPrefix(line, prefix) {
+			suffixes = append(suffixes, line[5:])
+		}
+	}
+
+	return suffixes, scanner.Err()
+}
@@ -0,0 +1,119 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func rules(violations []Violation, err error) []string {
+
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	return lo.Map(violations, func(item Violation, index int) string {
+		return item.Rule
+	})
+}
+
+func TestViolationsReturnsAllFailedRules(t *testing.T) {
+	policy := &Policy{
+		MinLength:  10,
+		Upper:      true,
+		Digit:      true,
+		Symbol:     true,
+		Classes:    3,
+		Dictionary: []string{"admin"},
+	}
+
+	got := rules(policy.Violations("admin"))
+
+	for _, rule := range []string{RuleOfMinLength, RuleOfUpper, RuleOfDigit, RuleOfSymbol, RuleOfClasses, RuleOfDictionary} {
+		if !lo.Contains(got, rule) {
+			t.Fatalf("expected rule %s to be violated, got %v", rule, got)
+		}
+	}
+
+	if violations, err := policy.Violations("Correct-Horse-9"); err != nil || len(violations) != 0 {
+		t.Fatalf("expected strong password to pass, got %v %v", violations, err)
+	}
+}
+
+func TestViolationsChecksRecentHistoryOnly(t *testing.T) {
+	older, _ := bcrypt.GenerateFromPassword([]byte("Previous-Secret-1"), bcrypt.MinCost)
+	recent, _ := bcrypt.GenerateFromPassword([]byte("Current-Secret-2"), bcrypt.MinCost)
+
+	policy := &Policy{History: 1}
+
+	if !lo.Contains(rules(policy.Violations("Current-Secret-2", string(recent), string(older))), RuleOfHistory) {
+		t.Fatal("expected reuse of the latest password to be rejected")
+	}
+
+	if lo.Contains(rules(policy.Violations("Previous-Secret-1", string(recent), string(older))), RuleOfHistory) {
+		t.Fatal("expected passwords older than the history window to be accepted")
+	}
+}
+
+func TestExpired(t *testing.T) {
+	policy := &Policy{MaxAge: 90}
+
+	if !policy.Expired(time.Now().AddDate(0, 0, -91)) {
+		t.Fatal("expected password older than max age to be expired")
+	}
+
+	if policy.Expired(time.Now().AddDate(0, 0, -1)) {
+		t.Fatal("expected recent password to be valid")
+	}
+
+	if (&Policy{}).Expired(time.Now().AddDate(-10, 0, 0)) {
+		t.Fatal("expected password to never expire without max age")
+	}
+}
+
+func TestViolationsReturnsBreachedErrors(t *testing.T) {
+
+	policy := &Policy{MinLength: 10, Breached: filepath.Join(t.TempDir(), "missing.txt")}
+
+	violations, err := policy.Violations("short")
+
+	if err == nil || !lo.ContainsBy(violations, func(item Violation) bool { return item.Rule == RuleOfMinLength }) {
+		t.Fatalf("expected breached error with other violations, got %v %v", violations, err)
+	}
+
+	if policy.Valid("Correct-Horse-9") {
+		t.Fatal("expected unreadable breached list to fail validation")
+	}
+}
+
+func TestIsBreached(t *testing.T) {
+	// SHA-1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte("1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "breached.txt")
+
+	if err := os.WriteFile(file, []byte("5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{dir, file} {
+
+		policy := &Policy{Breached: path}
+
+		if breached, err := policy.IsBreached("password"); err != nil || !breached {
+			t.Fatalf("expected password to be breached in %s, got %v %v", path, breached, err)
+		}
+
+		if breached, err := policy.IsBreached("Correct-Horse-9"); err != nil || breached {
+			t.Fatalf("expected password to be clean in %s, got %v %v", path, breached, err)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package password
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/herhe-com/framework/facades"
+	"github.com/samber/lo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	RuleOfMinLength  = "min_length"
+	RuleOfMaxLength  = "max_length"
+	RuleOfLower      = "lower"
+	RuleOfUpper      = "upper"
+	RuleOfDigit      = "digit"
+	RuleOfSymbol     = "symbol"
+	RuleOfClasses    = "classes"
+	RuleOfDictionary = "dictionary"
+	RuleOfHistory    = "history"
+	RuleOfBreached   = "breached"
+)
+
+// Policy 密码策略，字段为零值时表示不启用对应规则
+type Policy struct {
+	MinLength  int
+	MaxLength  int
+	Lower      bool
+	Upper      bool
+	Digit      bool
+	Symbol     bool
+	Classes    int      // 至少包含的字符类别数量（小写、大写、数字、符号）
+	Dictionary []string // 禁止包含的字典词
+	History    int      // 禁止与最近 N 次密码重复
+	MaxAge     int      // 密码最长有效期（天）
+	Breached   string   // 泄露密码库路径，可以是单个文件或按 SHA-1 前缀拆分的目录
+}
+
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// NewPolicy 从 auth.password.* 读取密码策略
+func NewPolicy() *Policy {
+
+	cfg := facades.Config()
+
+	policy := &Policy{
+		MinLength:  cfg.GetInt("auth.password.min_length", 8),
+		MaxLength:  cfg.GetInt("auth.password.max_length", 64),
+		Lower:      cfg.GetBool("auth.password.lower", false),
+		Upper:      cfg.GetBool("auth.password.upper", false),
+		Digit:      cfg.GetBool("auth.password.digit", false),
+		Symbol:     cfg.GetBool("auth.password.symbol", false),
+		Classes:    cfg.GetInt("auth.password.classes", 0),
+		Dictionary: cfg.GetStrings("auth.password.dictionary"),
+		History:    cfg.GetInt("auth.password.history", 0),
+		MaxAge:     cfg.GetInt("auth.password.max_age", 0),
+	}
+
+	if breached := cfg.GetString("auth.password.breached"); breached != "" {
+
+		if !filepath.IsAbs(breached) {
+			breached = facades.Root() + "/" + strings.TrimLeft(breached, "/")
+		}
+
+		policy.Breached = breached
+	}
+
+	return policy
+}
+
+// Violations 使用配置中的策略校验密码，返回所有未通过的规则
+func Violations(password string, histories ...string) ([]Violation, error) {
+	return NewPolicy().Violations(password, histories...)
+}
+
+// Valid 使用配置中的策略校验密码
+func Valid(password string, histories ...string) bool {
+	return NewPolicy().Valid(password, histories...)
+}
+
+// Expired 判断密码自 changed 起是否已超过配置的最长有效期
+func Expired(changed time.Time) bool {
+	return NewPolicy().Expired(changed)
+}
+
+// Violations
+//
+//	@Description: 校验密码并返回所有未通过的规则
+//	@param password	明文密码
+//	@param histories	历史密码哈希（bcrypt），按时间倒序排列
+//	@return violations
+//	@return err	泄露密码库读取失败，其他规则的结果仍然返回
+func (p *Policy) Violations(password string, histories ...string) (violations []Violation, err error) {
+
+	violations = make([]Violation, 0)
+
+	length := len([]rune(password))
+
+	if p.MinLength > 0 && length < p.MinLength {
+		violations = append(violations, Violation{Rule: RuleOfMinLength, Message: "password is too short"})
+	}
+
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{Rule: RuleOfMaxLength, Message: "password is too long"})
+	}
+
+	var lower, upper, digit, symbol bool
+
+	for _, item := range password {
+		switch {
+		case unicode.IsLower(item):
+			lower = true
+		case unicode.IsUpper(item):
+			upper = true
+		case unicode.IsDigit(item):
+			digit = true
+		case unicode.IsPunct(item) || unicode.IsSymbol(item) || unicode.IsSpace(item):
+			symbol = true
+		}
+	}
+
+	if p.Lower && !lower {
+		violations = append(violations, Violation{Rule: RuleOfLower, Message: "password must contain a lowercase letter"})
+	}
+
+	if p.Upper && !upper {
+		violations = append(violations, Violation{Rule: RuleOfUpper, Message: "password must contain an uppercase letter"})
+	}
+
+	if p.Digit && !digit {
+		violations = append(violations, Violation{Rule: RuleOfDigit, Message: "password must contain a digit"})
+	}
+
+	if p.Symbol && !symbol {
+		violations = append(violations, Violation{Rule: RuleOfSymbol, Message: "password must contain a symbol"})
+	}
+
+	if classes := lo.Count([]bool{lower, upper, digit, symbol}, true); p.Classes > 0 && classes < p.Classes {
+		violations = append(violations, Violation{Rule: RuleOfClasses, Message: "password does not contain enough character classes"})
+	}
+
+	lowered := strings.ToLower(password)
+
+	for _, word := range p.Dictionary {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" && strings.Contains(lowered, word) {
+			violations = append(violations, Violation{Rule: RuleOfDictionary, Message: "password contains a forbidden word"})
+			break
+		}
+	}
+
+	if p.Reused(password, histories...) {
+		violations = append(violations, Violation{Rule: RuleOfHistory, Message: "password has been used recently"})
+	}
+
+	if p.Breached != "" {
+
+		var breached bool
+
+		if breached, err = p.IsBreached(password); err != nil {
+			return violations, err
+		}
+
+		if breached {
+			violations = append(violations, Violation{Rule: RuleOfBreached, Message: "password has appeared in a data breach"})
+		}
+	}
+
+	return violations, nil
+}
+
+// Valid 校验密码，泄露密码库读取失败时记录日志并视为不通过
+func (p *Policy) Valid(password string, histories ...string) bool {
+
+	violations, err := p.Violations(password, histories...)
+	if err != nil {
+		hlog.Errorf("failed to check breached password: %v", err)
+		return false
+	}
+
+	return len(violations) == 0
+}
+
+// Reused 判断密码是否与最近 History 次的密码哈希重复
+func (p *Policy) Reused(password string, histories ...string) bool {
+
+	if p.History <= 0 {
+		return false
+	}
+
+	if len(histories) > p.History {
+		histories = histories[:p.History]
+	}
+
+	for _, item := range histories {
+		if bcrypt.CompareHashAndPassword([]byte(item), []byte(password)) == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Expired 判断密码是否已超过最长有效期，MaxAge 为 0 时永不过期
+func (p *Policy) Expired(changed time.Time) bool {
+
+	if p.MaxAge <= 0 {
+		return false
+	}
+
+	return time.Now().After(changed.AddDate(0, 0, p.MaxAge))
+}
@@ -0,0 +1,170 @@
+package permission
+
+import (
+	"fmt"
+
+	"github.com/herhe-com/framework/auth"
+	contractauth "github.com/herhe-com/framework/contracts/auth"
+	"github.com/herhe-com/framework/facades"
+	"github.com/samber/lo"
+)
+
+// 以下方法的 subject 与 role 均为已命名的主体，如 auth.NameOfUser(id)、auth.NameOfRole(id)
+
+// Grant 为主体授予平台（或平台下某个组织）的权限
+func Grant(subject string, platform uint16, organization string, permissions ...string) error {
+
+	if err := Check(platform, permissions...); err != nil {
+		return err
+	}
+
+	_, err := facades.Casbin().AddPoliciesEx(rules(subject, auth.Domain(platform, organization), permissions))
+
+	return err
+}
+
+// Revoke 撤销主体在平台（或平台下某个组织）的权限
+func Revoke(subject string, platform uint16, organization string, permissions ...string) error {
+
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	_, err := facades.Casbin().RemovePolicies(rules(subject, auth.Domain(platform, organization), permissions))
+
+	return err
+}
+
+// Replace 使用给定权限覆盖主体在平台（或平台下某个组织）的全部权限
+func Replace(subject string, platform uint16, organization string, permissions ...string) error {
+
+	if err := Check(platform, permissions...); err != nil {
+		return err
+	}
+
+	domain := auth.Domain(platform, organization)
+
+	if _, err := facades.Casbin().RemoveFilteredPolicy(0, subject, "", domain); err != nil {
+		return err
+	}
+
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	_, err := facades.Casbin().AddPoliciesEx(rules(subject, domain, permissions))
+
+	return err
+}
+
+// Permissions 主体在平台（或平台下某个组织）拥有的权限码，包含通过角色、平台作用域和上级组织继承的权限
+func Permissions(subject string, platform uint16, organization string) ([]string, error) {
+
+	permissions := make([]string, 0)
+
+	for _, domain := range auth.Domains(platform, organization) {
+
+		policies, err := facades.Casbin().GetImplicitPermissionsForUser(subject, domain)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range policies {
+			if len(item) > 1 {
+				permissions = append(permissions, item[1])
+			}
+		}
+	}
+
+	return lo.Uniq(permissions), nil
+}
+
+// AssignRoles 在平台（或平台下某个组织）为主体分配角色，平台为 0 时对全部平台生效；角色也可以作为主体继承其他角色
+func AssignRoles(subject string, platform uint16, organization string, roles ...string) error {
+
+	if len(roles) == 0 {
+		return nil
+	}
+
+	_, err := facades.Casbin().AddRolesForUser(subject, roles, auth.Domain(platform, organization))
+
+	return err
+}
+
+// RemoveRoles 移除主体在平台（或平台下某个组织）的角色
+func RemoveRoles(subject string, platform uint16, organization string, roles ...string) error {
+
+	domain := auth.Domain(platform, organization)
+
+	for _, item := range roles {
+		if _, err := facades.Casbin().DeleteRoleForUser(subject, item, domain); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Roles 主体在平台（或平台下某个组织）直接拥有的角色，包含平台作用域和全部平台的角色
+func Roles(subject string, platform uint16, organization string) ([]string, error) {
+	return facades.Casbin().GetRolesForUser(subject, auth.Domain(platform, organization))
+}
+
+// Attach 设置组织的上级组织，上级组织中的授权对下级组织同样有效，如门店隶属于区域、区域隶属于集团
+func Attach(platform uint16, organization string, parentPlatform uint16, parentOrganization string) error {
+
+	_, err := facades.Casbin().AddNamedGroupingPolicy("g2", auth.Domain(platform, organization), auth.Domain(parentPlatform, parentOrganization))
+
+	return err
+}
+
+// Detach 解除组织的全部上级组织
+func Detach(platform uint16, organization string) error {
+
+	_, err := facades.Casbin().RemoveFilteredNamedGroupingPolicy("g2", 0, auth.Domain(platform, organization))
+
+	return err
+}
+
+// Check 校验权限码是否为权限树中的权限节点，并且适用于指定平台
+func Check(platform uint16, permissions ...string) error {
+
+	trees, _ := facades.Config().Get("auth.trees").([]contractauth.Tree)
+
+	leaves := make(map[string][]uint16)
+
+	collect(trees, leaves)
+
+	for _, item := range permissions {
+
+		platforms, ok := leaves[item]
+
+		if !ok {
+			return fmt.Errorf("permission %s is not registered", item)
+		}
+
+		if platform > 0 && !lo.Contains(platforms, platform) {
+			return fmt.Errorf("permission %s is not available on platform %d", item, platform)
+		}
+	}
+
+	return nil
+}
+
+func collect(trees []contractauth.Tree, leaves map[string][]uint16) {
+
+	for _, item := range trees {
+
+		if len(item.Children) == 0 {
+			leaves[item.Code] = item.Platforms
+		}
+
+		collect(item.Children, leaves)
+	}
+}
+
+func rules(subject, domain string, permissions []string) [][]string {
+	return lo.Map(lo.Uniq(permissions), func(item string, index int) []string {
+		return []string{subject, item, domain}
+	})
+}
@@ -0,0 +1,34 @@
+package permission
+
+import (
+	"time"
+
+	"github.com/herhe-com/framework/facades"
+	"gorm.io/gorm"
+)
+
+// Model 同步到数据库中的权限树节点
+type Model struct {
+	Code      string    `gorm:"column:code;primaryKey;size:191" json:"code"`
+	Parent    string    `gorm:"column:parent;size:191;index" json:"parent"`
+	Name      string    `gorm:"column:name;size:120;not null" json:"name"`
+	Platforms string    `gorm:"column:platforms;size:255" json:"platforms"` // 适用平台，多个以逗号分隔，目录节点为空
+	IsLeaf    bool      `gorm:"column:is_leaf" json:"is_leaf"`
+	Sort      int       `gorm:"column:sort" json:"sort"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (m *Model) TableName() string {
+	return facades.Config().GetString("auth.permission.table", "sys_permission")
+}
+
+// Migrate 创建权限表
+func Migrate() error {
+	return DB().AutoMigrate(&Model{})
+}
+
+// DB 权限表所在的数据库连接
+func DB() *gorm.DB {
+	return facades.Database().Default()
+}
@@ -0,0 +1,441 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/casbin/casbin/v3"
+	adapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/glebarez/sqlite"
+	"github.com/herhe-com/framework/auth"
+	contractauth "github.com/herhe-com/framework/contracts/auth"
+	contractconfig "github.com/herhe-com/framework/contracts/config"
+	"github.com/herhe-com/framework/contracts/database"
+	"github.com/herhe-com/framework/facades"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+type fakeConfig struct {
+	values map[string]any
+}
+
+func (f fakeConfig) Env(key string, defaultValue ...any) any {
+	return f.Get(key, defaultValue...)
+}
+
+func (f fakeConfig) Add(name string, configuration map[string]any) {}
+
+func (f fakeConfig) Set(key string, configuration any) {
+	f.values[key] = configuration
+}
+
+func (f fakeConfig) Get(key string, defaultValue ...any) any {
+	if value, ok := f.values[key]; ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetString(key string, defaultValue ...string) string {
+	if value, ok := f.values[key]; ok {
+		return fmt.Sprint(value)
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return ""
+}
+
+func (f fakeConfig) GetStrings(key string, defaultValue ...[]string) []string {
+	return nil
+}
+
+func (f fakeConfig) GetMaps(key string, defaultValue ...map[string]any) map[string]any {
+	return nil
+}
+
+func (f fakeConfig) GetInt(key string, defaultValue ...int) int {
+	if value, ok := f.values[key].(int); ok {
+		return value
+	}
+
+	return 0
+}
+
+func (f fakeConfig) GetInt64(key string, defaultValue ...int64) int64 {
+	return 0
+}
+
+func (f fakeConfig) GetBool(key string, defaultValue ...bool) bool {
+	return false
+}
+
+func (f fakeConfig) IsSet(key string) bool {
+	_, ok := f.values[key]
+	return ok
+}
+
+type fakeDatabase struct {
+	db *gorm.DB
+}
+
+func (f fakeDatabase) Default() *gorm.DB {
+	return f.db
+}
+
+func (f fakeDatabase) Drivers(driver string, names ...string) (*gorm.DB, error) {
+	return f.db, nil
+}
+
+func setup(t *testing.T, trees []contractauth.Tree) fakeConfig {
+	original := facades.Container()
+	facades.SetContainer(&facades.Services{})
+	t.Cleanup(func() {
+		facades.SetContainer(original)
+	})
+
+	cfg := fakeConfig{values: map[string]any{"auth.trees": trees}}
+	facades.Register[contractconfig.Application](cfg)
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "permission.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	facades.Register[database.DB](fakeDatabase{db: db})
+
+	a, err := adapter.NewAdapterByDBUseTableName(db, "", "sys_casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enforcer, err := auth.NewEnforcer(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	facades.Register[*casbin.SyncedEnforcer](enforcer)
+
+	return cfg
+}
+
+func tree(leaves ...string) []contractauth.Tree {
+	return []contractauth.Tree{{
+		Code: "user",
+		Name: "用户管理",
+		Children: lo.Map(leaves, func(item string, index int) contractauth.Tree {
+			return contractauth.Tree{Code: "user." + item, Name: item, Platforms: []uint16{auth.CodeOfStore}}
+		}),
+	}}
+}
+
+func TestSyncUpsertsTreeAndCleansRemovedPolicies(t *testing.T) {
+	cfg := setup(t, tree("list", "detail"))
+
+	ctx := context.Background()
+
+	result, err := Sync(ctx)
+	if err != nil {
+		t.Fatalf("expected sync to succeed: %v", err)
+	}
+
+	if len(result.Created) != 3 {
+		t.Fatalf("expected 3 created codes, got %v", result.Created)
+	}
+
+	subject := auth.NameOfRole("manager")
+
+	if err = Grant(subject, auth.CodeOfStore, "", "user.list", "user.detail"); err != nil {
+		t.Fatalf("expected grant to succeed: %v", err)
+	}
+
+	if err = AssignRoles(auth.NameOfUser("1"), auth.CodeOfStore, "", subject); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := auth.Enforce(auth.NameOfUser("1"), "user.detail", auth.CodeOfStore); !ok {
+		t.Fatal("expected user to inherit permission from role")
+	}
+
+	cfg.Set("auth.trees", tree("list"))
+
+	if result, err = Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Removed) != 1 || result.Removed[0] != "user.detail" {
+		t.Fatalf("expected user.detail to be removed, got %v", result.Removed)
+	}
+
+	permissions, err := Permissions(auth.NameOfUser("1"), auth.CodeOfStore, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(permissions) != 1 || permissions[0] != "user.list" {
+		t.Fatalf("expected only user.list to remain, got %v", permissions)
+	}
+
+	var count int64
+	DB().Model(&Model{}).Count(&count)
+
+	if count != 2 {
+		t.Fatalf("expected 2 permission rows, got %d", count)
+	}
+}
+
+func TestSyncKeepsPoliciesOutsideRemovedCodes(t *testing.T) {
+	setup(t, tree("list"))
+
+	ctx := context.Background()
+
+	if _, err := Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 共用策略表的其他服务或应用自行添加的策略
+	if _, err := facades.Casbin().AddPolicy(auth.NameOfRole("manager"), "order.list", auth.Domain(auth.CodeOfStore, "")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if policies, _ := facades.Casbin().GetFilteredPolicy(1, "order.list"); len(policies) != 1 {
+		t.Fatalf("expected policy of another service to be kept, got %v", policies)
+	}
+}
+
+func TestGrantRejectsUnknownOrUnavailablePermissions(t *testing.T) {
+	setup(t, tree("list"))
+
+	if err := Grant(auth.NameOfRole("manager"), auth.CodeOfStore, "", "user.delete"); err == nil {
+		t.Fatal("expected unknown permission to be rejected")
+	}
+
+	if err := Grant(auth.NameOfRole("manager"), auth.CodeOfPlatform, "", "user.list"); err == nil {
+		t.Fatal("expected permission unavailable on the platform to be rejected")
+	}
+}
+
+func TestEnforceWithOrganizationDomains(t *testing.T) {
+	setup(t, tree("list", "detail"))
+
+	role := auth.NameOfRole("manager")
+	user := auth.NameOfUser("2")
+
+	// 角色模板定义在平台作用域，对平台下全部门店生效
+	if err := Grant(role, auth.CodeOfStore, "", "user.list"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AssignRoles(user, auth.CodeOfStore, "s1", role); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := auth.Enforce(user, "user.list", auth.CodeOfStore, "s1"); !ok {
+		t.Fatal("expected role in store s1 to allow user.list")
+	}
+
+	if ok, _ := auth.Enforce(user, "user.list", auth.CodeOfStore, "s2"); ok {
+		t.Fatal("expected role in store s1 not to apply to store s2")
+	}
+
+	// 集团授权对下属门店生效，权限树中的权限只适用于门店，这里直接写入策略
+	if _, err := facades.Casbin().AddPolicy(user, "user.detail", auth.Domain(auth.CodeOfClique, "c1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := auth.Enforce(user, "user.detail", auth.CodeOfStore, "s2"); ok {
+		t.Fatal("expected clique grant not to apply before the store is attached")
+	}
+
+	if err := Attach(auth.CodeOfStore, "s2", auth.CodeOfClique, "c1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := auth.Enforce(user, "user.detail", auth.CodeOfStore, "s2"); !ok {
+		t.Fatal("expected clique grant to apply to attached store")
+	}
+
+	permissions, err := Permissions(user, auth.CodeOfStore, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(permissions) != 1 || permissions[0] != "user.list" {
+		t.Fatalf("expected user.list in store s1, got %v", permissions)
+	}
+
+	if err = Detach(auth.CodeOfStore, "s2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := auth.Enforce(user, "user.detail", auth.CodeOfStore, "s2"); ok {
+		t.Fatal("expected clique grant to stop applying after detach")
+	}
+
+	developer := auth.NameOfUser("3")
+
+	if err = AssignRoles(developer, 0, "", auth.NameOfDeveloper()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !auth.IsDeveloper(developer) || auth.IsDeveloper(user) {
+		t.Fatal("expected only the user assigned in all domains to be a developer")
+	}
+}
+
+func TestEnforceDuringPolicyReload(t *testing.T) {
+	setup(t, tree("list"))
+
+	role := auth.NameOfRole("manager")
+	user := auth.NameOfUser("2")
+
+	if err := Grant(role, auth.CodeOfStore, "", "user.list"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AssignRoles(user, auth.CodeOfStore, "s1", role); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+
+	// watcher 收到其他节点的消息后在自己的 goroutine 中重新加载策略
+	go func() {
+		defer close(done)
+		for range 20 {
+			_ = facades.Casbin().LoadPolicy()
+		}
+	}()
+
+	for range 200 {
+		if ok, err := auth.Enforce(user, "user.list", auth.CodeOfStore, "s1"); err != nil || !ok {
+			t.Fatalf("expected user.list to stay allowed during reload, got %v %v", ok, err)
+		}
+	}
+
+	<-done
+}
+
+func TestDecisionCacheIsClearedOnPolicyChange(t *testing.T) {
+	cfg := setup(t, tree("list"))
+	cfg.Set("auth.casbin.cache", 60)
+	t.Cleanup(func() {
+		auth.Forget()
+	})
+
+	watcher, err := auth.NewWatcher(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = facades.Casbin().SetWatcher(watcher); err != nil {
+		t.Fatal(err)
+	}
+
+	user := auth.NameOfUser("4")
+
+	if err = Grant(user, auth.CodeOfStore, "s1", "user.list"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := auth.Enforce(user, "user.list", auth.CodeOfStore, "s1"); !ok {
+		t.Fatal("expected granted permission to be allowed")
+	}
+
+	// 不通知 Watcher 时，决策来自缓存
+	facades.Casbin().EnableAutoNotifyWatcher(false)
+
+	if _, err = facades.Casbin().RemovePolicy(user, "user.list", auth.Domain(auth.CodeOfStore, "s1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := auth.Enforce(user, "user.list", auth.CodeOfStore, "s1"); !ok {
+		t.Fatal("expected cached decision to be returned")
+	}
+
+	auth.Forget(user)
+
+	if ok, _ := auth.Enforce(user, "user.list", auth.CodeOfStore, "s1"); ok {
+		t.Fatal("expected decision to be recomputed after forget")
+	}
+
+	facades.Casbin().EnableAutoNotifyWatcher(true)
+
+	if err = Grant(user, auth.CodeOfStore, "s1", "user.list"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := auth.Enforce(user, "user.list", auth.CodeOfStore, "s1"); !ok {
+		t.Fatal("expected policy change to clear cached denial")
+	}
+}
+
+func TestNewEnforcerRejectsOutdatedModel(t *testing.T) {
+	cfg := setup(t, tree("list"))
+
+	path := filepath.Join(t.TempDir(), "casbin.conf")
+
+	outdated := "[request_definition]\nr = sub, obj\n\n[policy_definition]\np = sub, obj\n\n[role_definition]\ng = _, _\n\n[policy_effect]\ne = some(where (p.eft == allow))\n\n[matchers]\nm = g(r.sub, p.sub) && r.obj == p.obj\n"
+
+	if err := os.WriteFile(path, []byte(outdated), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Set("auth.casbin.model", path)
+
+	a, err := adapter.NewAdapterByDBUseTableName(DB(), "", "sys_casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = auth.NewEnforcer(a); !errors.Is(err, auth.ErrOutdatedModel) {
+		t.Fatalf("expected outdated model to be rejected, got %v", err)
+	}
+}
+
+func TestMigratePoliciesAssignsDomainOfAll(t *testing.T) {
+	setup(t, tree("list"))
+
+	DB().Exec("INSERT INTO sys_casbin (ptype, v0, v1) VALUES ('g', ?, ?), ('p', ?, 'user.list')", auth.NameOfUser("1"), auth.NameOfDeveloper(), auth.NameOfRole("manager"))
+
+	count, err := auth.MigratePolicies(DB(), "sys_casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 2 {
+		t.Fatalf("expected 2 migrated rules, got %d", count)
+	}
+
+	a, err := adapter.NewAdapterByDBUseTableName(DB(), "", "sys_casbin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enforcer, err := auth.NewEnforcer(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	facades.Register[*casbin.SyncedEnforcer](enforcer)
+
+	if !auth.IsDeveloper(auth.NameOfUser("1")) {
+		t.Fatal("expected migrated role to apply to all domains")
+	}
+}
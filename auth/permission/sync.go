@@ -0,0 +1,143 @@
+package permission
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	contractauth "github.com/herhe-com/framework/contracts/auth"
+	"github.com/herhe-com/framework/facades"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Result struct {
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+	Removed []string `json:"removed"`
+}
+
+// Sync
+//
+//	@Description: 将 auth.permissions 生成的权限树写入数据库，已从代码中移除的权限会被删除，并清理这些权限码的 Casbin 策略；
+//	其他服务或应用自行添加的策略不会清理
+//	@return result	新增、更新、移除的权限码
+func Sync(ctx context.Context) (result *Result, err error) {
+
+	trees, ok := facades.Config().Get("auth.trees").([]contractauth.Tree)
+	if !ok || len(trees) == 0 {
+		return nil, errors.New("permission trees are empty, please register auth.ServiceProvider first")
+	}
+
+	if err = Migrate(); err != nil {
+		return nil, err
+	}
+
+	models := Flatten(trees, "")
+
+	codes := lo.Map(models, func(item Model, index int) string {
+		return item.Code
+	})
+
+	var existing []string
+
+	if err = DB().WithContext(ctx).Model(&Model{}).Pluck("code", &existing).Error; err != nil {
+		return nil, err
+	}
+
+	created, removed := lo.Difference(codes, existing)
+
+	result = &Result{
+		Created: created,
+		Updated: lo.Intersect(codes, existing),
+		Removed: removed,
+	}
+
+	err = DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "code"}},
+			DoUpdates: clause.AssignmentColumns([]string{"parent", "name", "platforms", "is_leaf", "sort", "updated_at"}),
+		}).CreateInBatches(models, 200).Error; err != nil {
+			return err
+		}
+
+		if len(removed) > 0 {
+
+			if err := tx.Where("code IN ?", removed).Delete(&Model{}).Error; err != nil {
+				return err
+			}
+
+			// 策略清理失败时回滚权限表，下次同步仍然能找到这些权限码
+			if err := clean(removed); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// clean 只移除本次同步删除的权限码的策略，策略格式：(subject, permission, domain)
+func clean(removed []string) error {
+
+	policies, err := facades.Casbin().GetPolicy()
+	if err != nil {
+		return err
+	}
+
+	codes := lo.SliceToMap(removed, func(item string) (string, struct{}) {
+		return item, struct{}{}
+	})
+
+	stale := lo.Filter(policies, func(item []string, index int) bool {
+
+		if len(item) < 2 {
+			return false
+		}
+
+		_, ok := codes[item[1]]
+
+		return ok
+	})
+
+	if len(stale) == 0 {
+		return nil
+	}
+
+	_, err = facades.Casbin().RemovePolicies(stale)
+
+	return err
+}
+
+// Flatten 将权限树展开为数据库记录
+func Flatten(trees []contractauth.Tree, parent string) (models []Model) {
+
+	models = make([]Model, 0, len(trees))
+
+	for index, item := range trees {
+
+		models = append(models, Model{
+			Code:   item.Code,
+			Parent: parent,
+			Name:   item.Name,
+			Platforms: strings.Join(lo.Map(item.Platforms, func(platform uint16, index int) string {
+				return strconv.Itoa(int(platform))
+			}), ","),
+			IsLeaf: len(item.Children) == 0,
+			Sort:   index + 1,
+		})
+
+		models = append(models, Flatten(item.Children, item.Code)...)
+	}
+
+	return models
+}
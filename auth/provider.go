@@ -16,5 +16,6 @@ func (p *ServiceProvider) Register() (err error) {
 }
 
 func (p *ServiceProvider) Boot() error {
-	return nil
+	// Redis 可能晚于 auth 注册，Watcher 在 Boot 阶段设置
+	return watch()
 }
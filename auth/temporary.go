@@ -57,9 +57,7 @@ func SetTemporaryRole(c context.Context, ctx *app.RequestContext, platform uint1
 		data.Bak = backs[0]
 	}
 
-	lifetime := facades.Config().GetInt("jwt.lifetime")
-
-	expired := time.Hour * 2 * time.Duration(lifetime)
+	expired := TemporaryExpires()
 
 	if _, err = facades.Redis().Default().Set(c, RoleOfName(ID(ctx)), &data, expired).Result(); err != nil {
 		return err
@@ -81,9 +79,7 @@ func DeleteTemporaryRole(c context.Context, ctx *app.RequestContext) (err error)
 
 func RefreshTemporaryRole(c context.Context, ctx *app.RequestContext) (err error) {
 
-	lifetime := facades.Config().GetInt("jwt.lifetime")
-
-	expired := time.Hour * 2 * time.Duration(lifetime)
+	expired := TemporaryExpires()
 
 	_, err = facades.Redis().Default().Expire(c, RoleOfName(ID(ctx)), expired).Result()
 
@@ -94,6 +90,11 @@ func RefreshTemporaryRole(c context.Context, ctx *app.RequestContext) (err error
 	return nil
 }
 
+// TemporaryExpires 临时角色的有效期
+func TemporaryExpires() time.Duration {
+	return time.Hour * 2 * time.Duration(facades.Config().GetInt("jwt.lifetime"))
+}
+
 func RoleOfName(id string) string {
 
 	name := facades.Config().GetString("app.name")
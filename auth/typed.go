@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// NewTypedToken
+//
+//	@Description: 生成 JWT，并将自定义结构体写入扩展变量
+//	@param id 用户
+//	@param lifetime 生存时间（分钟）
+//	@param refresh 是否可被刷新
+//	@param data 自定义声明，按 json 标签展开到 ext 中
+func NewTypedToken[T any](id string, lifetime int, refresh bool, data T) (token string, err error) {
+
+	ext, err := ToExt(data)
+	if err != nil {
+		return "", err
+	}
+
+	return NewJWToken(id, lifetime, refresh, ext)
+}
+
+// TypedClaims 将请求上下文中 token 的扩展变量解析为自定义结构体，解析结果会缓存在上下文中
+func TypedClaims[T any](ctx *app.RequestContext) (data T, ok bool) {
+
+	key := fmt.Sprintf("%s:%T", ContextOfClaims, data)
+
+	if value, exist := ctx.Get(key); exist {
+		data, ok = value.(T)
+		return data, ok
+	}
+
+	claims := Claims(ctx)
+
+	if claims == nil {
+		return data, false
+	}
+
+	if err := FromExt(claims.Ext, &data); err != nil {
+		return data, false
+	}
+
+	ctx.Set(key, data)
+
+	return data, true
+}
+
+// ToExt 将自定义结构体转换为 token 扩展变量
+func ToExt(data any) (ext map[string]any, err error) {
+
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(buf, &ext); err != nil {
+		return nil, fmt.Errorf("typed claims must be a struct or map: %w", err)
+	}
+
+	return ext, nil
+}
+
+// FromExt 将 token 扩展变量解析为自定义结构体
+func FromExt(ext map[string]any, data any) error {
+
+	buf, err := json.Marshal(ext)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(buf, data)
+}
@@ -29,6 +29,7 @@ func NameOfRole(args ...any) string {
 	return Name(append([]any{"ROLE"}, args...)...)
 }
 
+// Deprecated: 权限策略改为 (subject, permission, domain)，作用域使用 Domain 生成，校验使用 Enforce
 func NameOfPermission(platform uint16, id *string, permission string) (permissions []string) {
 	permissions = append(permissions, strconv.Itoa(int(platform)))
 	if id != nil {
@@ -38,6 +39,22 @@ func NameOfPermission(platform uint16, id *string, permission string) (permissio
 	return permissions
 }
 
+// Domain 权限策略的作用域：平台，或平台 + 组织，平台为 0 时表示全部平台
+func Domain(platform uint16, organization ...string) string {
+
+	if platform == 0 {
+		return DomainOfAll
+	}
+
+	domain := strconv.Itoa(int(platform))
+
+	if len(organization) > 0 && organization[0] != "" {
+		domain += ":" + organization[0]
+	}
+
+	return domain
+}
+
 func NameOfDeveloper() string {
 	return NameOfRole(CodeOfDeveloper)
 }
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/casbin/casbin/v3/model"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/herhe-com/framework/audit"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/redis/go-redis/v9"
+)
+
+// Watcher 通过 Redis 发布订阅在多个节点之间广播 Casbin 策略变更
+//
+// 本节点修改策略后，Casbin 会调用 Update：清空本节点的决策缓存并发布消息；
+// 其他节点收到消息后重新加载策略，再清空各自的决策缓存。
+// client 为空时只在本节点清空决策缓存，不做广播。
+// Watcher 实现了 persist.WatcherEx，策略变更同时写入审计日志。
+type Watcher struct {
+	client   *redis.Client
+	pubsub   *redis.PubSub
+	channel  string
+	node     string
+	callback func(string)
+	mutex    sync.RWMutex
+	done     chan struct{}
+}
+
+// NewWatcher
+//
+//	@Description: 创建策略变更的 Watcher 并开始订阅
+//	@param client	Redis 连接，为空时只清空本节点的决策缓存
+//	@param channel	发布订阅的频道，为空时使用 KeyOfWatcher()
+func NewWatcher(client *redis.Client, channel string) (*Watcher, error) {
+
+	if channel == "" {
+		channel = KeyOfWatcher()
+	}
+
+	node := make([]byte, 8)
+
+	if _, err := rand.Read(node); err != nil {
+		return nil, err
+	}
+
+	watcher := &Watcher{
+		client:  client,
+		channel: channel,
+		node:    hex.EncodeToString(node),
+		done:    make(chan struct{}),
+	}
+
+	if client == nil {
+		return watcher, nil
+	}
+
+	watcher.pubsub = client.Subscribe(context.Background(), channel)
+
+	// 等待订阅确认，保证返回后不会漏掉其他节点的消息
+	if _, err := watcher.pubsub.Receive(context.Background()); err != nil {
+		_ = watcher.pubsub.Close()
+		return nil, err
+	}
+
+	go watcher.listen()
+
+	return watcher, nil
+}
+
+func (w *Watcher) SetUpdateCallback(callback func(string)) error {
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.callback = callback
+
+	return nil
+}
+
+func (w *Watcher) Update() error {
+
+	Forget()
+
+	if w.client == nil {
+		return nil
+	}
+
+	return w.client.Publish(context.Background(), w.channel, w.node).Err()
+}
+
+func (w *Watcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.changed("add", sec, ptype, params)
+}
+
+func (w *Watcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.changed("remove", sec, ptype, params)
+}
+
+func (w *Watcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.changed("remove_filtered", sec, ptype, map[string]any{"index": fieldIndex, "values": fieldValues})
+}
+
+func (w *Watcher) UpdateForSavePolicy(model model.Model) error {
+	return w.changed("save", "", "", nil)
+}
+
+func (w *Watcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.changed("add", sec, ptype, rules)
+}
+
+func (w *Watcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.changed("remove", sec, ptype, rules)
+}
+
+// changed 记录策略变更的审计日志后广播，g 开头的分组策略（角色分配、组织层级）记为角色变更
+func (w *Watcher) changed(operation, sec, ptype string, rules any) error {
+
+	event := &audit.Event{
+		Type:   audit.TypeOfPolicyChanged,
+		Result: audit.ResultOfSuccess,
+		Metadata: map[string]any{
+			"operation": operation,
+			"ptype":     ptype,
+		},
+	}
+
+	if sec == "g" || strings.HasPrefix(ptype, "g") {
+		event.Type = audit.TypeOfRoleChanged
+	}
+
+	if rules != nil {
+		event.Metadata["rules"] = rules
+	}
+
+	if err := audit.Record(context.Background(), event); err != nil {
+		hlog.Errorf("failed to record casbin policy change: %v", err)
+	}
+
+	return w.Update()
+}
+
+func (w *Watcher) Close() {
+
+	select {
+	case <-w.done:
+		return
+	default:
+		close(w.done)
+	}
+
+	if w.pubsub != nil {
+		_ = w.pubsub.Close()
+	}
+}
+
+func (w *Watcher) listen() {
+
+	messages := w.pubsub.Channel()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case message, ok := <-messages:
+
+			if !ok {
+				return
+			}
+
+			// 忽略本节点发布的消息
+			if message.Payload == w.node {
+				continue
+			}
+
+			w.mutex.RLock()
+			callback := w.callback
+			w.mutex.RUnlock()
+
+			if callback != nil {
+				callback(message.Payload)
+			}
+
+			Forget()
+		}
+	}
+}
+
+// KeyOfWatcher 策略变更的广播频道
+func KeyOfWatcher() string {
+	return util.Keys("casbin", "policy")
+}
+
+// watch 根据 auth.casbin.watcher、auth.casbin.cache、audit.sinks 为执行器设置 Watcher
+func watch() error {
+
+	broadcast := facades.Config().GetBool("auth.casbin.watcher")
+
+	if !broadcast && decisionLifetime() <= 0 && !audit.Enabled() {
+		return nil
+	}
+
+	var client *redis.Client
+
+	if broadcast {
+
+		cache, ok := facades.OptionalRedis()
+		if !ok {
+			return errors.New("please initialize Redis first")
+		}
+
+		client = cache.Default()
+	}
+
+	watcher, err := NewWatcher(client, facades.Config().GetString("auth.casbin.channel"))
+	if err != nil {
+		return err
+	}
+
+	if err = facades.Casbin().SetWatcher(watcher); err != nil {
+		return err
+	}
+
+	// WatcherEx 需要自行设置收到其他节点消息后的回调
+	return watcher.SetUpdateCallback(func(string) {
+		_ = facades.Casbin().LoadPolicy()
+	})
+}
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/redis/go-redis/v9"
+)
+
+// 把缓存 key 加入标签，标签的有效期取最长的缓存有效期，新建的标签 TTL 为 -1
+var tagScript = redis.NewScript(`
+	for _, tag in ipairs(KEYS) do
+		redis.call("SADD", tag, ARGV[1])
+
+		local ttl = redis.call("TTL", tag)
+		if ttl < tonumber(ARGV[2]) then
+			redis.call("EXPIRE", tag, ARGV[2])
+		end
+	end
+
+	return 1
+`)
+
+// 删除标签下的全部缓存和标签本身
+var flushScript = redis.NewScript(`
+	local count = 0
+
+	for _, tag in ipairs(KEYS) do
+		local keys = redis.call("SMEMBERS", tag)
+
+		for i = 1, #keys, 500 do
+			count = count + redis.call("DEL", unpack(keys, i, math.min(i + 499, #keys)))
+		end
+
+		redis.call("DEL", tag)
+	end
+
+	return count
+`)
+
+// Tag
+//
+//	@Description: 给缓存打上标签，之后通过 Flush 按标签批量删除
+//	@param key	缓存的 key
+//	@param ttl	缓存的有效期，标签的有效期不会短于它
+//	@param tags	标签，如 permissions、dictionaries
+func Tag(c context.Context, key string, ttl time.Duration, tags ...string) error {
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return errors.New("please initialize Redis first")
+	}
+
+	keys := make([]string, len(tags))
+
+	for index, tag := range tags {
+		keys[index] = KeyOfTag(tag)
+	}
+
+	return tagScript.Run(c, cache.Default(), keys, key, int64(ttl.Seconds())).Err()
+}
+
+// Flush 删除标签下的全部缓存，数据变更后在 service 中调用，返回删除的缓存数量
+func Flush(c context.Context, tags ...string) (int64, error) {
+
+	if len(tags) == 0 {
+		return 0, nil
+	}
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return 0, errors.New("please initialize Redis first")
+	}
+
+	keys := make([]string, len(tags))
+
+	for index, tag := range tags {
+		keys[index] = KeyOfTag(tag)
+	}
+
+	return flushScript.Run(c, cache.Default(), keys).Int64()
+}
+
+// KeyOfTag 标签在 Redis 中的 key，值为缓存 key 的集合
+func KeyOfTag(tag string) string {
+	return util.Keys("cache", "tag", tag)
+}
@@ -0,0 +1,117 @@
+package consoles
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+
+	"github.com/gookit/color"
+	"github.com/herhe-com/framework/auth/permission"
+	"github.com/herhe-com/framework/contracts/console"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/http/route"
+	"github.com/spf13/cobra"
+)
+
+type PermissionProvider struct {
+}
+
+func (that *PermissionProvider) Register() console.Console {
+
+	return console.Console{
+		Cmd:  "permission",
+		Name: "权限管理",
+		Consoles: []console.Console{
+			{
+				Cmd:     "sync",
+				Name:    "同步权限",
+				Summary: "将 auth.permissions 生成的权限树同步到数据库，已移除的权限会同时清理 Casbin 策略",
+				Run:     that.sync,
+			},
+			{
+				Cmd:     "routes",
+				Name:    "路由权限",
+				Summary: "遍历 server.route 注册的路由，生成权限树，并列出路由与 auth.permissions 不一致的权限码",
+				Run:     that.routes,
+				Tags: func(cmd *cobra.Command) {
+					cmd.Flags().StringP("output", "o", "", "生成的权限树写入的 Go 文件，为空时只输出比对结果")
+					cmd.Flags().StringP("package", "p", "config", "生成文件的包名")
+					cmd.Flags().StringP("name", "n", "Permissions", "生成文件的变量名")
+				},
+			},
+		},
+	}
+}
+
+func (that *PermissionProvider) sync(cmd *cobra.Command, args []string) {
+
+	result, err := permission.Sync(context.Background())
+
+	if err != nil {
+		color.Errorf("\n\n权限同步失败：%v\n\n", err)
+		return
+	}
+
+	color.Successf("\n\n权限同步成功：新增 %d，更新 %d，移除 %d\n", len(result.Created), len(result.Updated), len(result.Removed))
+
+	if len(result.Created) > 0 {
+		color.Infof("新增：%s\n", strings.Join(result.Created, ", "))
+	}
+
+	if len(result.Removed) > 0 {
+		color.Warnf("移除：%s\n", strings.Join(result.Removed, ", "))
+	}
+
+	color.Println()
+}
+
+func (that *PermissionProvider) routes(cmd *cobra.Command, args []string) {
+
+	register, ok := facades.Config().Get("server.route").(func(route *server.Hertz))
+	if !ok {
+		color.Errorf("\n\n请先配置 server.route\n\n")
+		return
+	}
+
+	// 只注册路由，不启动服务
+	serv := server.New(server.WithDisablePrintRoute(true))
+
+	register(serv)
+
+	report := route.Inspect(serv.Engine)
+
+	color.Successf("\n\n共 %d 个路由声明了权限\n", len(report.Routes))
+
+	if len(report.Missing) > 0 {
+		color.Warnf("权限树中缺少：%s\n", strings.Join(report.Missing, ", "))
+	}
+
+	if len(report.Unused) > 0 {
+		color.Warnf("没有路由使用：%s\n", strings.Join(report.Unused, ", "))
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+
+	if output != "" {
+
+		pkg, _ := cmd.Flags().GetString("package")
+		name, _ := cmd.Flags().GetString("name")
+
+		source, err := route.Source(pkg, name, report.Permissions)
+		if err != nil {
+			color.Errorf("\n生成权限树失败：%v\n\n", err)
+			return
+		}
+
+		if err = os.WriteFile(output, source, 0644); err != nil {
+			color.Errorf("\n写入文件失败：%v\n\n", err)
+			return
+		}
+
+		color.Infof("权限树已写入：%s\n", output)
+	}
+
+	color.Println()
+}
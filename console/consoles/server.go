@@ -10,6 +10,7 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/herhe-com/framework/contracts/console"
 	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/http/middleware"
 	"github.com/hertz-contrib/swagger"
 	"github.com/spf13/cobra"
 	files "github.com/swaggo/files"
@@ -46,7 +47,10 @@ func (*ServerProvider) Register() console.Console {
 				options = append(options, option...)
 			}
 
-			serv := server.Default(options...)
+			serv := server.New(options...)
+
+			// 替代 server.Default 的 recovery，返回统一的响应格式
+			serv.Use(middleware.Recovery())
 
 			if middlewares, ok := facades.Config().Get("server.middlewares").([]app.HandlerFunc); ok {
 				serv.Use(middlewares...)
@@ -6,5 +6,6 @@ type Claims struct {
 	jwt.RegisteredClaims
 
 	Refresh bool           `json:"ref,omitempty"`
+	Type    string         `json:"typ,omitempty"` // 签发方式，用户登录签发的 token 为空
 	Ext     map[string]any `json:"ext,omitempty"`
 }
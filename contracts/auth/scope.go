@@ -0,0 +1,11 @@
+package auth
+
+// Scope 第三方令牌（如 OAuth2）的授权范围，由签发令牌的模块实现并注册到 facades，middleware.Permission 据此限制令牌可访问的权限
+type Scope interface {
+	// Restricted 令牌是否受 scope 限制
+	Restricted(claims *Claims) bool
+	// Allowed scope 是否覆盖权限码
+	Allowed(claims *Claims, permission string) bool
+	// Subjectless 令牌没有用户身份（如客户端凭证），scope 覆盖权限码后不再校验用户权限
+	Subjectless(claims *Claims) bool
+}
@@ -0,0 +1,13 @@
+package request
+
+type Cursor struct {
+	Cursor string `form:"cursor" json:"cursor" query:"cursor" validate:"omitempty,max=1024" label:"游标"`
+	Size   int    `form:"size" json:"size" query:"size" default:"15" validate:"omitempty,number,gte=0,lte=100" label:"页数"`
+}
+
+func (c *Cursor) GetSize() int {
+	if c.Size > 0 {
+		return c.Size
+	}
+	return 15
+}
@@ -0,0 +1,10 @@
+package request
+
+type Presign struct {
+	Name string `form:"name" json:"name" validate:"required,max=255" label:"文件名"`
+	Size int64  `form:"size" json:"size" validate:"required,gt=0" label:"文件大小"`
+}
+
+type Confirm struct {
+	Path string `form:"path" json:"path" validate:"required,max=255" label:"文件路径"`
+}
@@ -13,6 +13,12 @@ type Paginate[T any] struct {
 	Data  []T   `json:"data"`
 }
 
+type Cursor[T any] struct {
+	Next string `json:"next"`
+	Size int    `json:"size"`
+	Data []T    `json:"data"`
+}
+
 type Event[T any] struct {
 	ID        any    `json:"id,omitempty"`
 	Event     string `json:"event"`
@@ -0,0 +1,8 @@
+package response
+
+type Presign struct {
+	URL       string `json:"url"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	ExpiresAt string `json:"expires_at"`
+}
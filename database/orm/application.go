@@ -9,6 +9,7 @@ import (
 
 	"github.com/glebarez/sqlite"
 	"github.com/gookit/color"
+	"github.com/herhe-com/framework/database/orm/datascope"
 	"github.com/herhe-com/framework/facades"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
@@ -60,7 +61,21 @@ func DefaultDriver() string {
 }
 
 func NewDriver(driver string, name string) (*gorm.DB, string, error) {
-	driver = resolveDatabaseDriver(driver, name)
+
+	db, name, err := newClient(resolveDatabaseDriver(driver, name), name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 数据权限回调只处理带有主体的语句，对其他语句没有影响
+	if err = datascope.Register(db); err != nil {
+		return nil, "", err
+	}
+
+	return db, name, nil
+}
+
+func newClient(driver string, name string) (*gorm.DB, string, error) {
 
 	switch driver {
 	case DriverMySQL:
@@ -0,0 +1,138 @@
+package datascope
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const name = "framework:datascope"
+
+// Register
+//
+//	@Description: 在查询、更新、删除前注入数据权限条件，主体来自 Scope 或 WithSubject，没有主体的语句不受影响
+//	@param db	GORM 连接
+func Register(db *gorm.DB) error {
+
+	if err := db.Callback().Query().Before("gorm:query").Register(name, apply); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Row().Before("gorm:row").Register(name, apply); err != nil {
+		return err
+	}
+
+	if err := db.Callback().Update().Before("gorm:update").Register(name, apply); err != nil {
+		return err
+	}
+
+	return db.Callback().Delete().Before("gorm:delete").Register(name, apply)
+}
+
+func apply(db *gorm.DB) {
+
+	// 原生 SQL 无法追加条件
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.SQL.Len() > 0 {
+		return
+	}
+
+	if skip, ok := db.Get(keyOfSkip); ok && skip.(bool) {
+		return
+	}
+
+	subject := subjectOf(db)
+	if subject == nil {
+		return
+	}
+
+	scoped, ok := reflect.New(db.Statement.Schema.ModelType).Interface().(Scoped)
+	if !ok {
+		return
+	}
+
+	expressions, err := Expressions(subject, scoped.DataScope(), db.Statement.Table)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+
+	if len(expressions) == 0 {
+		return
+	}
+
+	// 没有条件的批量更新、删除交给 GORM 报 ErrMissingWhereClause，不能因为数据权限条件而放行
+	if !guarded(db) {
+		return
+	}
+
+	where := clause.Where{Exprs: expressions}
+
+	// 原有条件整体加括号，避免其中的 OR 绕过数据权限条件
+	if existing, ok := db.Statement.Clauses["WHERE"]; ok {
+		if current, ok := existing.Expression.(clause.Where); ok && len(current.Exprs) > 0 {
+			where.Exprs = append([]clause.Expression{clause.And(current.Exprs...)}, expressions...)
+		}
+	}
+
+	db.Statement.Clauses["WHERE"] = clause.Clause{Name: "WHERE", Expression: where}
+}
+
+func subjectOf(db *gorm.DB) *Subject {
+
+	if value, ok := db.Get(keyOfSubject); ok {
+		if subject, ok := value.(*Subject); ok && subject != nil {
+			return subject
+		}
+	}
+
+	if subject, ok := SubjectOf(db.Statement.Context); ok {
+		return subject
+	}
+
+	return nil
+}
+
+// guarded 查询语句，或者已经带有条件、主键、允许全表操作的更新、删除语句
+func guarded(db *gorm.DB) bool {
+
+	if !isWrite(db) || db.AllowGlobalUpdate {
+		return true
+	}
+
+	if _, ok := db.Statement.Clauses["WHERE"]; ok {
+		return true
+	}
+
+	return hasPrimaryKey(db)
+}
+
+func isWrite(db *gorm.DB) bool {
+
+	for _, item := range db.Statement.BuildClauses {
+		if item == "UPDATE" || item == "DELETE" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasPrimaryKey(db *gorm.DB) bool {
+
+	field := db.Statement.Schema.PrioritizedPrimaryField
+
+	if field == nil || !db.Statement.ReflectValue.IsValid() {
+		return false
+	}
+
+	switch db.Statement.ReflectValue.Kind() {
+	case reflect.Struct:
+		_, zero := field.ValueOf(db.Statement.Context, db.Statement.ReflectValue)
+		return !zero
+	case reflect.Slice, reflect.Array:
+		return db.Statement.ReflectValue.Len() > 0
+	}
+
+	return false
+}
@@ -0,0 +1,171 @@
+package datascope
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Rule 数据权限规则
+type Rule string
+
+const (
+	RuleOfAll            Rule = "all"             // 当前平台（组织）的全部数据
+	RuleOfOwn            Rule = "own"             // 本人的数据
+	RuleOfDepartment     Rule = "department"      // 本部门的数据
+	RuleOfDepartmentTree Rule = "department_tree" // 本部门及下级部门的数据
+	RuleOfRegion         Rule = "region"          // 指定区域的数据
+	RuleOfNone           Rule = "none"            // 没有数据权限
+)
+
+// Subject 数据权限的主体，通常由 middleware.DataScope 根据登录信息生成
+type Subject struct {
+	ID           string          `json:"id"`
+	Platform     uint16          `json:"platform"`
+	Organization string          `json:"organization"`
+	Rule         Rule            `json:"rule"`         // 默认规则，为空时按 RuleOfOwn 处理
+	Rules        map[string]Rule `json:"rules"`        // 按表名覆盖默认规则
+	Departments  []string        `json:"departments"`  // 所在部门
+	Subordinates []string        `json:"subordinates"` // 所在部门及全部下级部门
+	Regions      []string        `json:"regions"`      // 可以访问的区域
+}
+
+// Columns 模型中用于数据权限的字段，未声明的字段不参与过滤
+type Columns struct {
+	Platform     string
+	Organization string
+	Owner        string
+	Department   string
+	Region       string
+}
+
+// Scoped 需要数据权限的模型实现该接口
+//
+//	func (m *Order) DataScope() datascope.Columns {
+//		return datascope.Columns{Platform: "platform", Organization: "organization_id", Owner: "user_id", Department: "department_id"}
+//	}
+type Scoped interface {
+	DataScope() Columns
+}
+
+type contextKey struct{}
+
+const (
+	keyOfSubject = "framework:datascope:subject"
+	keyOfSkip    = "framework:datascope:skip"
+)
+
+// WithSubject 将数据权限主体写入 context，使用 db.WithContext(c) 的查询会自动过滤
+func WithSubject(c context.Context, subject *Subject) context.Context {
+	return context.WithValue(c, contextKey{}, subject)
+}
+
+// SubjectOf 读取 context 中的数据权限主体
+func SubjectOf(c context.Context) (*Subject, bool) {
+
+	if c == nil {
+		return nil, false
+	}
+
+	subject, ok := c.Value(contextKey{}).(*Subject)
+
+	return subject, ok && subject != nil
+}
+
+// Scope 以 GORM scope 的方式为查询指定数据权限主体：db.Scopes(datascope.Scope(subject))
+func Scope(subject *Subject) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Set(keyOfSkip, false).Set(keyOfSubject, subject)
+	}
+}
+
+// Skip 跳过数据权限过滤，用于后台任务或超级管理员：db.Scopes(datascope.Skip)
+func Skip(db *gorm.DB) *gorm.DB {
+	return db.Set(keyOfSkip, true)
+}
+
+// RuleOf 主体对指定表生效的规则
+func (s *Subject) RuleOf(table string) Rule {
+
+	if rule, ok := s.Rules[table]; ok && rule != "" {
+		return rule
+	}
+
+	if s.Rule == "" {
+		return RuleOfOwn
+	}
+
+	return s.Rule
+}
+
+// Expressions
+//
+//	@Description: 根据主体和模型字段生成过滤条件，字段通过 clause.Column 交给方言处理引号，兼容 MySQL、PostgreSQL、SQL Server、SQLite
+//	@param subject	数据权限主体
+//	@param columns	模型声明的字段
+//	@param table	表名，用于选择 Subject.Rules 中的规则
+func Expressions(subject *Subject, columns Columns, table string) ([]clause.Expression, error) {
+
+	expressions := make([]clause.Expression, 0, 3)
+
+	if columns.Platform != "" && subject.Platform > 0 {
+
+		expressions = append(expressions, clause.Eq{Column: column(columns.Platform), Value: subject.Platform})
+
+		if columns.Organization != "" {
+
+			var organization any
+
+			if subject.Organization != "" {
+				organization = subject.Organization
+			}
+
+			expressions = append(expressions, clause.Eq{Column: column(columns.Organization), Value: organization})
+		}
+	}
+
+	rule := subject.RuleOf(table)
+
+	var (
+		field  string
+		values []string
+	)
+
+	switch rule {
+	case RuleOfAll:
+		return expressions, nil
+	case RuleOfNone:
+		return append(expressions, clause.Expr{SQL: "1 = 0"}), nil
+	case RuleOfOwn:
+		field, values = columns.Owner, []string{subject.ID}
+	case RuleOfDepartment:
+		field, values = columns.Department, subject.Departments
+	case RuleOfDepartmentTree:
+		field, values = columns.Department, subject.Subordinates
+	case RuleOfRegion:
+		field, values = columns.Region, subject.Regions
+	default:
+		return nil, fmt.Errorf("datascope: unknown rule %s", rule)
+	}
+
+	// 模型没有声明规则需要的字段时拒绝查询，避免数据越权
+	if field == "" {
+		return nil, fmt.Errorf("datascope: table %s does not declare the column required by rule %s", table, rule)
+	}
+
+	values = lo.Uniq(lo.Compact(values))
+
+	// 没有可访问的值时不返回任何数据
+	if len(values) == 0 {
+		return append(expressions, clause.Expr{SQL: "1 = 0"}), nil
+	}
+
+	return append(expressions, clause.IN{Column: column(field), Values: lo.ToAnySlice(values)}), nil
+}
+
+func column(name string) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: name}
+}
@@ -0,0 +1,191 @@
+package datascope
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type order struct {
+	ID             uint `gorm:"primaryKey"`
+	Platform       uint16
+	OrganizationID *string
+	UserID         string
+	DepartmentID   string
+	Amount         int
+}
+
+func (o *order) DataScope() Columns {
+	return Columns{Platform: "platform", Organization: "organization_id", Owner: "user_id", Department: "department_id"}
+}
+
+type log struct {
+	ID      uint `gorm:"primaryKey"`
+	Content string
+}
+
+func open(t *testing.T) *gorm.DB {
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "datascope.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Register(db); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.AutoMigrate(&order{}, &log{}); err != nil {
+		t.Fatal(err)
+	}
+
+	store := "s1"
+
+	db.Create(&[]order{
+		{Platform: 999, OrganizationID: &store, UserID: "u1", DepartmentID: "d1", Amount: 1},
+		{Platform: 999, OrganizationID: &store, UserID: "u2", DepartmentID: "d2", Amount: 2},
+		{Platform: 999, OrganizationID: &store, UserID: "u3", DepartmentID: "d3", Amount: 3},
+		{Platform: 666, UserID: "u1", DepartmentID: "d1", Amount: 4},
+	})
+
+	db.Create(&log{Content: "unscoped"})
+
+	return db
+}
+
+func TestRulesFilterRecords(t *testing.T) {
+	db := open(t)
+
+	subject := &Subject{ID: "u1", Platform: 999, Organization: "s1", Departments: []string{"d2"}, Subordinates: []string{"d2", "d3"}}
+
+	cases := map[Rule]int{
+		RuleOfAll:            3,
+		RuleOfOwn:            1,
+		RuleOfDepartment:     1,
+		RuleOfDepartmentTree: 2,
+		RuleOfNone:           0,
+	}
+
+	for rule, expected := range cases {
+
+		subject.Rule = rule
+
+		var count int64
+
+		if err := db.WithContext(WithSubject(context.Background(), subject)).Model(&order{}).Count(&count).Error; err != nil {
+			t.Fatalf("%s: %v", rule, err)
+		}
+
+		if count != int64(expected) {
+			t.Fatalf("%s: expected %d records, got %d", rule, expected, count)
+		}
+	}
+
+	var orders []order
+
+	if err := db.Scopes(Scope(&Subject{ID: "u1", Platform: 666, Rule: RuleOfOwn})).Find(&orders).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(orders) != 1 || orders[0].Amount != 4 {
+		t.Fatalf("expected platform record with null organization, got %+v", orders)
+	}
+
+	var logs []log
+
+	if err := db.Scopes(Scope(subject)).Find(&logs).Error; err != nil || len(logs) != 1 {
+		t.Fatalf("expected models without DataScope to be untouched, got %v %v", logs, err)
+	}
+
+	if err := db.Scopes(Scope(&Subject{ID: "u1", Rule: RuleOfRegion})).Find(&orders).Error; err == nil {
+		t.Fatal("expected rule without declared column to fail")
+	}
+}
+
+func TestOrConditionsCannotEscapeScope(t *testing.T) {
+	db := open(t)
+
+	subject := &Subject{ID: "u1", Platform: 999, Organization: "s1", Rule: RuleOfOwn}
+
+	var count int64
+
+	db.Scopes(Scope(subject)).Model(&order{}).Where("amount = ?", 2).Or("amount = ?", 3).Count(&count)
+
+	if count != 0 {
+		t.Fatalf("expected OR conditions to stay inside the scope, got %d", count)
+	}
+}
+
+func TestWritesAreScoped(t *testing.T) {
+	db := open(t)
+
+	subject := &Subject{ID: "u1", Platform: 999, Organization: "s1", Rule: RuleOfOwn}
+	scoped := db.WithContext(WithSubject(context.Background(), subject))
+
+	result := scoped.Model(&order{}).Where("amount > ?", 0).Update("amount", 100)
+
+	if result.Error != nil || result.RowsAffected != 1 {
+		t.Fatalf("expected only own record to be updated, got %d %v", result.RowsAffected, result.Error)
+	}
+
+	// 他人的记录即使指定主键也不能删除
+	if result = scoped.Delete(&order{ID: 2}); result.RowsAffected != 0 {
+		t.Fatalf("expected delete of other user's record to affect nothing, got %d", result.RowsAffected)
+	}
+
+	if err := scoped.Model(&order{}).Update("amount", 0).Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatalf("expected global update to still be rejected, got %v", err)
+	}
+
+	var count int64
+
+	db.Scopes(Skip).Model(&order{}).Where("amount = ?", 100).Count(&count)
+
+	if count != 1 {
+		t.Fatalf("expected skip to bypass the scope, got %d", count)
+	}
+}
+
+func TestQuotingFollowsDialect(t *testing.T) {
+
+	dialectors := map[string]gorm.Dialector{
+		"mysql":     mysql.New(mysql.Config{DSN: "root@tcp(127.0.0.1:3306)/test", SkipInitializeWithVersion: true}),
+		"postgres":  postgres.New(postgres.Config{DSN: "host=127.0.0.1 user=test dbname=test"}),
+		"sqlserver": sqlserver.Open("sqlserver://test@127.0.0.1:1433?database=test"),
+	}
+
+	expected := map[string]string{
+		"mysql":     "`orders`.`user_id` =",
+		"postgres":  `"orders"."user_id" =`,
+		"sqlserver": `"orders"."user_id" =`,
+	}
+
+	for name, dialector := range dialectors {
+
+		db, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if err = Register(db); err != nil {
+			t.Fatal(err)
+		}
+
+		var orders []order
+
+		stmt := db.Scopes(Scope(&Subject{ID: "u1", Rule: RuleOfOwn})).Find(&orders).Statement
+
+		if sql := stmt.SQL.String(); !strings.Contains(sql, expected[name]) {
+			t.Fatalf("%s: unexpected sql %s", name, sql)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package orm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/herhe-com/framework/contracts/http/request"
+	"github.com/herhe-com/framework/contracts/http/response"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Paginate
+//
+//	@Description: 按页码分页查询，先查询总数，总数大于 0 时再查询当前页
+//	@param db	查询条件，可以通过 Scopes 组合 Sort、Filter
+//	@param req	分页参数
+func Paginate[T any](db *gorm.DB, req request.Paginate) (response.Paginate[T], error) {
+
+	result := response.Paginate[T]{
+		Page: req.GetPage(),
+		Size: req.GetSize(),
+		Data: make([]T, 0),
+	}
+
+	tx := db.Session(&gorm.Session{})
+
+	if tx.Statement.Model == nil {
+		tx = tx.Model(new(T))
+	}
+
+	if err := tx.Count(&result.Total).Error; err != nil {
+		return result, err
+	}
+
+	if result.Total > 0 {
+		if err := tx.Limit(req.GetLimit()).Offset(req.GetOffset()).Find(&result.Data).Error; err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// Keyset
+//
+//	@Description: 游标分页，按 keys 排序并从上一页最后一条之后开始查询，适用于大表和无限滚动，不查询总数
+//	@param db	查询条件
+//	@param req	游标参数，首页游标为空
+//	@param keys	排序字段，- 前缀为倒序，组合起来必须唯一，如 -created_at、-id
+func Keyset[T any](db *gorm.DB, req request.Cursor, keys ...string) (response.Cursor[T], error) {
+
+	result := response.Cursor[T]{
+		Size: req.GetSize(),
+		Data: make([]T, 0),
+	}
+
+	if len(keys) == 0 {
+		keys = []string{"id"}
+	}
+
+	value := new(T)
+
+	fields, err := keysetFields(db, value, keys)
+	if err != nil {
+		return result, err
+	}
+
+	tx := db.Session(&gorm.Session{})
+
+	if tx.Statement.Model == nil {
+		tx = tx.Model(value)
+	}
+
+	if req.Cursor != "" {
+
+		condition, err := keysetCondition(fields, req.Cursor)
+		if err != nil {
+			return result, err
+		}
+
+		tx = tx.Where(condition)
+	}
+
+	for _, field := range fields {
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Desc: field.desc})
+	}
+
+	if err = tx.Limit(result.Size + 1).Find(&result.Data).Error; err != nil {
+		return result, err
+	}
+
+	if len(result.Data) > result.Size {
+
+		result.Data = result.Data[:result.Size]
+
+		if result.Next, err = keysetCursor(db, fields, &result.Data[result.Size-1]); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+type keysetField struct {
+	*schema.Field
+	desc bool
+}
+
+var schemas = &sync.Map{}
+
+func keysetFields(db *gorm.DB, model any, keys []string) ([]keysetField, error) {
+
+	parsed, err := schema.Parse(model, schemas, db.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]keysetField, 0, len(keys))
+
+	for _, key := range keys {
+
+		name := strings.TrimPrefix(key, "-")
+
+		field := parsed.LookUpField(name)
+
+		if field == nil || field.DBName == "" {
+			return nil, errors.New("keyset field " + name + " is not found")
+		}
+
+		fields = append(fields, keysetField{Field: field, desc: strings.HasPrefix(key, "-")})
+	}
+
+	return fields, nil
+}
+
+// keysetCondition 排在游标之后的条件：(a > ?) OR (a = ? AND b > ?) ...
+func keysetCondition(fields []keysetField, cursor string) (clause.Expression, error) {
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var raws []json.RawMessage
+
+	if err = json.Unmarshal(data, &raws); err != nil || len(raws) != len(fields) {
+		return nil, ErrInvalidCursor
+	}
+
+	// 按字段类型解析，保证时间等类型与数据库中的值可以比较
+	values := make([]any, len(fields))
+
+	for index, field := range fields {
+
+		value := reflect.New(field.FieldType)
+
+		if err = json.Unmarshal(raws[index], value.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+
+		values[index] = value.Elem().Interface()
+	}
+
+	conditions := make([]clause.Expression, 0, len(fields))
+
+	for index, field := range fields {
+
+		expressions := make([]clause.Expression, 0, index+1)
+
+		for previous := 0; previous < index; previous++ {
+			expressions = append(expressions, clause.Eq{Column: keysetColumn(fields[previous]), Value: values[previous]})
+		}
+
+		if field.desc {
+			expressions = append(expressions, clause.Lt{Column: keysetColumn(field), Value: values[index]})
+		} else {
+			expressions = append(expressions, clause.Gt{Column: keysetColumn(field), Value: values[index]})
+		}
+
+		conditions = append(conditions, clause.And(expressions...))
+	}
+
+	return clause.Or(conditions...), nil
+}
+
+func keysetCursor(db *gorm.DB, fields []keysetField, item any) (string, error) {
+
+	value := reflect.ValueOf(item)
+	values := make([]any, len(fields))
+
+	for index, field := range fields {
+		values[index], _ = field.ValueOf(db.Statement.Context, value)
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func keysetColumn(field keysetField) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+}
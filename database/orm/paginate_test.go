@@ -0,0 +1,145 @@
+package orm
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/glebarez/sqlite"
+	"github.com/herhe-com/framework/contracts/http/request"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type paginateOrder struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	Status    int
+	CreatedAt time.Time
+}
+
+func paginateDB(t *testing.T) *gorm.DB {
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "paginate.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.AutoMigrate(&paginateOrder{}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 部分记录的创建时间相同，验证游标分页不会重复或遗漏
+	for index := 1; index <= 9; index++ {
+		db.Create(&paginateOrder{
+			ID:        uint(index),
+			Name:      []string{"apple", "banana", "100%_off"}[index%3],
+			Status:    index % 2,
+			CreatedAt: start.Add(time.Duration(index/2) * time.Hour),
+		})
+	}
+
+	return db
+}
+
+func queryContext(query string) *app.RequestContext {
+	ctx := app.NewContext(0)
+	ctx.Request.SetRequestURI("/orders?" + query)
+	return ctx
+}
+
+func TestPaginateWithSortAndFilter(t *testing.T) {
+
+	db := paginateDB(t)
+
+	columns := Columns{"status": "status", "name": "name", "created_at": "paginate_orders.created_at", "id": "id"}
+
+	ctx := queryContext("sort=-created_at,id&filter[status]=1&filter[id][gte]=2&page=1&size=2")
+
+	sort, err := Sort(ctx, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := Filter(ctx, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Paginate[paginateOrder](db.Scopes(sort, filter), request.Paginate{Page: 1, Size: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// status = 1 且 id >= 2：3、5、7、9
+	if result.Total != 4 || len(result.Data) != 2 || result.Data[0].ID != 9 || result.Data[1].ID != 7 {
+		t.Fatalf("unexpected page: %+v", result)
+	}
+
+	filter, _ = Filter(queryContext("filter[name][like]=100%25_"), columns)
+
+	if result, _ = Paginate[paginateOrder](db.Scopes(filter), request.Paginate{}); result.Total != 3 {
+		t.Fatalf("expected like to escape wildcards, got %d", result.Total)
+	}
+
+	filter, _ = Filter(queryContext("filter[id][in]=1,2,3"), columns)
+
+	if result, _ = Paginate[paginateOrder](db.Scopes(filter), request.Paginate{}); result.Total != 3 {
+		t.Fatalf("expected in filter, got %d", result.Total)
+	}
+
+	for _, query := range []string{"sort=password", "filter[password]=1", "filter[status][regexp]=1"} {
+
+		ctx = queryContext(query)
+
+		_, sortErr := Sort(ctx, columns)
+		_, filterErr := Filter(ctx, columns)
+
+		if sortErr == nil && filterErr == nil {
+			t.Fatalf("expected %s to be rejected", query)
+		}
+	}
+}
+
+func TestKeyset(t *testing.T) {
+
+	db := paginateDB(t)
+
+	seen := make([]uint, 0)
+	cursor := ""
+
+	for pages := 0; pages < 10; pages++ {
+
+		result, err := Keyset[paginateOrder](db, request.Cursor{Cursor: cursor, Size: 4}, "-created_at", "-id")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, item := range result.Data {
+			seen = append(seen, item.ID)
+		}
+
+		if cursor = result.Next; cursor == "" {
+			break
+		}
+	}
+
+	expected := []uint{9, 8, 7, 6, 5, 4, 3, 2, 1}
+
+	if len(seen) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, seen)
+	}
+
+	for index := range expected {
+		if seen[index] != expected[index] {
+			t.Fatalf("expected %v, got %v", expected, seen)
+		}
+	}
+
+	if _, err := Keyset[paginateOrder](db, request.Cursor{Cursor: "bad"}, "-created_at", "-id"); err != ErrInvalidCursor {
+		t.Fatalf("expected invalid cursor, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Columns 允许排序、筛选的字段，key 为请求参数中的名称，value 为数据表字段，可以带表名，如 orders.created_at
+type Columns map[string]string
+
+// 筛选支持的运算符
+const (
+	OperatorOfEq   = "eq"
+	OperatorOfNe   = "ne"
+	OperatorOfGt   = "gt"
+	OperatorOfGte  = "gte"
+	OperatorOfLt   = "lt"
+	OperatorOfLte  = "lte"
+	OperatorOfLike = "like"
+	OperatorOfIn   = "in"
+)
+
+// Sort
+//
+//	@Description: 按查询参数 sort=-created_at,name 排序，- 前缀为倒序，只允许 columns 中的字段
+//	@param columns	允许排序的字段
+//	@param defaults	未传 sort 时的排序，格式相同
+func Sort(ctx *app.RequestContext, columns Columns, defaults ...string) (func(db *gorm.DB) *gorm.DB, error) {
+
+	value := string(ctx.Query("sort"))
+
+	if value == "" {
+		value = strings.Join(defaults, ",")
+	}
+
+	orders := make([]clause.OrderByColumn, 0)
+
+	for _, item := range strings.Split(value, ",") {
+
+		item = strings.TrimSpace(item)
+
+		if item == "" {
+			continue
+		}
+
+		name := strings.TrimLeft(item, "+-")
+
+		column, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("sort field %s is not allowed", name)
+		}
+
+		orders = append(orders, clause.OrderByColumn{Column: columnOf(column), Desc: strings.HasPrefix(item, "-")})
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+
+		for _, order := range orders {
+			db = db.Order(order)
+		}
+
+		return db
+	}, nil
+}
+
+// Filter
+//
+//	@Description: 按查询参数 filter[status]=1、filter[created_at][gte]=2024-01-01 筛选，只允许 columns 中的字段
+//	@param columns	允许筛选的字段，运算符支持 eq、ne、gt、gte、lt、lte、like、in（逗号分隔）
+func Filter(ctx *app.RequestContext, columns Columns) (func(db *gorm.DB) *gorm.DB, error) {
+
+	expressions := make([]clause.Expression, 0)
+
+	var err error
+
+	ctx.QueryArgs().VisitAll(func(key, value []byte) {
+
+		if err != nil {
+			return
+		}
+
+		name, operator, ok := filterKey(string(key))
+		if !ok {
+			return
+		}
+
+		column, allowed := columns[name]
+		if !allowed {
+			err = fmt.Errorf("filter field %s is not allowed", name)
+			return
+		}
+
+		var expression clause.Expression
+
+		if expression, err = filterExpression(columnOf(column), operator, string(value)); err == nil {
+			expressions = append(expressions, expression)
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+
+		if len(expressions) == 0 {
+			return db
+		}
+
+		return db.Where(clause.And(expressions...))
+	}, nil
+}
+
+// filterKey 解析 filter[name] 和 filter[name][operator]
+func filterKey(key string) (name, operator string, ok bool) {
+
+	rest, found := strings.CutPrefix(key, "filter[")
+	if !found {
+		return "", "", false
+	}
+
+	name, rest, found = strings.Cut(rest, "]")
+	if !found || name == "" {
+		return "", "", false
+	}
+
+	if rest == "" {
+		return name, OperatorOfEq, true
+	}
+
+	operator, found = strings.CutPrefix(rest, "[")
+	if !found || !strings.HasSuffix(operator, "]") {
+		return "", "", false
+	}
+
+	return name, strings.ToLower(strings.TrimSuffix(operator, "]")), true
+}
+
+func filterExpression(column clause.Column, operator, value string) (clause.Expression, error) {
+
+	switch operator {
+	case OperatorOfEq:
+		return clause.Eq{Column: column, Value: value}, nil
+	case OperatorOfNe:
+		return clause.Neq{Column: column, Value: value}, nil
+	case OperatorOfGt:
+		return clause.Gt{Column: column, Value: value}, nil
+	case OperatorOfGte:
+		return clause.Gte{Column: column, Value: value}, nil
+	case OperatorOfLt:
+		return clause.Lt{Column: column, Value: value}, nil
+	case OperatorOfLte:
+		return clause.Lte{Column: column, Value: value}, nil
+	case OperatorOfLike:
+
+		// 转义通配符，只做包含匹配；各数据库默认的转义字符不同，统一指定为 !
+		value = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_", "[", "![").Replace(value)
+
+		return clause.Expr{SQL: "? LIKE ? ESCAPE '!'", Vars: []any{column, "%" + value + "%"}}, nil
+	case OperatorOfIn:
+
+		values := make([]any, 0)
+
+		for _, item := range strings.Split(value, ",") {
+			values = append(values, item)
+		}
+
+		return clause.IN{Column: column, Values: values}, nil
+	}
+
+	return nil, fmt.Errorf("filter operator %s is not supported", operator)
+}
+
+// columnOf 字段交给方言处理引号
+func columnOf(column string) clause.Column {
+
+	if table, name, ok := strings.Cut(column, "."); ok {
+		return clause.Column{Table: table, Name: name}
+	}
+
+	return clause.Column{Name: column}
+}
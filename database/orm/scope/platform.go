@@ -1,11 +1,10 @@
 package scope
 
 import (
-	"fmt"
-
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/herhe-com/framework/auth"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func Platform(ctx *app.RequestContext, tables ...string) func(db *gorm.DB) *gorm.DB {
@@ -16,23 +15,22 @@ func Platform(ctx *app.RequestContext, tables ...string) func(db *gorm.DB) *gorm
 		table = tables[0]
 	}
 
-	return func(db *gorm.DB) *gorm.DB {
-
-		organization := auth.Organization(ctx)
-
-		if organization.Valid {
+	// 字段交给方言处理引号，兼容 MySQL、PostgreSQL、SQL Server、SQLite
+	column := func(name string) clause.Column {
+		return clause.Column{Table: table, Name: name}
+	}
 
-			query := "`platform`=? and `organization_id`=?"
+	return func(db *gorm.DB) *gorm.DB {
 
-			if len(tables) > 0 {
-				query = fmt.Sprintf("`%s`.`platform`=? and `%s`.`organization_id`=?", table, table)
-			}
+		var organization any
 
-			db.Where(query, auth.Platform(ctx), organization.String)
-		} else {
-			db.Where("`platform`=? and `organization_id` is null", auth.Platform(ctx))
+		if id := auth.Organization(ctx); id.Valid {
+			organization = id.String
 		}
 
-		return db
+		return db.Where(clause.And(
+			clause.Eq{Column: column("platform"), Value: auth.Platform(ctx)},
+			clause.Eq{Column: column("organization_id"), Value: organization},
+		))
 	}
 }
@@ -161,8 +161,8 @@ func Console() *cobra.Command {
 }
 
 // Casbin returns the registered authorization enforcer.
-func Casbin() *casbin.Enforcer {
-	return MustGet[*casbin.Enforcer]()
+func Casbin() *casbin.SyncedEnforcer {
+	return MustGet[*casbin.SyncedEnforcer]()
 }
 
 // Locker returns the registered distributed lock service.
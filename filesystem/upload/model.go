@@ -0,0 +1,54 @@
+package upload
+
+import (
+	"context"
+	"time"
+
+	"github.com/herhe-com/framework/facades"
+	"gorm.io/gorm"
+)
+
+// File 上传记录
+type File struct {
+	ID           uint64    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	Disk         string    `gorm:"column:disk;size:64" json:"disk"`
+	Path         string    `gorm:"column:path;size:255;uniqueIndex" json:"path"` // 对象存储中的 key
+	Name         string    `gorm:"column:name;size:255" json:"name"`             // 客户端的原始文件名
+	Extension    string    `gorm:"column:extension;size:32" json:"extension"`    // 按文件头识别的扩展名
+	Size         int64     `gorm:"column:size" json:"size"`
+	User         string    `gorm:"column:user;size:64;index" json:"user"`
+	Platform     uint16    `gorm:"column:platform" json:"platform"`
+	Organization string    `gorm:"column:organization;size:64;index" json:"organization"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at"`
+
+	URL string `gorm:"-" json:"url"`
+}
+
+func (f *File) TableName() string {
+	return facades.Config().GetString("filesystem.upload.table", "sys_upload")
+}
+
+// Migrate 创建上传记录表
+func Migrate() error {
+	return DB().AutoMigrate(&File{})
+}
+
+// DB 上传记录所在的数据库连接
+func DB() *gorm.DB {
+	return facades.Database().Default()
+}
+
+// Record 写入上传记录
+func Record(c context.Context, file *File) error {
+	return DB().WithContext(c).Create(file).Error
+}
+
+// recorded 路径是否已经有上传记录
+func recorded(c context.Context, path string) (bool, error) {
+
+	var count int64
+
+	err := DB().WithContext(c).Model(&File{}).Where("path = ?", path).Count(&count).Error
+
+	return count > 0, err
+}
@@ -0,0 +1,329 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/contracts/filesystem"
+	"github.com/herhe-com/framework/contracts/http/request"
+	"github.com/herhe-com/framework/contracts/http/response"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/http"
+	"github.com/herhe-com/framework/support/file"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/lo"
+)
+
+// KeyOfPending 已经签发、等待确认的上传
+func KeyOfPending(path string) string {
+	return util.Keys("upload", "pending", path)
+}
+
+// Presign
+//
+//	@Description: 签发直传对象存储的链接，客户端使用 PUT 上传后调用 Confirm 确认，链接有效期内未确认的上传不会记录，文件由 Prune 清理
+//	@param configs	上传限制，签发时按文件名校验扩展名，确认时再按文件头校验
+func Presign(configs ...Config) app.HandlerFunc {
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		config := configure(configs)
+
+		req, ok := http.Bind[request.Presign](ctx)
+		if !ok {
+			return
+		}
+
+		if req.Size > config.MaxSize {
+			http.BadRequest(ctx, ErrFileTooLarge)
+			return
+		}
+
+		extension := strings.ToLower(file.ClientOriginalExtension(req.Name))
+
+		if extension == "" || (len(config.Extensions) > 0 && !lo.Contains(config.Extensions, extension)) {
+			http.BadRequest(ctx, ErrExtensionNotAllowed)
+			return
+		}
+
+		cache, exists := facades.OptionalRedis()
+		if !exists {
+			http.Fail(ctx, "redis cannot be null")
+			return
+		}
+
+		driver, err := config.driver()
+		if err != nil {
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		item := File{
+			Disk:      config.Disk,
+			Path:      config.path(extension),
+			Name:      name(req.Name),
+			Extension: extension,
+			Size:      req.Size,
+			User:      auth.ID(ctx),
+		}
+
+		url, err := driver.PresignedUploadUrl(item.Path, config.Expires)
+		if err != nil {
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		data, err := json.Marshal(item)
+		if err != nil {
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		// 链接到期前开始的上传可能在到期后才完成，多保留一个有效期用于确认
+		if err = cache.Default().Set(c, KeyOfPending(item.Path), data, config.Expires*2).Err(); err != nil {
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		http.Success(ctx, response.Presign{
+			URL:       url,
+			Method:    nethttp.MethodPut,
+			Path:      item.Path,
+			ExpiresAt: time.Now().Add(config.Expires).Format(time.RFC3339),
+		})
+	}
+}
+
+// Confirm
+//
+//	@Description: 确认直传的文件：只能确认自己签发的上传，校验文件存在、大小和文件头后记录，不符合时删除文件
+//	@param configs	上传限制，与 Presign 使用相同的配置
+func Confirm(configs ...Config) app.HandlerFunc {
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		config := configure(configs)
+
+		req, ok := http.Bind[request.Confirm](ctx)
+		if !ok {
+			return
+		}
+
+		cache, exists := facades.OptionalRedis()
+		if !exists {
+			http.Fail(ctx, "redis cannot be null")
+			return
+		}
+
+		key := KeyOfPending(req.Path)
+
+		// 先取出并删除等待确认的上传，同一次签发的并发确认只有一个能继续
+		data, ttl, err := claim(c, cache.Default(), key)
+
+		if errors.Is(err, redis.Nil) {
+			http.NotFound(ctx, "upload is not found or has expired")
+			return
+		} else if err != nil {
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		// 还可以重试的失败放回等待确认的上传，保留原来的有效期
+		restore := func() {
+			_ = cache.Default().Set(context.WithoutCancel(c), key, data, ttl).Err()
+		}
+
+		var item File
+
+		if err = json.Unmarshal(data, &item); err != nil {
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		if item.User != auth.ID(ctx) {
+			restore()
+			http.Forbidden(ctx)
+			return
+		}
+
+		config.Disk = item.Disk
+
+		driver, err := config.driver()
+		if err != nil {
+			restore()
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		if driver.Missing(item.Path) {
+			restore()
+			http.BadRequest(ctx, "file has not been uploaded")
+			return
+		}
+
+		if err = verify(c, config, driver, &item); err != nil {
+
+			// 不符合限制的文件不再保留，需要重新签发
+			_ = driver.Delete(item.Path)
+
+			http.BadRequest(ctx, err)
+			return
+		}
+
+		if err = record(c, ctx, driver, &item); errors.Is(err, ErrUploadConfirmed) {
+			http.Conflict(ctx, err.Error())
+			return
+		} else if err != nil {
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		http.Success(ctx, item)
+	}
+}
+
+// claim 在同一个事务中读取有效期并 GETDEL 等待确认的上传，不存在时返回 redis.Nil
+func claim(c context.Context, client *redis.Client, key string) ([]byte, time.Duration, error) {
+
+	var ttl *redis.DurationCmd
+	var value *redis.StringCmd
+
+	_, err := client.TxPipelined(c, func(pipe redis.Pipeliner) error {
+		ttl = pipe.PTTL(c, key)
+		value = pipe.GetDel(c, key)
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, 0, err
+	}
+
+	data, err := value.Bytes()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return data, max(ttl.Val(), time.Second), nil
+}
+
+// Prune
+//
+//	@Description: 清理某一天签发后没有确认的直传文件，等待确认和已经记录的文件会保留，可以在定时任务中按天清理已经超过两个有效期的目录
+//	@param day	签发的日期，不要传入当天，multipart 上传在写入文件和记录之间也可能被清理
+//	@param configs	上传限制，与 Presign 使用相同的配置
+//	@return count	删除的文件数
+func Prune(c context.Context, day time.Time, configs ...Config) (count int, err error) {
+
+	config := configure(configs)
+
+	cache, exists := facades.OptionalRedis()
+	if !exists {
+		return 0, errors.New("redis cannot be null")
+	}
+
+	driver, err := config.driver()
+	if err != nil {
+		return 0, err
+	}
+
+	files, err := driver.Files(config.dir(day))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, item := range files {
+
+		pending, err := cache.Default().Exists(c, KeyOfPending(item.Path)).Result()
+		if err != nil {
+			return count, err
+		}
+
+		if pending > 0 {
+			continue
+		}
+
+		exists, err := recorded(c, item.Path)
+		if err != nil {
+			return count, err
+		}
+
+		if exists {
+			continue
+		}
+
+		if err = driver.Delete(item.Path); err != nil {
+			return count, err
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// verify 按实际的大小和文件头校验，文件头通过临时链接读取
+func verify(c context.Context, config Config, driver filesystem.Driver, item *File) error {
+
+	size, err := driver.Size(item.Path)
+	if err != nil {
+		return err
+	}
+
+	if size > config.MaxSize {
+		return ErrFileTooLarge
+	}
+
+	head, err := sniff(c, driver, item.Path)
+	if err != nil {
+		return err
+	}
+
+	extension, err := config.extension(head, item.Path)
+	if err != nil {
+		return err
+	}
+
+	item.Size = size
+	item.Extension = extension
+
+	return nil
+}
+
+func sniff(c context.Context, driver filesystem.Driver, path string) ([]byte, error) {
+
+	url, err := driver.TemporaryUrl(path, time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := nethttp.NewRequestWithContext(c, nethttp.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", file.HeaderSize-1))
+
+	client := &nethttp.Client{Timeout: time.Duration(facades.Config().GetInt("filesystem.upload.timeout", 10)) * time.Second}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != nethttp.StatusOK && resp.StatusCode != nethttp.StatusPartialContent {
+		return nil, fmt.Errorf("file cannot be read: %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, file.HeaderSize))
+}
@@ -0,0 +1,239 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/contracts/filesystem"
+	"github.com/herhe-com/framework/facades"
+	filesystemconfig "github.com/herhe-com/framework/filesystem/config"
+	"github.com/herhe-com/framework/filesystem/util"
+	"github.com/herhe-com/framework/http"
+	"github.com/herhe-com/framework/support/file"
+	"github.com/samber/lo"
+)
+
+var (
+	ErrFileTooLarge        = errors.New("file is too large")
+	ErrExtensionNotAllowed = errors.New("file type is not allowed")
+	ErrUploadConfirmed     = errors.New("upload has already been confirmed")
+)
+
+// Config 上传限制，零值字段读取 filesystem.upload 中的配置
+type Config struct {
+	Disk       string        // 磁盘名称，默认 filesystem.default
+	Dir        string        // 保存目录，按日期再分目录
+	MaxSize    int64         // 最大字节数
+	Extensions []string      // 允许的扩展名，为空时允许所有能按文件头识别的类型
+	Field      string        // multipart 的表单字段
+	Expires    time.Duration // 预签名上传链接的有效期
+}
+
+func configure(configs []Config) Config {
+
+	var config Config
+
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+
+	cfg := facades.Config()
+
+	if config.Disk == "" {
+		config.Disk = filesystemconfig.DefaultDisk()
+	}
+
+	if config.Dir == "" {
+		config.Dir = cfg.GetString("filesystem.upload.dir", "uploads")
+	}
+
+	if config.MaxSize <= 0 {
+		config.MaxSize = cfg.GetInt64("filesystem.upload.max_size", 10<<20)
+	}
+
+	if len(config.Extensions) == 0 {
+		config.Extensions = cfg.GetStrings("filesystem.upload.extensions")
+	}
+
+	if config.Field == "" {
+		config.Field = cfg.GetString("filesystem.upload.field", "file")
+	}
+
+	if config.Expires <= 0 {
+		config.Expires = time.Duration(cfg.GetInt("filesystem.upload.expires", 900)) * time.Second
+	}
+
+	config.Extensions = lo.Map(config.Extensions, func(item string, index int) string {
+		return strings.ToLower(strings.TrimPrefix(item, "."))
+	})
+
+	return config
+}
+
+// Upload
+//
+//	@Description: 接收 multipart 上传，按文件头校验类型后写入磁盘并记录，响应上传记录
+//	@param configs	上传限制，请求体大小还受 server.WithMaxRequestBodySize 限制
+func Upload(configs ...Config) app.HandlerFunc {
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		config := configure(configs)
+
+		header, err := ctx.FormFile(config.Field)
+		if err != nil {
+			http.BadRequest(ctx, err)
+			return
+		}
+
+		if header.Size > config.MaxSize {
+			http.BadRequest(ctx, ErrFileTooLarge)
+			return
+		}
+
+		source, err := header.Open()
+		if err != nil {
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		defer source.Close()
+
+		head := make([]byte, file.HeaderSize)
+
+		count, err := io.ReadFull(source, head)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		head = head[:count]
+
+		extension, err := config.extension(head, header.Filename)
+		if err != nil {
+			http.BadRequest(ctx, err)
+			return
+		}
+
+		driver, err := config.driver()
+		if err != nil {
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		item := &File{
+			Disk:      config.Disk,
+			Path:      config.path(extension),
+			Name:      name(header.Filename),
+			Extension: extension,
+			Size:      header.Size,
+		}
+
+		// 已经读取的文件头和剩余内容一起写入
+		if err = driver.Put(item.Path, io.MultiReader(bytes.NewReader(head), source), header.Size); err != nil {
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		if err = record(c, ctx, driver, item); err != nil {
+			http.Fail(ctx, "%v", err)
+			return
+		}
+
+		http.Success(ctx, item)
+	}
+}
+
+// extension 按文件头识别扩展名，文本等无法识别的类型只有在 Extensions 中明确允许时才使用原始扩展名
+func (c Config) extension(head []byte, name string) (string, error) {
+
+	original := strings.ToLower(file.ClientOriginalExtension(name))
+
+	extension, err := file.ExtensionOf(head)
+
+	if errors.Is(err, file.ErrUnknownExtension) {
+
+		if original != "" && lo.Contains(c.Extensions, original) {
+			return original, nil
+		}
+
+		return "", ErrExtensionNotAllowed
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(c.Extensions) > 0 && !lo.Contains(c.Extensions, extension) {
+		return "", ErrExtensionNotAllowed
+	}
+
+	return extension, nil
+}
+
+func (c Config) driver() (filesystem.Driver, error) {
+	return facades.Storage().Disk(filesystemconfig.Driver(c.Disk, facades.Config().GetString("filesystem.driver")), c.Disk)
+}
+
+// dir 某一天上传的目录
+func (c Config) dir(day time.Time) string {
+	return util.ValidPath(c.Dir) + day.Format("2006/01/02")
+}
+
+// path 按日期分目录，文件名随机生成，不使用客户端的文件名
+func (c Config) path(extension string) string {
+
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return fmt.Sprintf("%s/%s.%s", c.dir(time.Now()), hex.EncodeToString(id), extension)
+}
+
+// record 写入上传记录，失败时删除已经上传的文件，同一路径已经记录过时文件属于已有的记录，返回 ErrUploadConfirmed 且不删除
+func record(c context.Context, ctx *app.RequestContext, driver filesystem.Driver, item *File) error {
+
+	item.User = auth.ID(ctx)
+	item.Platform = auth.Platform(ctx)
+
+	if organization := auth.Organization(ctx); organization.Valid {
+		item.Organization = organization.String
+	}
+
+	if err := Record(c, item); err != nil {
+
+		// 无法确认是否已经记录时保留文件，由 Prune 清理
+		if exists, e := recorded(c, item.Path); exists {
+			return ErrUploadConfirmed
+		} else if e == nil {
+			_ = driver.Delete(item.Path)
+		}
+
+		return err
+	}
+
+	item.URL = driver.Url(item.Path)
+
+	return nil
+}
+
+// name 客户端的原始文件名，去掉目录并限制长度
+func name(value string) string {
+
+	value = filepath.Base(strings.ReplaceAll(value, "\\", "/"))
+
+	if runes := []rune(value); len(runes) > 255 {
+		return string(runes[:255])
+	}
+
+	return value
+}
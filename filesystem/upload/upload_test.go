@@ -0,0 +1,517 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	nethttp "net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/glebarez/sqlite"
+	"github.com/herhe-com/framework/auth"
+	contractconfig "github.com/herhe-com/framework/contracts/config"
+	"github.com/herhe-com/framework/contracts/database"
+	"github.com/herhe-com/framework/contracts/filesystem"
+	"github.com/herhe-com/framework/contracts/http/response"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/validation"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type fakeConfig struct {
+	values map[string]any
+}
+
+func (f fakeConfig) Env(key string, defaultValue ...any) any {
+	return f.Get(key, defaultValue...)
+}
+
+func (f fakeConfig) Add(name string, configuration map[string]any) {}
+
+func (f fakeConfig) Set(key string, configuration any) {}
+
+func (f fakeConfig) Get(key string, defaultValue ...any) any {
+	if value, ok := f.values[key]; ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetString(key string, defaultValue ...string) string {
+	if value, ok := f.values[key]; ok {
+		return fmt.Sprint(value)
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return ""
+}
+
+func (f fakeConfig) GetStrings(key string, defaultValue ...[]string) []string {
+	if value, ok := f.values[key].([]string); ok {
+		return value
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetMaps(key string, defaultValue ...map[string]any) map[string]any {
+	return nil
+}
+
+func (f fakeConfig) GetInt(key string, defaultValue ...int) int {
+	if value, ok := f.values[key].(int); ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return 0
+}
+
+func (f fakeConfig) GetInt64(key string, defaultValue ...int64) int64 {
+	if value, ok := f.values[key].(int64); ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return 0
+}
+
+func (f fakeConfig) GetBool(key string, defaultValue ...bool) bool {
+	value, _ := f.values[key].(bool)
+	return value
+}
+
+func (f fakeConfig) IsSet(key string) bool {
+	_, ok := f.values[key]
+	return ok
+}
+
+type fakeDatabase struct {
+	db *gorm.DB
+}
+
+func (f fakeDatabase) Default() *gorm.DB {
+	return f.db
+}
+
+func (f fakeDatabase) Drivers(driver string, names ...string) (*gorm.DB, error) {
+	return f.db, nil
+}
+
+type fakeRedis struct {
+	client *redis.Client
+}
+
+func (f fakeRedis) Default() *redis.Client {
+	return f.client
+}
+
+func (f fakeRedis) Channel(name string) (*redis.Client, error) {
+	return f.client, nil
+}
+
+// fakeStorage 内存中的对象存储，临时链接由测试服务提供
+type fakeStorage struct {
+	filesystem.Driver
+	mutex   sync.Mutex
+	objects map[string][]byte
+	server  *httptest.Server
+}
+
+func (f *fakeStorage) Disk(driver string, disk string) (filesystem.Driver, error) {
+	return f, nil
+}
+
+func (f *fakeStorage) Put(file string, content io.Reader, size int64) error {
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	f.store(file, data)
+
+	return nil
+}
+
+func (f *fakeStorage) store(file string, data []byte) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.objects[file] = data
+}
+
+func (f *fakeStorage) object(file string) ([]byte, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	data, ok := f.objects[file]
+	return data, ok
+}
+
+func (f *fakeStorage) Missing(file string) bool {
+	_, ok := f.object(file)
+	return !ok
+}
+
+func (f *fakeStorage) Files(path string) ([]filesystem.Pathname, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	files := make([]filesystem.Pathname, 0)
+
+	for file := range f.objects {
+		if name, ok := strings.CutPrefix(file, prefix); ok && !strings.Contains(name, "/") {
+			files = append(files, filesystem.Pathname{Name: name, Path: file})
+		}
+	}
+
+	return files, nil
+}
+
+func (f *fakeStorage) Size(file string) (int64, error) {
+	data, _ := f.object(file)
+	return int64(len(data)), nil
+}
+
+func (f *fakeStorage) Delete(files ...string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, file := range files {
+		delete(f.objects, file)
+	}
+	return nil
+}
+
+func (f *fakeStorage) TemporaryUrl(file string, time time.Duration) (string, error) {
+	return f.server.URL + "/" + file, nil
+}
+
+func (f *fakeStorage) PresignedUploadUrl(file string, time time.Duration) (string, error) {
+	return "https://upload.example.com/" + file + "?signature=test", nil
+}
+
+func (f *fakeStorage) Url(file string) string {
+	return "https://cdn.example.com/" + file
+}
+
+var png = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+
+func setup(t *testing.T, values map[string]any) *fakeStorage {
+	original := facades.Container()
+	facades.SetContainer(&facades.Services{})
+	t.Cleanup(func() {
+		facades.SetContainer(original)
+	})
+
+	configuration := map[string]any{
+		"app.name":     "framework",
+		"app.language": "en",
+	}
+
+	for key, value := range values {
+		configuration[key] = value
+	}
+
+	facades.Register[contractconfig.Application](fakeConfig{values: configuration})
+
+	validation.NewApplication()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "upload.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	facades.Register[database.DB](fakeDatabase{db: db})
+
+	if err = Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := miniredis.RunT(t)
+	facades.Register[database.Redis](fakeRedis{client: redis.NewClient(&redis.Options{Addr: cache.Addr()})})
+
+	storage := &fakeStorage{objects: make(map[string][]byte)}
+
+	storage.server = httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		data, ok := storage.object(strings.TrimPrefix(r.URL.Path, "/"))
+		if !ok {
+			w.WriteHeader(nethttp.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(storage.server.Close)
+
+	facades.Register[filesystem.Storage](storage)
+
+	return storage
+}
+
+func multipartRequest(user, name string, content []byte) *app.RequestContext {
+
+	var body bytes.Buffer
+
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", name)
+	_, _ = part.Write(content)
+	_ = writer.Close()
+
+	ctx := app.NewContext(0)
+	ctx.Request.Header.SetMethod("POST")
+	ctx.Request.SetRequestURI("/upload")
+	ctx.Request.Header.SetContentTypeBytes([]byte(writer.FormDataContentType()))
+	ctx.Request.SetBody(body.Bytes())
+	ctx.Set(auth.ContextOfID, user)
+
+	return ctx
+}
+
+func jsonRequest(user, body string) *app.RequestContext {
+
+	ctx := app.NewContext(0)
+	ctx.Request.Header.SetMethod("POST")
+	ctx.Request.SetRequestURI("/upload")
+	ctx.Request.Header.SetContentTypeBytes([]byte("application/json"))
+	ctx.Request.SetBody([]byte(body))
+	ctx.Request.Header.SetContentLength(len(body))
+	ctx.Set(auth.ContextOfID, user)
+
+	return ctx
+}
+
+func decode[T any](t *testing.T, ctx *app.RequestContext) response.Response[T] {
+
+	var result response.Response[T]
+
+	if err := json.Unmarshal(ctx.Response.Body(), &result); err != nil {
+		t.Fatalf("expected json response, got %s", ctx.Response.Body())
+	}
+
+	return result
+}
+
+func TestUploadDetectsTypeAndRecords(t *testing.T) {
+	storage := setup(t, map[string]any{
+		"filesystem.upload.extensions": []string{"png", "jpg"},
+		"filesystem.upload.max_size":   int64(1024),
+	})
+
+	handler := Upload()
+	c := context.Background()
+
+	// 按文件头识别为 png，不使用客户端的扩展名
+	ctx := multipartRequest("user-1", "../avatar.JPG", png)
+	handler(c, ctx)
+
+	result := decode[File](t, ctx)
+
+	if result.Code != 20000 || result.Data.Extension != "png" || !strings.HasPrefix(result.Data.Path, "uploads/") || !strings.HasSuffix(result.Data.Path, ".png") {
+		t.Fatalf("expected png upload, got %s", ctx.Response.Body())
+	}
+
+	if result.Data.Name != "avatar.JPG" || result.Data.User != "user-1" || result.Data.URL != "https://cdn.example.com/"+result.Data.Path {
+		t.Fatalf("expected name, user and url, got %+v", result.Data)
+	}
+
+	if data, _ := storage.object(result.Data.Path); !bytes.Equal(data, png) {
+		t.Fatal("expected the whole file to be stored")
+	}
+
+	var count int64
+
+	if DB().Model(&File{}).Where("path = ?", result.Data.Path).Count(&count); count != 1 {
+		t.Fatalf("expected upload to be recorded, got %d", count)
+	}
+
+	for name, content := range map[string][]byte{
+		"notes.txt": []byte("plain text"),                          // 无法识别且没有允许
+		"big.png":   append(png, bytes.Repeat([]byte{0}, 1024)...), // 超过大小限制
+		"fake.png":  []byte("%PDF-1.4 not an image"),               // 实际是 pdf
+	} {
+		ctx = multipartRequest("user-1", name, content)
+		handler(c, ctx)
+
+		if code := decode[any](t, ctx).Code; code != 40000 {
+			t.Fatalf("expected %s to be rejected, got %s", name, ctx.Response.Body())
+		}
+	}
+
+	// 文本只能通过扩展名明确允许
+	ctx = multipartRequest("user-1", "notes.txt", []byte("plain text"))
+	Upload(Config{Extensions: []string{"txt"}})(c, ctx)
+
+	if result = decode[File](t, ctx); result.Code != 20000 || result.Data.Extension != "txt" {
+		t.Fatalf("expected allowed text upload, got %s", ctx.Response.Body())
+	}
+}
+
+func TestPresignAndConfirm(t *testing.T) {
+	storage := setup(t, map[string]any{
+		"filesystem.upload.extensions": []string{"png"},
+	})
+
+	c := context.Background()
+
+	ctx := jsonRequest("user-1", `{"name":"photo.gif","size":10}`)
+	Presign()(c, ctx)
+
+	if code := decode[any](t, ctx).Code; code != 40000 {
+		t.Fatalf("expected disallowed extension to be rejected, got %s", ctx.Response.Body())
+	}
+
+	ctx = jsonRequest("user-1", `{"name":"photo.png","size":10}`)
+	Presign()(c, ctx)
+
+	presigned := decode[response.Presign](t, ctx)
+
+	if presigned.Code != 20000 || presigned.Data.Method != "PUT" || !strings.Contains(presigned.Data.URL, presigned.Data.Path) {
+		t.Fatalf("expected presigned url, got %s", ctx.Response.Body())
+	}
+
+	path := presigned.Data.Path
+	confirm := Confirm()
+
+	ctx = jsonRequest("user-1", `{"path":"`+path+`"}`)
+	confirm(c, ctx)
+
+	if code := decode[any](t, ctx).Code; code != 40000 {
+		t.Fatalf("expected confirm before upload to fail, got %s", ctx.Response.Body())
+	}
+
+	// 客户端直传到对象存储
+	storage.store(path, png)
+
+	ctx = jsonRequest("user-2", `{"path":"`+path+`"}`)
+	confirm(c, ctx)
+
+	if ctx.Response.StatusCode() != nethttp.StatusForbidden {
+		t.Fatalf("expected other users to be forbidden, got %s", ctx.Response.Body())
+	}
+
+	ctx = jsonRequest("user-1", `{"path":"`+path+`"}`)
+	confirm(c, ctx)
+
+	result := decode[File](t, ctx)
+
+	if result.Code != 20000 || result.Data.Size != int64(len(png)) || result.Data.Extension != "png" || result.Data.ID == 0 {
+		t.Fatalf("expected confirmed upload, got %s", ctx.Response.Body())
+	}
+
+	ctx = jsonRequest("user-1", `{"path":"`+path+`"}`)
+	confirm(c, ctx)
+
+	if code := decode[any](t, ctx).Code; code != 40400 {
+		t.Fatalf("expected confirm to be one-time, got %s", ctx.Response.Body())
+	}
+
+	// 文件头与签发时的扩展名不符时删除文件
+	ctx = jsonRequest("user-1", `{"name":"fake.png","size":10}`)
+	Presign()(c, ctx)
+
+	path = decode[response.Presign](t, ctx).Data.Path
+	storage.store(path, []byte("%PDF-1.4 not an image"))
+
+	ctx = jsonRequest("user-1", `{"path":"`+path+`"}`)
+	confirm(c, ctx)
+
+	if code := decode[any](t, ctx).Code; code != 40000 || !storage.Missing(path) {
+		t.Fatalf("expected mismatched file to be rejected and deleted, got %s", ctx.Response.Body())
+	}
+}
+
+func presign(t *testing.T, user string) string {
+
+	ctx := jsonRequest(user, `{"name":"photo.png","size":10}`)
+	Presign()(context.Background(), ctx)
+
+	result := decode[response.Presign](t, ctx)
+
+	if result.Code != 20000 {
+		t.Fatalf("expected presigned url, got %s", ctx.Response.Body())
+	}
+
+	return result.Data.Path
+}
+
+func TestConfirmKeepsRecordedObject(t *testing.T) {
+	storage := setup(t, nil)
+
+	c := context.Background()
+
+	path := presign(t, "user-1")
+	storage.store(path, png)
+
+	// 同一路径已经有记录，例如并发的确认已经写入
+	if err := Record(c, &File{Disk: "local", Path: path, User: "user-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := jsonRequest("user-1", `{"path":"`+path+`"}`)
+	Confirm()(c, ctx)
+
+	if code := decode[any](t, ctx).Code; code != 40900 || storage.Missing(path) {
+		t.Fatalf("expected conflict without deleting the object, got %s", ctx.Response.Body())
+	}
+}
+
+func TestPruneDeletesUnconfirmedObjects(t *testing.T) {
+	storage := setup(t, nil)
+
+	c := context.Background()
+	cache, _ := facades.OptionalRedis()
+
+	pending := presign(t, "user-1")
+	storage.store(pending, png)
+
+	expired := presign(t, "user-1")
+	storage.store(expired, png)
+
+	if err := cache.Default().Del(c, KeyOfPending(expired)).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	confirmed := presign(t, "user-1")
+	storage.store(confirmed, png)
+
+	ctx := jsonRequest("user-1", `{"path":"`+confirmed+`"}`)
+	Confirm()(c, ctx)
+
+	if code := decode[any](t, ctx).Code; code != 20000 {
+		t.Fatalf("expected confirmed upload, got %s", ctx.Response.Body())
+	}
+
+	count, err := Prune(c, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 || !storage.Missing(expired) || storage.Missing(pending) || storage.Missing(confirmed) {
+		t.Fatalf("expected only the expired upload to be pruned, got %d", count)
+	}
+}
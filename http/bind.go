@@ -0,0 +1,254 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/utils"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/go-playground/validator/v10"
+	"github.com/herhe-com/framework/contracts/http/response"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/validation"
+	"github.com/spf13/cast"
+)
+
+// Bind
+//
+//	@Description: 绑定并验证请求参数，未传的零值字段使用 default 标签的值，失败时写入 BadRequest 响应，验证失败时 data 中包含全部字段的错误
+//	@return ok	为 false 时已经写入响应，直接返回即可
+func Bind[T any](ctx *app.RequestContext) (req T, ok bool) {
+
+	if err := ctx.Bind(&req); err != nil {
+		BadRequest(ctx, err)
+		return req, false
+	}
+
+	// 请求体中出现过的字段，JSON 中明确传入的零值不使用 default 标签
+	// 不能把请求体整体再解析到 req 中，否则请求体会覆盖路径、查询参数和请求头中的值
+	var fields any
+
+	if body := ctx.Request.Body(); len(body) > 0 && strings.EqualFold(utils.FilterContentType(string(ctx.Request.Header.ContentType())), consts.MIMEApplicationJSON) {
+
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+
+		_ = decoder.Decode(&fields)
+	}
+
+	defaults(ctx, reflect.ValueOf(&req), fields)
+
+	var err error
+
+	if validate, exists := facades.Get[*validator.Validate](); exists {
+		err = validate.Struct(&req)
+	} else {
+		err = ctx.Validate(&req)
+	}
+
+	if err == nil {
+		return req, true
+	}
+
+	var errs validator.ValidationErrors
+
+	if errors.As(err, &errs) {
+		ctx.JSON(http.StatusOK, response.Response[map[string][]string]{
+			Code:    40000,
+			Message: validation.Error(errs),
+			Data:    validation.Fields(errs, &req),
+		})
+		return req, false
+	}
+
+	BadRequest(ctx, err)
+
+	return req, false
+}
+
+// defaults 递归地把未传入的零值字段设置为 default 标签的值，fields 为对应的 JSON 值
+func defaults(ctx *app.RequestContext, value reflect.Value, fields any) {
+
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+
+		if value.IsNil() {
+			return
+		}
+
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+
+		items, _ := fields.([]any)
+
+		for index := 0; index < value.Len(); index++ {
+
+			var item any
+
+			if index < len(items) {
+				item = items[index]
+			}
+
+			defaults(ctx, value.Index(index), item)
+		}
+
+	case reflect.Struct:
+
+		object, _ := fields.(map[string]any)
+
+		for index := 0; index < value.NumField(); index++ {
+
+			field := value.Field(index)
+
+			if !field.CanSet() {
+				continue
+			}
+
+			structField := value.Type().Field(index)
+
+			name, embedded := jsonName(structField)
+
+			// 嵌入的结构体与外层共用同一个 JSON 对象
+			if embedded {
+				defaults(ctx, field.Addr(), fields)
+				continue
+			}
+
+			item, present := lookup(object, name)
+
+			if tag, exists := structField.Tag.Lookup("default"); exists {
+
+				if !present {
+
+					if field.IsZero() {
+						setDefault(field, tag)
+					}
+
+					continue
+				}
+
+				// 嵌入字段中 Hertz 会用 default 标签覆盖请求体中的值，路径、查询参数等来源没有值时按请求体恢复该字段
+				if !bound(ctx, structField) {
+					restore(field, item)
+				}
+
+				continue
+			}
+
+			defaults(ctx, field.Addr(), item)
+		}
+	}
+}
+
+// bound 字段是否从路径、查询参数、表单、请求头或 Cookie 中取到了值，这些来源优先于请求体
+func bound(ctx *app.RequestContext, field reflect.StructField) bool {
+
+	for _, source := range []string{"path", "query", "form", "header", "cookie"} {
+
+		name, _, _ := strings.Cut(field.Tag.Get(source), ",")
+
+		if name == "" || name == "-" {
+			continue
+		}
+
+		switch source {
+		case "path":
+			if _, ok := ctx.Params.Get(name); ok {
+				return true
+			}
+		case "query":
+			if ctx.QueryArgs().Has(name) {
+				return true
+			}
+		case "form":
+			if ctx.PostArgs().Has(name) {
+				return true
+			}
+		case "header":
+			if len(ctx.GetHeader(name)) > 0 {
+				return true
+			}
+		case "cookie":
+			if len(ctx.Cookie(name)) > 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// restore 把请求体中的值写回字段
+func restore(field reflect.Value, value any) {
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	_ = json.Unmarshal(data, field.Addr().Interface())
+}
+
+// jsonName 字段在 JSON 中的名称，embedded 为没有指定名称的嵌入字段
+func jsonName(field reflect.StructField) (name string, embedded bool) {
+
+	name, _, _ = strings.Cut(field.Tag.Get("json"), ",")
+
+	if name == "-" {
+		return "", false
+	}
+
+	if name == "" {
+
+		if field.Anonymous {
+			return "", true
+		}
+
+		name = field.Name
+	}
+
+	return name, false
+}
+
+// lookup 与 encoding/json 一致，名称优先完全匹配，其次不区分大小写匹配
+func lookup(object map[string]any, name string) (any, bool) {
+
+	if object == nil || name == "" {
+		return nil, false
+	}
+
+	if value, ok := object[name]; ok {
+		return value, true
+	}
+
+	for key, value := range object {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+func setDefault(field reflect.Value, tag string) {
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(tag)
+	case reflect.Bool:
+		field.SetBool(cast.ToBool(tag))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(cast.ToInt64(tag))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(cast.ToUint64(tag))
+	case reflect.Float32, reflect.Float64:
+		field.SetFloat(cast.ToFloat64(tag))
+	}
+}
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/route/param"
+	contractconfig "github.com/herhe-com/framework/contracts/config"
+	"github.com/herhe-com/framework/contracts/http/request"
+	"github.com/herhe-com/framework/contracts/http/response"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/validation"
+)
+
+type fakeConfig struct {
+	values map[string]any
+}
+
+func (f fakeConfig) Env(key string, defaultValue ...any) any {
+	return f.Get(key, defaultValue...)
+}
+
+func (f fakeConfig) Add(name string, configuration map[string]any) {}
+
+func (f fakeConfig) Set(key string, configuration any) {}
+
+func (f fakeConfig) Get(key string, defaultValue ...any) any {
+	if value, ok := f.values[key]; ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetString(key string, defaultValue ...string) string {
+	if value, ok := f.values[key]; ok {
+		return fmt.Sprint(value)
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return ""
+}
+
+func (f fakeConfig) GetStrings(key string, defaultValue ...[]string) []string {
+	return nil
+}
+
+func (f fakeConfig) GetMaps(key string, defaultValue ...map[string]any) map[string]any {
+	return nil
+}
+
+func (f fakeConfig) GetInt(key string, defaultValue ...int) int {
+	return 0
+}
+
+func (f fakeConfig) GetInt64(key string, defaultValue ...int64) int64 {
+	return 0
+}
+
+func (f fakeConfig) GetBool(key string, defaultValue ...bool) bool {
+	return false
+}
+
+func (f fakeConfig) IsSet(key string) bool {
+	_, ok := f.values[key]
+	return ok
+}
+
+func setup(t *testing.T) {
+	original := facades.Container()
+	facades.SetContainer(&facades.Services{})
+	t.Cleanup(func() {
+		facades.SetContainer(original)
+	})
+
+	facades.Register[contractconfig.Application](fakeConfig{values: map[string]any{"app.language": "en"}})
+
+	validation.NewApplication()
+}
+
+type bindItem struct {
+	Title string `json:"title" label:"title" validate:"required"`
+	Count int    `json:"count" default:"1" label:"count" validate:"gte=1"`
+}
+
+type bindRequest struct {
+	request.Paginate
+	request.Enable
+	Name  string     `json:"name" label:"name" validate:"required,min=3"`
+	Email string     `json:"email_address" label:"email" validate:"required,email"`
+	Items []bindItem `json:"items" validate:"dive"`
+}
+
+func bind(body string) (*app.RequestContext, bindRequest, bool) {
+
+	ctx := app.NewContext(0)
+	ctx.Request.Header.SetMethod("POST")
+	ctx.Request.SetRequestURI("/users?size=20")
+	ctx.Request.Header.SetContentTypeBytes([]byte("application/json; charset=utf-8"))
+	ctx.Request.SetBodyString(body)
+	ctx.Request.Header.SetContentLength(len(body))
+
+	req, ok := Bind[bindRequest](ctx)
+
+	return ctx, req, ok
+}
+
+func TestBindAppliesDefaults(t *testing.T) {
+	setup(t)
+
+	_, req, ok := bind(`{"name":"alice","email_address":"a@example.com","is_enable":2,"items":[{"title":"a"}]}`)
+
+	if !ok {
+		t.Fatal("expected request to be valid")
+	}
+
+	if req.Page != 1 || req.Size != 20 || req.IsEnable != 2 || req.Items[0].Count != 1 {
+		t.Fatalf("unexpected defaults: %+v", req)
+	}
+}
+
+func TestBindKeepsExplicitZeroValues(t *testing.T) {
+	setup(t)
+
+	ctx, req, ok := bind(`{"name":"alice","email_address":"a@example.com","page":0,"is_enable":0,"items":[{"title":"a","count":0}]}`)
+
+	if ok {
+		t.Fatalf("expected explicit zero count to fail validation, got %+v", req)
+	}
+
+	if req.Page != 0 || req.IsEnable != 0 || req.Items[0].Count != 0 || req.Size != 20 {
+		t.Fatalf("expected explicit zero values to be kept, got %+v", req)
+	}
+
+	var result response.Response[map[string][]string]
+
+	if err := json.Unmarshal(ctx.Response.Body(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Data["items[0].count"]) != 1 {
+		t.Fatalf("expected error for items[0].count, got %v", result.Data)
+	}
+}
+
+type bindUpdate struct {
+	request.Paginate
+	ID   string `path:"id" json:"id"`
+	Name string `json:"name"`
+}
+
+func TestBindKeepsPathAndQueryPrecedence(t *testing.T) {
+	setup(t)
+
+	body := `{"id":"999","name":"alice","page":3,"size":5}`
+
+	ctx := app.NewContext(0)
+	ctx.Request.Header.SetMethod("PUT")
+	ctx.Request.SetRequestURI("/users/1?size=20")
+	ctx.Params = append(ctx.Params, param.Param{Key: "id", Value: "1"})
+	ctx.Request.Header.SetContentTypeBytes([]byte("application/json"))
+	ctx.Request.SetBodyString(body)
+	ctx.Request.Header.SetContentLength(len(body))
+
+	req, ok := Bind[bindUpdate](ctx)
+
+	if !ok {
+		t.Fatalf("expected request to be valid, got %s", ctx.Response.Body())
+	}
+
+	if req.ID != "1" || req.Size != 20 || req.Page != 3 || req.Name != "alice" {
+		t.Fatalf("expected path and query to win over the body, got %+v", req)
+	}
+}
+
+func TestBindReturnsAllFieldErrors(t *testing.T) {
+	setup(t)
+
+	ctx, _, ok := bind(`{"name":"al","items":[{},{"title":"b","count":-1}]}`)
+
+	if ok {
+		t.Fatal("expected validation to fail")
+	}
+
+	var result response.Response[map[string][]string]
+
+	if err := json.Unmarshal(ctx.Response.Body(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Code != 40000 || result.Message == "" {
+		t.Fatalf("unexpected response: %+v", result)
+	}
+
+	for _, key := range []string{"name", "email_address", "items[0].title", "items[1].count"} {
+		if len(result.Data[key]) != 1 {
+			t.Fatalf("expected error for %s, got %v", key, result.Data)
+		}
+	}
+
+	if len(result.Data) != 4 {
+		t.Fatalf("expected 4 field errors, got %v", result.Data)
+	}
+}
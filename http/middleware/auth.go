@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/audit"
 	"github.com/herhe-com/framework/auth"
 	"github.com/herhe-com/framework/http"
 )
@@ -19,11 +20,18 @@ func Auth() app.HandlerFunc {
 		}
 
 		if auth.CheckBlacklist(c, auth.BlacklistOfJwtName(ctx)) {
+			audit.RecordRequest(c, ctx, &audit.Event{
+				Type:    audit.TypeOfTokenRejected,
+				Actor:   auth.ID(ctx),
+				Subject: auth.Claims(ctx).ID,
+				Result:  audit.ResultOfDenied,
+			})
 			ctx.Abort()
 			http.Unauthorized(ctx)
 			return
 		}
 
-		ctx.Next(c)
+		// 模型变更历史从 context 中读取操作人
+		ctx.Next(audit.WithActor(c, auth.ID(ctx)))
 	}
 }
@@ -0,0 +1,291 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/go-redsync/redsync/v4"
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/cache"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/microservice/locker"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/redis/go-redis/v9"
+)
+
+// CacheConfig 路由的响应缓存配置，空值字段沿用 server.cache.* 配置
+type CacheConfig struct {
+	TTL      time.Duration // 缓存有效期，默认 60 秒
+	Lock     time.Duration // 生成缓存时其他请求的最长等待时间，默认 10 秒
+	Shared   bool          // 登录用户之间共享缓存，默认已登录的请求按用户分别缓存，只用于与用户无关的数据
+	Platform bool          // 按平台分别缓存
+	Tags     []string      // 缓存标签，数据变更后通过 cache.Flush 删除
+}
+
+// cached 缓存的响应
+type cached struct {
+	ETag    string            `json:"etag"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    []byte            `json:"body"`
+}
+
+// Cache
+//
+//	@Description: 响应缓存中间件，GET 请求的完整响应按路由和查询参数缓存在 Redis 中，支持 ETag / If-None-Match 返回 304，同一缓存只由一个请求生成
+//	@param configs	路由的缓存配置，只取第一个
+func Cache(configs ...CacheConfig) app.HandlerFunc {
+
+	config := CacheConfig{
+		TTL:  time.Duration(facades.Config().GetInt("server.cache.ttl", 60)) * time.Second,
+		Lock: time.Duration(facades.Config().GetInt("server.cache.lock", 10)) * time.Second,
+	}
+
+	if len(configs) > 0 {
+
+		route := configs[0]
+
+		if route.TTL > 0 {
+			config.TTL = route.TTL
+		}
+
+		if route.Lock > 0 {
+			config.Lock = route.Lock
+		}
+
+		config.Shared = route.Shared
+		config.Platform = route.Platform
+		config.Tags = route.Tags
+	}
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		if string(ctx.Request.Header.Method()) != consts.MethodGet {
+			ctx.Next(c)
+			return
+		}
+
+		store, ok := facades.OptionalRedis()
+		if !ok {
+			hlog.CtxErrorf(c, "cache: please initialize Redis first")
+			ctx.Next(c)
+			return
+		}
+
+		client := store.Default()
+
+		key := keyOfCache(ctx, config)
+
+		record, err := cacheLoad(c, client, key)
+		if err != nil {
+			hlog.CtxErrorf(c, "cache: %v", err)
+			ctx.Next(c)
+			return
+		}
+
+		if record != nil {
+			cacheServe(ctx, record)
+			return
+		}
+
+		mutex := lockerOf(client).NewMutex(locker.Keys("cache", key), redsync.WithExpiry(config.Lock), redsync.WithTries(1))
+
+		if err = mutex.TryLockContext(c); err != nil {
+
+			// 其他请求正在生成缓存，等待其完成，超时后直接执行
+			if record = cacheWait(c, client, key, config.Lock); record != nil {
+				cacheServe(ctx, record)
+				return
+			}
+
+			ctx.Next(c)
+			return
+		}
+
+		defer func() {
+			_, _ = mutex.UnlockContext(context.WithoutCancel(c))
+		}()
+
+		// 加锁前缓存可能刚刚生成
+		if record, err = cacheLoad(c, client, key); err == nil && record != nil {
+			cacheServe(ctx, record)
+			return
+		}
+
+		ctx.Next(c)
+
+		// 只缓存成功且与用户凭证无关的响应，刷新 token 的 Authorization、Set-Cookie 不能被其他用户读到
+		if ctx.Response.StatusCode() != consts.StatusOK || len(ctx.Response.Header.Peek("Set-Cookie")) > 0 || len(ctx.Response.Header.Peek(auth.Authorization)) > 0 {
+			return
+		}
+
+		// http.Fail、http.BadRequest、http.NotFound 等同样返回 200，只缓存 http.Success 的响应
+		if !succeeded(ctx.Response.Body()) {
+			return
+		}
+
+		sum := sha256.Sum256(ctx.Response.Body())
+
+		record = &cached{
+			ETag:    `W/"` + hex.EncodeToString(sum[:16]) + `"`,
+			Status:  ctx.Response.StatusCode(),
+			Headers: replayHeaders(ctx),
+			Body:    ctx.Response.Body(),
+		}
+
+		ctx.Header("ETag", record.ETag)
+		ctx.Header("X-Cache", "MISS")
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			hlog.CtxErrorf(c, "cache: %v", err)
+			return
+		}
+
+		if err = client.Set(context.WithoutCancel(c), key, data, config.TTL).Err(); err != nil {
+			hlog.CtxErrorf(c, "cache: %v", err)
+			return
+		}
+
+		if err = cache.Tag(context.WithoutCancel(c), key, config.TTL, config.Tags...); err != nil {
+			hlog.CtxErrorf(c, "cache: %v", err)
+		}
+
+		if etagMatch(string(ctx.GetHeader("If-None-Match")), record.ETag) {
+			ctx.Response.SetStatusCode(consts.StatusNotModified)
+			ctx.Response.ResetBody()
+		}
+	}
+}
+
+// keyOfCache 缓存的 key，查询参数排序后参与计算
+func keyOfCache(ctx *app.RequestContext, config CacheConfig) string {
+
+	queries := make([]string, 0)
+
+	ctx.QueryArgs().VisitAll(func(key, value []byte) {
+		queries = append(queries, string(key)+"="+string(value))
+	})
+
+	sort.Strings(queries)
+
+	values := []string{string(ctx.URI().Path()), strings.Join(queries, "&")}
+
+	if !config.Shared && auth.Check(ctx) {
+		values = append(values, "user:"+auth.ID(ctx))
+	}
+
+	if config.Platform {
+		values = append(values, "platform:"+strconv.Itoa(int(auth.Platform(ctx))))
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+
+	return util.Keys("cache", "http", hex.EncodeToString(sum[:]))
+}
+
+// succeeded 响应体是否为 http.Success 的响应
+func succeeded(body []byte) bool {
+
+	var result struct {
+		Code int `json:"code"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return false
+	}
+
+	return result.Code == 20000
+}
+
+func cacheLoad(c context.Context, client *redis.Client, key string) (*cached, error) {
+
+	data, err := client.Get(c, key).Bytes()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var record cached
+
+	if err = json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// cacheWait 每 50 毫秒检查一次缓存是否生成
+func cacheWait(c context.Context, client *redis.Client, key string, timeout time.Duration) *cached {
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-c.Done():
+			return nil
+		case <-deadline.C:
+			return nil
+		case <-ticker.C:
+			if record, err := cacheLoad(c, client, key); err != nil || record != nil {
+				return record
+			}
+		}
+	}
+}
+
+// cacheServe 返回缓存的响应，If-None-Match 匹配时返回 304
+func cacheServe(ctx *app.RequestContext, record *cached) {
+
+	ctx.Abort()
+
+	ctx.Header("ETag", record.ETag)
+	ctx.Header("X-Cache", "HIT")
+
+	if etagMatch(string(ctx.GetHeader("If-None-Match")), record.ETag) {
+		ctx.Response.SetStatusCode(consts.StatusNotModified)
+		return
+	}
+
+	for name, value := range record.Headers {
+		ctx.Response.Header.Set(name, value)
+	}
+
+	ctx.Response.SetStatusCode(record.Status)
+	ctx.Response.SetBody(record.Body)
+}
+
+// etagMatch 弱比较 If-None-Match 中的 ETag
+func etagMatch(header, etag string) bool {
+
+	if header == "" {
+		return false
+	}
+
+	for _, item := range strings.Split(header, ",") {
+
+		item = strings.TrimSpace(item)
+
+		if item == "*" || strings.TrimPrefix(item, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
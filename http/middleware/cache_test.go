@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/cache"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/http"
+	"github.com/herhe-com/framework/microservice/locker"
+)
+
+func TestCacheResponses(t *testing.T) {
+	setup(t, map[string]any{"app.name": "framework"})
+	useRedis(t)
+
+	calls := 0
+
+	handlers := app.HandlersChain{Cache(CacheConfig{Tags: []string{"permissions"}}), func(c context.Context, ctx *app.RequestContext) {
+		calls++
+		http.Success(ctx, map[string]any{"user": auth.ID(ctx), "calls": calls})
+	}}
+
+	send := func(uri, user, etag string) *app.RequestContext {
+		ctx := request("GET", uri, func(ctx *app.RequestContext) {
+			ctx.Set(auth.ContextOfID, user)
+			if etag != "" {
+				ctx.Request.Header.Set("If-None-Match", etag)
+			}
+		})
+		ctx.SetHandlers(handlers)
+		ctx.Next(context.Background())
+		return ctx
+	}
+
+	first := send("/trees?a=1&b=2", "1", "")
+	etag := string(first.Response.Header.Peek("ETag"))
+
+	if calls != 1 || etag == "" || string(first.Response.Header.Peek("X-Cache")) != "MISS" {
+		t.Fatalf("expected first request to run with etag, calls %d", calls)
+	}
+
+	// 查询参数顺序不影响缓存
+	if hit := send("/trees?b=2&a=1", "1", ""); calls != 1 || string(hit.Response.Body()) != string(first.Response.Body()) || string(hit.Response.Header.Peek("X-Cache")) != "HIT" {
+		t.Fatalf("expected cached response, calls %d", calls)
+	}
+
+	if modified := send("/trees?a=1&b=2", "1", etag); modified.Response.StatusCode() != 304 || len(modified.Response.Body()) > 0 {
+		t.Fatalf("expected 304 for matching etag, got %d", modified.Response.StatusCode())
+	}
+
+	if send("/trees?a=1&b=2", "2", ""); calls != 2 {
+		t.Fatalf("expected another user to miss cache, calls %d", calls)
+	}
+
+	count, err := cache.Flush(context.Background(), "permissions")
+
+	if err != nil || count != 2 {
+		t.Fatalf("expected flush to delete 2 responses, got %d %v", count, err)
+	}
+
+	if send("/trees?a=1&b=2", "1", ""); calls != 3 {
+		t.Fatalf("expected flushed cache to miss, calls %d", calls)
+	}
+}
+
+func TestCacheSkipsResponsesWithCredentials(t *testing.T) {
+	setup(t, map[string]any{"app.name": "framework"})
+	useRedis(t)
+
+	calls := 0
+
+	handlers := app.HandlersChain{Cache(), func(c context.Context, ctx *app.RequestContext) {
+		calls++
+		// 模拟 middleware.Jwt 刷新 token
+		ctx.Header(auth.Authorization, "Bearer refreshed")
+		http.Success(ctx, map[string]any{"calls": calls})
+	}}
+
+	for range 2 {
+		ctx := request("GET", "/articles", nil)
+		ctx.SetHandlers(handlers)
+		ctx.Next(context.Background())
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected responses with Authorization not to be cached, calls %d", calls)
+	}
+}
+
+func TestCacheSkipsFailedResponses(t *testing.T) {
+	setup(t, map[string]any{"app.name": "framework"})
+	useRedis(t)
+
+	calls := 0
+
+	handlers := app.HandlersChain{Cache(), func(c context.Context, ctx *app.RequestContext) {
+		calls++
+		http.NotFound(ctx, "Not found")
+	}}
+
+	for range 2 {
+		ctx := request("GET", "/articles/1", nil)
+		ctx.SetHandlers(handlers)
+		ctx.Next(context.Background())
+	}
+
+	if calls != 2 {
+		t.Fatalf("expected error envelopes with status 200 not to be cached, calls %d", calls)
+	}
+}
+
+func TestCacheStampede(t *testing.T) {
+	setup(t, map[string]any{"app.name": "framework"})
+	useRedis(t)
+
+	calls := 0
+
+	handlers := app.HandlersChain{Cache(), func(c context.Context, ctx *app.RequestContext) {
+		calls++
+	}}
+
+	ctx := request("GET", "/dictionaries", nil)
+	ctx.SetHandlers(handlers)
+
+	key := keyOfCache(ctx, CacheConfig{})
+	client := facades.Redis().Default()
+
+	// 模拟其他请求正在生成缓存
+	client.Set(context.Background(), locker.Keys("cache", key), "other", time.Minute)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		data, _ := json.Marshal(cached{ETag: `W/"1"`, Status: 200, Body: []byte("cached")})
+		client.Set(context.Background(), key, data, time.Minute)
+	}()
+
+	ctx.Next(context.Background())
+
+	if calls != 0 || string(ctx.Response.Body()) != "cached" {
+		t.Fatalf("expected waiting request to use cache, calls %d body %s", calls, ctx.Response.Body())
+	}
+}
@@ -1,19 +1,210 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/facades"
 	"github.com/hertz-contrib/cors"
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
 )
 
-func Cors() app.HandlerFunc {
+// CorsGroup 按路径前缀覆盖的跨域配置，空值字段沿用 server.cors.* 配置
+type CorsGroup struct {
+	Prefix        string
+	Origins       []string // 允许的来源，* 为全部，https://*.example.com 匹配所有子域名
+	Methods       []string
+	Headers       []string // 在默认请求头之外允许的请求头
+	ExposeHeaders []string // 在默认暴露的响应头之外允许浏览器读取的响应头
+	Credentials   *bool
+	MaxAge        time.Duration
+}
+
+var (
+	corsMethods       = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+	corsHeaders       = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	corsExposeHeaders = []string{auth.Authorization, "Content-Disposition", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After"}
+)
+
+// Cors
+//
+//	@Description: 跨域中间件，读取 server.cors.*，server.cors.groups 与参数 groups 按最长路径前缀覆盖全局配置；来源为 * 且携带凭证时 panic
+//	@param groups	路由组的跨域配置，需要作为全局中间件使用，才能处理路由组的预检请求
+func Cors(groups ...CorsGroup) app.HandlerFunc {
+
+	global := CorsGroup{
+		Origins:       facades.Config().GetStrings("server.cors.origins", []string{"*"}),
+		Methods:       facades.Config().GetStrings("server.cors.methods", corsMethods),
+		Headers:       facades.Config().GetStrings("server.cors.headers"),
+		ExposeHeaders: facades.Config().GetStrings("server.cors.expose_headers"),
+		MaxAge:        time.Duration(facades.Config().GetInt("server.cors.max_age", 43200)) * time.Second,
+	}
+
+	credentials := facades.Config().GetBool("server.cors.credentials")
+	global.Credentials = &credentials
+
+	handler := newCors(global, CorsGroup{})
 
-	return cors.New(cors.Config{
-		AllowAllOrigins:  true,
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
-		AllowCredentials: false,
-		MaxAge:           12 * time.Hour,
+	groups = append(corsGroups(), groups...)
+
+	if len(groups) == 0 {
+		return handler
+	}
+
+	// 前缀越长越优先
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Prefix) > len(groups[j].Prefix)
 	})
+
+	handlers := make([]app.HandlerFunc, len(groups))
+
+	for index, group := range groups {
+		handlers[index] = newCors(global, group)
+	}
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		path := string(ctx.URI().Path())
+
+		for index, group := range groups {
+			if matchPrefix(path, group.Prefix) {
+				handlers[index](c, ctx)
+				return
+			}
+		}
+
+		handler(c, ctx)
+	}
+}
+
+// corsGroups 读取 server.cors.groups 中的路由组配置
+func corsGroups() []CorsGroup {
+
+	items, _ := facades.Config().Get("server.cors.groups").([]any)
+
+	groups := make([]CorsGroup, 0, len(items))
+
+	for _, item := range items {
+
+		values := cast.ToStringMap(item)
+
+		group := CorsGroup{
+			Prefix:        cast.ToString(values["prefix"]),
+			Origins:       cast.ToStringSlice(values["origins"]),
+			Methods:       cast.ToStringSlice(values["methods"]),
+			Headers:       cast.ToStringSlice(values["headers"]),
+			ExposeHeaders: cast.ToStringSlice(values["expose_headers"]),
+			MaxAge:        time.Duration(cast.ToInt(values["max_age"])) * time.Second,
+		}
+
+		if value, ok := values["credentials"]; ok {
+			credentials := cast.ToBool(value)
+			group.Credentials = &credentials
+		}
+
+		if group.Prefix != "" {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups
+}
+
+func newCors(global, group CorsGroup) app.HandlerFunc {
+
+	origins := global.Origins
+	methods := global.Methods
+	credentials := *global.Credentials
+	age := global.MaxAge
+
+	if len(group.Origins) > 0 {
+		origins = group.Origins
+	}
+
+	if len(group.Methods) > 0 {
+		methods = group.Methods
+	}
+
+	if group.Credentials != nil {
+		credentials = *group.Credentials
+	}
+
+	if group.MaxAge > 0 {
+		age = group.MaxAge
+	}
+
+	config := cors.Config{
+		AllowMethods:     methods,
+		AllowHeaders:     unique(corsHeaders, global.Headers, group.Headers),
+		ExposeHeaders:    unique(corsExposeHeaders, global.ExposeHeaders, group.ExposeHeaders),
+		AllowCredentials: credentials,
+		MaxAge:           age,
+	}
+
+	// 允许全部来源又携带凭证时，任意网站都能以用户身份读取响应，启动时直接拒绝
+	if credentials && lo.Contains(origins, "*") {
+		panic(fmt.Sprintf("cors: origins * cannot be used with credentials (prefix %q), list the allowed origins instead", group.Prefix))
+	}
+
+	if len(origins) == 1 && origins[0] == "*" {
+		config.AllowAllOrigins = true
+	} else {
+		config.AllowOriginFunc = func(origin string) bool {
+			for _, pattern := range origins {
+				if MatchOrigin(pattern, origin) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	return cors.New(config)
+}
+
+// matchPrefix 按路径段匹配前缀，/api 匹配 /api、/api/users，不匹配 /apis
+func matchPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+// MatchOrigin 判断来源是否匹配，* 匹配全部，https://*.example.com 匹配 example.com 的所有子域名
+func MatchOrigin(pattern, origin string) bool {
+
+	pattern, origin = strings.ToLower(pattern), strings.ToLower(origin)
+
+	if pattern == "*" || pattern == origin {
+		return true
+	}
+
+	prefix, suffix, ok := strings.Cut(pattern, "*")
+
+	if !ok || len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+
+	// 通配符只能匹配域名，不能跨越协议、端口和路径
+	return !strings.ContainsAny(origin[len(prefix):len(origin)-len(suffix)], "/:@")
+}
+
+func unique(lists ...[]string) []string {
+
+	values := make([]string, 0)
+	exists := make(map[string]bool)
+
+	for _, list := range lists {
+		for _, item := range list {
+			if key := strings.ToLower(item); item != "" && !exists[key] {
+				exists[key] = true
+				values = append(values, item)
+			}
+		}
+	}
+
+	return values
 }
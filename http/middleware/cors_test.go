@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	contractconfig "github.com/herhe-com/framework/contracts/config"
+	"github.com/herhe-com/framework/facades"
+)
+
+type fakeConfig struct {
+	values map[string]any
+}
+
+func (f fakeConfig) Env(key string, defaultValue ...any) any {
+	return f.Get(key, defaultValue...)
+}
+
+func (f fakeConfig) Add(name string, configuration map[string]any) {}
+
+func (f fakeConfig) Set(key string, configuration any) {
+	f.values[key] = configuration
+}
+
+func (f fakeConfig) Get(key string, defaultValue ...any) any {
+	if value, ok := f.values[key]; ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetString(key string, defaultValue ...string) string {
+	if value, ok := f.values[key]; ok {
+		return fmt.Sprint(value)
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return ""
+}
+
+func (f fakeConfig) GetStrings(key string, defaultValue ...[]string) []string {
+	if value, ok := f.values[key].([]string); ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetMaps(key string, defaultValue ...map[string]any) map[string]any {
+	return nil
+}
+
+func (f fakeConfig) GetInt(key string, defaultValue ...int) int {
+	if value, ok := f.values[key].(int); ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return 0
+}
+
+func (f fakeConfig) GetInt64(key string, defaultValue ...int64) int64 {
+	return 0
+}
+
+func (f fakeConfig) GetBool(key string, defaultValue ...bool) bool {
+	value, _ := f.values[key].(bool)
+	return value
+}
+
+func (f fakeConfig) IsSet(key string) bool {
+	_, ok := f.values[key]
+	return ok
+}
+
+func setup(t *testing.T, values map[string]any) {
+	original := facades.Container()
+	facades.SetContainer(&facades.Services{})
+	t.Cleanup(func() {
+		facades.SetContainer(original)
+	})
+
+	facades.Register[contractconfig.Application](fakeConfig{values: values})
+}
+
+func preflight(handler app.HandlerFunc, path, origin string) *app.RequestContext {
+
+	ctx := app.NewContext(0)
+	ctx.Request.SetRequestURI(path)
+	ctx.Request.Header.SetMethod("OPTIONS")
+	ctx.Request.SetHost("api.example.com")
+	ctx.Request.Header.Set("Origin", origin)
+
+	handler(context.Background(), ctx)
+
+	return ctx
+}
+
+func TestMatchOrigin(t *testing.T) {
+	for _, item := range []struct {
+		pattern, origin string
+		matched         bool
+	}{
+		{"*", "https://any.com", true},
+		{"https://app.example.com", "https://APP.example.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "http://a.example.com", false},
+		{"https://*.example.com", "https://evil.com/.example.com", false},
+		{"https://*.example.com", "https://evil.com:1.example.com", false},
+	} {
+		if MatchOrigin(item.pattern, item.origin) != item.matched {
+			t.Fatalf("MatchOrigin(%q, %q) should be %v", item.pattern, item.origin, item.matched)
+		}
+	}
+}
+
+func TestCorsWithCredentialsAndGroups(t *testing.T) {
+	setup(t, map[string]any{
+		"server.cors.origins":     []string{"https://*.example.com"},
+		"server.cors.credentials": true,
+		"server.cors.headers":     []string{"X-Tenant"},
+		"server.cors.groups": []any{
+			map[string]any{"prefix": "/open", "origins": []any{"*"}, "credentials": false},
+		},
+	})
+
+	handler := Cors()
+
+	ctx := preflight(handler, "/users", "https://admin.example.com")
+
+	if string(ctx.Response.Header.Peek("Access-Control-Allow-Origin")) != "https://admin.example.com" {
+		t.Fatalf("expected origin echoed with credentials, got %q", ctx.Response.Header.Peek("Access-Control-Allow-Origin"))
+	}
+
+	if string(ctx.Response.Header.Peek("Access-Control-Allow-Credentials")) != "true" {
+		t.Fatal("expected credentials allowed")
+	}
+
+	if headers := string(ctx.Response.Header.Peek("Access-Control-Allow-Headers")); headers != "Origin,Content-Length,Content-Type,Authorization,X-Tenant" {
+		t.Fatalf("expected custom header allowed, got %q", headers)
+	}
+
+	if ctx = preflight(handler, "/users", "https://evil.com"); ctx.Response.StatusCode() != 403 {
+		t.Fatalf("expected unknown origin rejected, got %d", ctx.Response.StatusCode())
+	}
+
+	// 路由组覆盖为允许全部来源、不携带凭证
+	ctx = preflight(handler, "/open/articles", "https://evil.com")
+
+	if string(ctx.Response.Header.Peek("Access-Control-Allow-Origin")) != "*" || len(ctx.Response.Header.Peek("Access-Control-Allow-Credentials")) > 0 {
+		t.Fatalf("expected open group to allow all origins, got %q", ctx.Response.Header.Peek("Access-Control-Allow-Origin"))
+	}
+
+	// 前缀按路径段匹配，/openapi 不属于 /open
+	if ctx = preflight(handler, "/openapi", "https://evil.com"); ctx.Response.StatusCode() != 403 {
+		t.Fatalf("expected /openapi to use global config, got %d", ctx.Response.StatusCode())
+	}
+}
+
+func TestCorsRejectsAllOriginsWithCredentials(t *testing.T) {
+	setup(t, map[string]any{
+		"server.cors.origins":     []string{"*"},
+		"server.cors.credentials": true,
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected origins * with credentials to be rejected")
+		}
+	}()
+
+	Cors()
+}
+
+func TestCorsExposesRefreshedToken(t *testing.T) {
+	setup(t, map[string]any{})
+
+	ctx := app.NewContext(0)
+	ctx.Request.SetRequestURI("/users")
+	ctx.Request.SetHost("api.example.com")
+	ctx.Request.Header.Set("Origin", "https://web.example.com")
+
+	Cors()(context.Background(), ctx)
+
+	if expose := string(ctx.Response.Header.Peek("Access-Control-Expose-Headers")); expose == "" || expose[:len("Authorization")] != "Authorization" {
+		t.Fatalf("expected Authorization exposed, got %q", expose)
+	}
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/http"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/samber/lo"
+)
+
+const ContextOfCSRF = "CSRFToken"
+
+// CSRF 基于 Cookie 的跨站请求伪造防护，读取 server.security.csrf.*：
+// - cookie: 保存令牌的 Cookie，默认 csrf_token，前端读取后放到请求头中
+// - header: 提交令牌的请求头，默认 X-CSRF-Token，也可以使用表单字段 field（默认 _csrf）
+// - lifetime: 令牌有效期（秒），默认 7200
+// - session: 会话 Cookie 名称，设置后令牌与会话绑定，会话变化时令牌失效
+// - same_site: Cookie 的 SameSite，可选 strict、lax、none，默认 lax
+// - secure: Cookie 是否只在 HTTPS 中发送，默认 true
+// - domain、path: Cookie 的域名和路径，默认为空和 /
+// - exempt: 不校验的路径前缀，按路径段匹配，如第三方回调
+//
+// 写请求需要同时携带 Cookie 和请求头（双重提交），且令牌必须由服务端签发并保存在 Redis 中（同步令牌）；
+// 没有 Redis 时只做双重提交校验。携带 Bearer token 的请求不依赖 Cookie 认证，直接跳过。
+func CSRF() app.HandlerFunc {
+
+	cfg := facades.Config()
+
+	cookie := cfg.GetString("server.security.csrf.cookie", "csrf_token")
+	header := cfg.GetString("server.security.csrf.header", "X-CSRF-Token")
+	field := cfg.GetString("server.security.csrf.field", "_csrf")
+	lifetime := cfg.GetInt("server.security.csrf.lifetime", 7200)
+	session := cfg.GetString("server.security.csrf.session")
+	domain := cfg.GetString("server.security.csrf.domain")
+	path := cfg.GetString("server.security.csrf.path", "/")
+	https := cfg.GetBool("server.security.csrf.secure", true)
+	exempt := cfg.GetStrings("server.security.csrf.exempt")
+
+	site := protocol.CookieSameSiteLaxMode
+
+	switch strings.ToLower(cfg.GetString("server.security.csrf.same_site")) {
+	case "strict":
+		site = protocol.CookieSameSiteStrictMode
+	case "none":
+		site = protocol.CookieSameSiteNoneMode
+	}
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		if bearer(ctx) || lo.ContainsBy(exempt, func(prefix string) bool {
+			return matchPrefix(string(ctx.URI().Path()), prefix)
+		}) {
+			ctx.Next(c)
+			return
+		}
+
+		binding := ""
+
+		if session != "" {
+			binding = string(ctx.Cookie(session))
+		}
+
+		token := string(ctx.Cookie(cookie))
+		valid := token != "" && issued(c, token, binding)
+
+		method := string(ctx.Request.Header.Method())
+
+		if lo.Contains([]string{consts.MethodPost, consts.MethodPut, consts.MethodPatch, consts.MethodDelete}, method) {
+
+			submitted := string(ctx.GetHeader(header))
+
+			if submitted == "" {
+				submitted = ctx.PostForm(field)
+			}
+
+			if !valid || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+				ctx.Abort()
+				http.Forbidden(ctx)
+				return
+			}
+		} else if !valid {
+
+			// 读请求时签发新令牌，前端从 Cookie 或响应头中读取
+			value, err := issue(c, binding, lifetime)
+			if err != nil {
+				hlog.CtxErrorf(c, "failed to issue csrf token: %v", err)
+				ctx.Next(c)
+				return
+			}
+
+			token = value
+
+			ctx.SetCookie(cookie, token, lifetime, path, domain, site, https, false)
+		}
+
+		ctx.Set(ContextOfCSRF, token)
+		ctx.Header(header, token)
+
+		ctx.Next(c)
+	}
+}
+
+// CSRFToken 本次请求的 CSRF 令牌，用于服务端渲染的表单
+func CSRFToken(ctx *app.RequestContext) string {
+	return ctx.GetString(ContextOfCSRF)
+}
+
+// KeyOfCSRF 令牌在 Redis 中的 key，值为绑定的会话
+func KeyOfCSRF(token string) string {
+	return util.Keys("csrf", token)
+}
+
+// bearer 是否携带 Bearer token，这类请求不会被浏览器自动附带凭证
+func bearer(ctx *app.RequestContext) bool {
+
+	value := ctx.GetHeader("Authorization")
+
+	return len(value) > 7 && bytes.EqualFold(value[:7], []byte("Bearer "))
+}
+
+func issue(c context.Context, binding string, lifetime int) (string, error) {
+
+	value := make([]byte, 32)
+
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(value)
+
+	if cache, ok := facades.OptionalRedis(); ok {
+		if err := cache.Default().Set(c, KeyOfCSRF(token), binding, time.Duration(lifetime)*time.Second).Err(); err != nil {
+			return "", err
+		}
+	}
+
+	return token, nil
+}
+
+// issued 令牌是否由服务端签发且与当前会话一致，没有 Redis 时只要求格式正确
+func issued(c context.Context, token, binding string) bool {
+
+	if decoded, err := base64.RawURLEncoding.DecodeString(token); err != nil || len(decoded) != 32 {
+		return false
+	}
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return true
+	}
+
+	// 令牌不存在、已过期或 Redis 出错时都视为无效
+	value, err := cache.Default().Get(c, KeyOfCSRF(token)).Result()
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(value), []byte(binding)) == 1
+}
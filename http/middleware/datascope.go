@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/database/orm/datascope"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/http"
+)
+
+// DataScope 根据登录信息生成数据权限主体并写入 context，后续使用 db.WithContext(c) 的查询会按模型声明自动过滤
+//
+// auth.data.subject 可以配置为 func(c context.Context, ctx *app.RequestContext) (*datascope.Subject, error)，
+// 用于补充规则、部门、区域等信息；未配置时只包含用户、平台、组织，规则为 datascope.RuleOfOwn。
+func DataScope() app.HandlerFunc {
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		if !auth.Check(ctx) {
+			ctx.Next(c)
+			return
+		}
+
+		subject := &datascope.Subject{
+			ID:           auth.ID(ctx),
+			Platform:     auth.Platform(ctx),
+			Organization: auth.Organization(ctx).String,
+			Rule:         datascope.RuleOfOwn,
+		}
+
+		if resolver, ok := facades.Config().Get("auth.data.subject").(func(c context.Context, ctx *app.RequestContext) (*datascope.Subject, error)); ok {
+
+			resolved, err := resolver(c, ctx)
+
+			if err != nil {
+				ctx.Abort()
+				http.Fail(ctx, "%v", err)
+				return
+			}
+
+			if resolved != nil {
+				subject = resolved
+			}
+		}
+
+		ctx.Next(datascope.WithSubject(c, subject))
+	}
+}
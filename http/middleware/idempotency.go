@@ -0,0 +1,287 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/go-redsync/redsync/v4"
+	"github.com/go-redsync/redsync/v4/redis/goredis/v9"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/http"
+	"github.com/herhe-com/framework/microservice/locker"
+	"github.com/herhe-com/framework/ratelimit"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/lo"
+)
+
+// IdempotencyConfig 路由的幂等配置，空值字段沿用 server.idempotency.* 配置
+type IdempotencyConfig struct {
+	Header   string            // 幂等键请求头，默认 Idempotency-Key
+	TTL      time.Duration     // 响应保存时间，默认 86400 秒
+	Lock     time.Duration     // 处理中锁的有效期，默认 30 秒，应大于接口的最长处理时间
+	Methods  []string          // 需要幂等的请求方法，默认 POST、PATCH
+	Required *bool             // 是否必须携带幂等键，默认 false
+	Scope    ratelimit.KeyFunc // 幂等键的作用范围，默认按登录用户，未登录时按客户端 IP
+}
+
+// idempotent 保存的首次响应
+type idempotent struct {
+	Fingerprint string            `json:"fingerprint"`
+	Status      int               `json:"status"`
+	Headers     map[string]string `json:"headers"`
+	Body        []byte            `json:"body"`
+}
+
+// 需要重放的响应头，Authorization（刷新后的 token）、Set-Cookie、X-CSRF-Token 等与用户相关的响应头不能保存
+var replayAllowHeaders = []string{"Content-Type", "Content-Encoding", "ETag", "Cache-Control", "Vary", "Location"}
+
+// Idempotency
+//
+//	@Description: 幂等中间件，按 Idempotency-Key 请求头保存首次响应，重试时直接返回，请求内容不一致或首次请求仍在处理时返回 http.Conflict
+//	@param configs	路由的幂等配置，只取第一个
+func Idempotency(configs ...IdempotencyConfig) app.HandlerFunc {
+
+	cfg := facades.Config()
+
+	config := IdempotencyConfig{
+		Header:  cfg.GetString("server.idempotency.header", "Idempotency-Key"),
+		TTL:     time.Duration(cfg.GetInt("server.idempotency.ttl", 86400)) * time.Second,
+		Lock:    time.Duration(cfg.GetInt("server.idempotency.lock", 30)) * time.Second,
+		Methods: cfg.GetStrings("server.idempotency.methods", []string{consts.MethodPost, consts.MethodPatch}),
+		Scope:   ratelimit.ByUser,
+	}
+
+	required := cfg.GetBool("server.idempotency.required")
+	config.Required = &required
+
+	if len(configs) > 0 {
+
+		route := configs[0]
+
+		if route.Header != "" {
+			config.Header = route.Header
+		}
+
+		if route.TTL > 0 {
+			config.TTL = route.TTL
+		}
+
+		if route.Lock > 0 {
+			config.Lock = route.Lock
+		}
+
+		if len(route.Methods) > 0 {
+			config.Methods = route.Methods
+		}
+
+		if route.Required != nil {
+			config.Required = route.Required
+		}
+
+		if route.Scope != nil {
+			config.Scope = route.Scope
+		}
+	}
+
+	methods := lo.Map(config.Methods, func(method string, _ int) string {
+		return strings.ToUpper(method)
+	})
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		if !lo.Contains(methods, string(ctx.Request.Header.Method())) {
+			ctx.Next(c)
+			return
+		}
+
+		value := string(ctx.GetHeader(config.Header))
+
+		if value == "" {
+
+			if *config.Required {
+				ctx.Abort()
+				http.BadRequest(ctx, "Missing %s header", config.Header)
+				return
+			}
+
+			ctx.Next(c)
+			return
+		}
+
+		if len(value) > 255 {
+			ctx.Abort()
+			http.BadRequest(ctx, "Invalid %s header", config.Header)
+			return
+		}
+
+		cache, ok := facades.OptionalRedis()
+		if !ok {
+			hlog.CtxErrorf(c, "idempotency: please initialize Redis first")
+			ctx.Next(c)
+			return
+		}
+
+		client := cache.Default()
+
+		key := KeyOfIdempotency(config.Scope(c, ctx), value)
+		fingerprint := idempotencyFingerprint(ctx)
+
+		record, err := idempotencyLoad(c, client, key)
+		if err != nil {
+			hlog.CtxErrorf(c, "idempotency: %v", err)
+			ctx.Next(c)
+			return
+		}
+
+		if record != nil {
+			idempotencyReplay(ctx, record, fingerprint)
+			return
+		}
+
+		mutex := lockerOf(client).NewMutex(locker.Keys("idempotency", key), redsync.WithExpiry(config.Lock), redsync.WithTries(1))
+
+		if err = mutex.TryLockContext(c); err != nil {
+
+			var taken *redsync.ErrTaken
+
+			if errors.Is(err, redsync.ErrFailed) || errors.As(err, &taken) {
+				ctx.Abort()
+				http.Conflict(ctx, "A request with the same "+config.Header+" is being processed")
+				return
+			}
+
+			hlog.CtxErrorf(c, "idempotency: %v", err)
+			ctx.Next(c)
+			return
+		}
+
+		defer func() {
+			_, _ = mutex.UnlockContext(context.WithoutCancel(c))
+		}()
+
+		// 加锁前首次请求可能刚刚完成
+		if record, err = idempotencyLoad(c, client, key); err == nil && record != nil {
+			idempotencyReplay(ctx, record, fingerprint)
+			return
+		}
+
+		ctx.Next(c)
+
+		// 服务端错误不保存，客户端可以使用同一个幂等键重试
+		if ctx.Response.StatusCode() >= consts.StatusInternalServerError {
+			return
+		}
+
+		record = &idempotent{
+			Fingerprint: fingerprint,
+			Status:      ctx.Response.StatusCode(),
+			Headers:     replayHeaders(ctx),
+			Body:        ctx.Response.Body(),
+		}
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			hlog.CtxErrorf(c, "idempotency: %v", err)
+			return
+		}
+
+		if err = client.Set(context.WithoutCancel(c), key, data, config.TTL).Err(); err != nil {
+			hlog.CtxErrorf(c, "idempotency: %v", err)
+		}
+	}
+}
+
+// KeyOfIdempotency 保存响应的 key，幂等键只保存摘要
+func KeyOfIdempotency(scope, value string) string {
+
+	sum := sha256.Sum256([]byte(value))
+
+	return util.Keys("idempotency", scope, hex.EncodeToString(sum[:]))
+}
+
+// idempotencyFingerprint 请求指纹，由请求方法、路径、查询参数和请求体组成
+func idempotencyFingerprint(ctx *app.RequestContext) string {
+
+	hash := sha256.New()
+
+	hash.Write(ctx.Request.Header.Method())
+	hash.Write([]byte{0})
+	hash.Write(ctx.URI().Path())
+	hash.Write([]byte{0})
+	hash.Write(ctx.URI().QueryString())
+	hash.Write([]byte{0})
+	hash.Write(ctx.Request.Body())
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func idempotencyLoad(c context.Context, client *redis.Client, key string) (*idempotent, error) {
+
+	data, err := client.Get(c, key).Bytes()
+
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var record idempotent
+
+	if err = json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// idempotencyReplay 重放首次响应，请求内容不一致时返回冲突
+func idempotencyReplay(ctx *app.RequestContext, record *idempotent, fingerprint string) {
+
+	ctx.Abort()
+
+	if record.Fingerprint != fingerprint {
+		http.Conflict(ctx, "Idempotency key has been used with a different request")
+		return
+	}
+
+	for name, value := range record.Headers {
+		ctx.Response.Header.Set(name, value)
+	}
+
+	ctx.Response.Header.Set("Idempotent-Replayed", "true")
+	ctx.Response.SetStatusCode(record.Status)
+	ctx.Response.SetBody(record.Body)
+}
+
+// replayHeaders 按白名单取出需要重放的响应头
+func replayHeaders(ctx *app.RequestContext) map[string]string {
+
+	headers := make(map[string]string)
+
+	for _, name := range replayAllowHeaders {
+		if value := ctx.Response.Header.Peek(name); len(value) > 0 {
+			headers[name] = string(value)
+		}
+	}
+
+	return headers
+}
+
+// lockerOf 优先使用 microservice/locker 注册的分布式锁
+func lockerOf(client *redis.Client) *redsync.Redsync {
+
+	if value, ok := facades.Optional[*redsync.Redsync](); ok {
+		return value
+	}
+
+	return redsync.New(goredis.NewPool(client))
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/microservice/locker"
+)
+
+func TestIdempotencyReplaysFirstResponse(t *testing.T) {
+	setup(t, map[string]any{"app.name": "framework"})
+	useRedis(t)
+
+	calls := 0
+
+	handlers := app.HandlersChain{Idempotency(), func(c context.Context, ctx *app.RequestContext) {
+		calls++
+		ctx.Header("Location", "/orders/1")
+		ctx.Header("Authorization", "Bearer refreshed")
+		ctx.Header("X-CSRF-Token", "token")
+		ctx.JSON(201, map[string]any{"id": calls})
+	}}
+
+	send := func(method, key, body string) *app.RequestContext {
+		ctx := request(method, "/orders", func(ctx *app.RequestContext) {
+			ctx.Request.Header.Set("X-Real-IP", "10.0.0.1")
+			if key != "" {
+				ctx.Request.Header.Set("Idempotency-Key", key)
+			}
+			ctx.Request.SetBodyString(body)
+		})
+		ctx.SetHandlers(handlers)
+		ctx.Next(context.Background())
+		return ctx
+	}
+
+	first := send("POST", "k1", `{"sku":"a"}`)
+
+	if calls != 1 || first.Response.StatusCode() != 201 {
+		t.Fatalf("expected first request to run, calls %d status %d", calls, first.Response.StatusCode())
+	}
+
+	retry := send("POST", "k1", `{"sku":"a"}`)
+
+	if calls != 1 || retry.Response.StatusCode() != 201 || string(retry.Response.Body()) != string(first.Response.Body()) {
+		t.Fatalf("expected replayed response, calls %d body %s", calls, retry.Response.Body())
+	}
+
+	if string(retry.Response.Header.Peek("Idempotent-Replayed")) != "true" || string(retry.Response.Header.Peek("Location")) != "/orders/1" {
+		t.Fatal("expected replay headers")
+	}
+
+	if len(retry.Response.Header.Peek("Authorization")) > 0 || len(retry.Response.Header.Peek("X-CSRF-Token")) > 0 {
+		t.Fatal("expected credentials not to be replayed")
+	}
+
+	if content := string(retry.Response.Header.ContentType()); content != "application/json; charset=utf-8" {
+		t.Fatalf("expected content type to be replayed, got %q", content)
+	}
+
+	if conflict := send("POST", "k1", `{"sku":"b"}`); calls != 1 || conflict.Response.StatusCode() != 409 {
+		t.Fatalf("expected conflict for different body, got %d", conflict.Response.StatusCode())
+	}
+
+	send("POST", "", `{"sku":"a"}`)
+	send("GET", "k1", "")
+
+	if calls != 3 {
+		t.Fatalf("expected requests without key and safe methods to run, calls %d", calls)
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	setup(t, map[string]any{"app.name": "framework"})
+	useRedis(t)
+
+	required := true
+
+	handler := Idempotency(IdempotencyConfig{
+		Required: &required,
+		Scope: func(c context.Context, ctx *app.RequestContext) string {
+			return "user:1"
+		},
+	})
+
+	ctx := request("POST", "/orders", nil)
+	handler(context.Background(), ctx)
+
+	if !ctx.IsAborted() {
+		t.Fatal("expected missing key to be rejected when required")
+	}
+
+	// 模拟首次请求仍在处理
+	client := facades.Redis().Default()
+	client.Set(context.Background(), locker.Keys("idempotency", KeyOfIdempotency("user:1", "k2")), "other", 0)
+
+	ctx = request("POST", "/orders", func(ctx *app.RequestContext) {
+		ctx.Request.Header.Set("Idempotency-Key", "k2")
+	})
+	handler(context.Background(), ctx)
+
+	if !ctx.IsAborted() || ctx.Response.StatusCode() != 409 {
+		t.Fatalf("expected in progress request to conflict, got %d", ctx.Response.StatusCode())
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/auth/impersonation"
+	"github.com/herhe-com/framework/http"
+	"github.com/samber/lo"
+)
+
+// Impersonation 校验 token 中的借位会话是否仍然有效，并记录借位期间的写请求
+func Impersonation() app.HandlerFunc {
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		if !auth.Check(ctx) {
+			ctx.Next(c)
+			return
+		}
+
+		log := &impersonation.Log{
+			Actor: impersonation.Actor(ctx),
+			Event: impersonation.EventOfRequest,
+		}
+
+		if id := impersonation.SessionOf(ctx); id != "" {
+
+			// 借位已结束或被强制结束，携带旧会话的 token 不能继续使用
+			session, err := impersonation.Find(c, log.Actor)
+
+			if err != nil || session.ID != id {
+				ctx.Abort()
+				http.Unauthorized(ctx)
+				return
+			}
+
+			log.Session = session.ID
+			log.Platform, log.Org, log.Organization = session.Platform, session.Org, session.Organization
+
+		} else if role, _ := auth.Temporary(c, ctx); role != nil {
+
+			// 直接通过 auth.SetTemporaryRole 设置的临时角色同样记录
+			log.Platform, log.Org, log.Organization = role.Platform, role.Org, role.Organization
+
+		} else {
+			ctx.Next(c)
+			return
+		}
+
+		ctx.Next(c)
+
+		method := string(ctx.Request.Header.Method())
+
+		if !lo.Contains([]string{consts.MethodPost, consts.MethodPut, consts.MethodPatch, consts.MethodDelete}, method) {
+			return
+		}
+
+		log.Method = method
+		log.Path = string(ctx.Request.URI().Path())
+		log.Status = ctx.Response.StatusCode()
+		log.IP = ctx.ClientIP()
+
+		if err := impersonation.Record(c, log); err != nil {
+			hlog.CtxErrorf(c, "failed to record impersonation request: %v", err)
+		}
+	}
+}
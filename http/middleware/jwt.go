@@ -4,9 +4,9 @@ import (
 	"context"
 
 	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/audit"
 	"github.com/herhe-com/framework/auth"
 	contractauth "github.com/herhe-com/framework/contracts/auth"
-	"github.com/herhe-com/framework/facades"
 )
 
 func Jwt() app.HandlerFunc {
@@ -22,11 +22,8 @@ func Jwt() app.HandlerFunc {
 			refresh, err := auth.CheckJWToken(&claims, string(token))
 
 			if err == nil {
-				ctx.Set(auth.ContextOfID, claims.Subject)
-				ctx.Set(auth.ContextOfClaims, claims)
-			}
-
-			if platform := auth.DefaultPlatform(); platform > 0 {
+				auth.Authenticate(ctx, &claims)
+			} else if platform := auth.DefaultPlatform(); platform > 0 {
 				ctx.Set(auth.ContextOfPlatform, platform)
 			}
 
@@ -38,26 +35,23 @@ func Jwt() app.HandlerFunc {
 					return
 				}
 
-				ctx.Set(auth.ContextOfID, claims.Subject)
-				ctx.Set(auth.ContextOfClaims, claims)
+				auth.Authenticate(ctx, &claims)
 
 				ctx.Header(auth.Authorization, refreshToken)
 
-				//  获取令牌刷新后的操作
-				if callback := facades.Config().Get("auth.callback.refresh"); callback != nil {
+				audit.RecordRequest(c, ctx, &audit.Event{
+					Type:     audit.TypeOfTokenRefreshed,
+					Actor:    claims.Subject,
+					Subject:  claims.ID,
+					Platform: auth.Platform(ctx),
+					Result:   audit.ResultOfSuccess,
+				})
 
-					if function, ok := callback.(func(c context.Context, ctx *app.RequestContext)); ok {
-						function(c, ctx)
-					}
-				}
+				//  获取令牌刷新后的操作
+				auth.Callback(c, ctx, "refresh")
 			}
 
-			if callback := facades.Config().Get("auth.callback.jwt"); callback != nil {
-
-				if function, ok := callback.(func(c context.Context, ctx *app.RequestContext)); ok {
-					function(c, ctx)
-				}
-			}
+			auth.Callback(c, ctx, "jwt")
 		}
 
 		ctx.Next(c)
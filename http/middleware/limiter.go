@@ -10,6 +10,9 @@ import (
 	"github.com/herhe-com/framework/support/util"
 )
 
+// Limiter 按路由 + 客户端 IP 的固定窗口限流，默认每分钟 3 次；超出时响应 http.Fail，Redis 出错时拒绝请求，没有 Redis 时不限流
+//
+// Deprecated: 使用 RateLimit，可以选择限流算法和限流对象，被拒绝时返回 429
 func Limiter(option *LimiterOption) app.HandlerFunc {
 
 	return func(c context.Context, ctx *app.RequestContext) {
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/facades"
+)
+
+func TestLoginLimiterKeepsFailResponseWhenLocked(t *testing.T) {
+	setup(t, map[string]any{"app.name": "framework"})
+	useRedis(t)
+
+	c := context.Background()
+
+	// 与旧版 LoginLimiter 相同的锁定 key
+	facades.Redis().Default().Set(c, "framework:login:lock:alice", "1", 90*time.Second)
+
+	ctx := request("POST", "/login", func(ctx *app.RequestContext) {
+		ctx.Request.Header.SetContentTypeBytes([]byte("application/json"))
+		ctx.Request.SetBodyString(`{"username":"alice"}`)
+	})
+
+	LoginLimiter()(c, ctx)
+
+	if !ctx.IsAborted() || ctx.Response.StatusCode() != 200 || len(ctx.Response.Header.Peek("Retry-After")) > 0 {
+		t.Fatalf("expected locked login to keep the http.Fail response, got %d", ctx.Response.StatusCode())
+	}
+
+	if body := string(ctx.Response.Body()); !strings.Contains(body, `"code":60000`) || !strings.Contains(body, "try again in 2 minutes") {
+		t.Fatalf("unexpected body %s", body)
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/auth/mfa"
+	"github.com/herhe-com/framework/http"
+)
+
+// MFA 敏感路由要求 token 中携带有效期内的二次验证声明，有效期默认读取 auth.mfa.fresh（分钟）
+func MFA(durations ...time.Duration) app.HandlerFunc {
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		if !mfa.Fresh(auth.Claims(ctx), durations...) {
+			ctx.Abort()
+			http.MFARequired(ctx)
+			return
+		}
+
+		ctx.Next(c)
+	}
+}
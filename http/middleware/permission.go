@@ -4,7 +4,9 @@ import (
 	"context"
 
 	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/audit"
 	"github.com/herhe-com/framework/auth"
+	contractauth "github.com/herhe-com/framework/contracts/auth"
 	"github.com/herhe-com/framework/facades"
 	"github.com/herhe-com/framework/http"
 )
@@ -13,25 +15,40 @@ func Permission(permission string) app.HandlerFunc {
 
 	return func(c context.Context, ctx *app.RequestContext) {
 
-		if ok, _ := facades.Casbin().HasRoleForUser(auth.NameOfUser(auth.ID(ctx)), auth.NameOfDeveloper()); ok {
-			ctx.Next(c)
-			return
+		// 第三方令牌（如 OAuth2）只能访问 scope 覆盖的权限，没有用户身份的令牌由 scope 直接授权
+		claims := auth.Claims(ctx)
+
+		if scope, ok := facades.Get[contractauth.Scope](); ok && scope.Restricted(claims) {
+
+			if !scope.Allowed(claims, permission) {
+				denied(c, ctx, permission, auth.Platform(ctx), auth.Organization(ctx).String, "scope")
+				ctx.Abort()
+				http.Forbidden(ctx)
+				return
+			}
+
+			if scope.Subjectless(claims) {
+				ctx.Next(c)
+				return
+			}
 		}
 
-		if temporary, _ := auth.Temporary(c, ctx); temporary != nil {
+		subject := auth.NameOfUser(auth.ID(ctx))
+
+		if auth.IsDeveloper(subject) {
 			ctx.Next(c)
 			return
 		}
 
-		permissions := []any{auth.NameOfUser(auth.ID(ctx)), permission}
-
-		platform := auth.Platform(ctx)
+		platform, organization := auth.Platform(ctx), auth.Organization(ctx).String
 
-		if platform > 0 {
-			permissions = append(permissions, auth.SPlatform(ctx))
+		// 临时角色只切换校验的作用域，仍然需要在借位组织（或其上级组织）拥有权限
+		if temporary, _ := auth.Temporary(c, ctx); temporary != nil {
+			platform, organization = temporary.Platform, temporary.Org
 		}
 
-		if ok, _ := facades.Casbin().Enforce(permissions...); !ok {
+		if ok, _ := auth.Enforce(subject, permission, platform, organization); !ok {
+			denied(c, ctx, permission, platform, organization, "policy")
 			ctx.Abort()
 			http.Forbidden(ctx)
 			return
@@ -40,3 +57,16 @@ func Permission(permission string) app.HandlerFunc {
 		ctx.Next(c)
 	}
 }
+
+// denied 记录权限校验未通过的审计日志
+func denied(c context.Context, ctx *app.RequestContext, permission string, platform uint16, organization, reason string) {
+	audit.RecordRequest(c, ctx, &audit.Event{
+		Type:         audit.TypeOfPermissionDenied,
+		Actor:        auth.ID(ctx),
+		Subject:      permission,
+		Platform:     platform,
+		Organization: organization,
+		Result:       audit.ResultOfDenied,
+		Message:      reason,
+	})
+}
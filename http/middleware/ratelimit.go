@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/herhe-com/framework/http"
+	"github.com/herhe-com/framework/ratelimit"
+)
+
+// RateLimit
+//
+//	@Description: 按 limit 限流，响应头返回 X-RateLimit-*，被拒绝时返回 429 和 Retry-After
+//	@param limit	限流规则
+//	@param keys	限流对象，多个时组合使用，为空时按路由 + 客户端 IP
+func RateLimit(limit ratelimit.Limit, keys ...ratelimit.KeyFunc) app.HandlerFunc {
+
+	if len(keys) == 0 {
+		keys = []ratelimit.KeyFunc{ratelimit.ByRoute, ratelimit.ByIP}
+	}
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		names := make([]string, 0, len(keys))
+
+		for _, key := range keys {
+			names = append(names, key(c, ctx))
+		}
+
+		result, err := ratelimit.Allow(c, strings.Join(names, ":"), limit)
+
+		if err != nil {
+			hlog.CtxErrorf(c, "rate limit: %v", err)
+			ctx.Next(c)
+			return
+		}
+
+		ctx.Header("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+		ctx.Header("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+		ctx.Header("X-RateLimit-Reset", seconds(result.ResetAfter))
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", seconds(result.RetryAfter))
+			ctx.Abort()
+			http.TooManyRequests(ctx)
+			return
+		}
+
+		ctx.Next(c)
+	}
+}
+
+// seconds 向上取整的秒数
+func seconds(duration time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(duration.Seconds())), 10)
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/http"
+	"github.com/herhe-com/framework/reporter"
+	"github.com/samber/lo"
+)
+
+// 不上报的敏感请求头
+var recoverySkipHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "X-Csrf-Token", "X-Api-Key"}
+
+// 上报时隐藏值的敏感查询参数，如 OAuth2 回调的 code、state，WebSocket 的 token
+var recoveryRedactQueries = []string{"token", "access_token", "refresh_token", "id_token", "code", "state", "password", "secret", "client_secret", "signature", "sign", "ticket"}
+
+// Recovery 捕获 panic，记录调用栈和请求信息，返回 http.InternalServerError 并上报到 reporter.reporters
+func Recovery() app.HandlerFunc {
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		defer func() {
+
+			value := recover()
+
+			if value == nil {
+				return
+			}
+
+			stack := debug.Stack()
+
+			event := &reporter.Event{
+				Level:   "fatal",
+				Type:    fmt.Sprintf("%T", value),
+				Message: fmt.Sprint(value),
+				Frames:  panicFrames(),
+				Stack:   string(stack),
+				Method:  string(ctx.Request.Header.Method()),
+				URL:     recoveryURL(ctx),
+				IP:      ctx.ClientIP(),
+				User:    auth.ID(ctx),
+				Headers: make(map[string]string),
+				Tags:    map[string]string{"route": ctx.FullPath()},
+			}
+
+			if platform := auth.Platform(ctx); platform > 0 {
+				event.Tags["platform"] = strconv.Itoa(int(platform))
+			}
+
+			ctx.Request.Header.VisitAll(func(name, value []byte) {
+				if !lo.ContainsBy(recoverySkipHeaders, func(item string) bool {
+					return strings.EqualFold(item, string(name))
+				}) {
+					event.Headers[string(name)] = string(value)
+				}
+			})
+
+			hlog.CtxErrorf(c, "[Recovery] panic recovered: %v\nmethod=%s url=%s ip=%s user=%s\n%s", value, event.Method, event.URL, event.IP, event.User, stack)
+
+			if reporter.Enabled() {
+				go func() {
+					if err := reporter.Capture(context.WithoutCancel(c), event); err != nil {
+						hlog.CtxErrorf(c, "failed to report panic: %v", err)
+					}
+				}()
+			}
+
+			ctx.Abort()
+			http.InternalServerError(ctx)
+		}()
+
+		ctx.Next(c)
+	}
+}
+
+// panicFrames 从引发 panic 的函数开始的调用栈
+func panicFrames() []reporter.Frame {
+
+	frames := reporter.Frames(1)
+
+	for index, frame := range frames {
+		if frame.Function == "runtime.gopanic" {
+			return frames[index+1:]
+		}
+	}
+
+	return frames
+}
+
+// recoveryURL 上报的请求地址，敏感查询参数的值替换为 ***
+func recoveryURL(ctx *app.RequestContext) string {
+
+	uri := ctx.URI()
+
+	result := string(uri.Scheme()) + "://" + string(uri.Host()) + string(uri.Path())
+
+	queries := make([]string, 0)
+
+	uri.QueryArgs().VisitAll(func(key, value []byte) {
+
+		item := url.QueryEscape(string(value))
+
+		if lo.ContainsBy(recoveryRedactQueries, func(name string) bool {
+			return strings.EqualFold(name, string(key))
+		}) {
+			item = "***"
+		}
+
+		queries = append(queries, url.QueryEscape(string(key))+"="+item)
+	})
+
+	if len(queries) > 0 {
+		result += "?" + strings.Join(queries, "&")
+	}
+
+	return result
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/auth"
+	"github.com/herhe-com/framework/reporter"
+)
+
+type channelReporter chan *reporter.Event
+
+func (r channelReporter) Report(c context.Context, event *reporter.Event) error {
+	r <- event
+	return nil
+}
+
+func TestRecoveryReportsPanic(t *testing.T) {
+	setup(t, map[string]any{"reporter.reporters": []string{"channel"}})
+
+	events := make(channelReporter, 1)
+	reporter.Extend("channel", events)
+
+	ctx := request("GET", "/orders?id=1&token=secret&code=oauth", func(ctx *app.RequestContext) {
+		ctx.Set(auth.ContextOfID, "7")
+		ctx.Request.Header.Set("Authorization", "Bearer secret")
+	})
+	ctx.SetHandlers(app.HandlersChain{Recovery(), func(c context.Context, ctx *app.RequestContext) {
+		panic("boom")
+	}})
+	ctx.Next(context.Background())
+
+	if ctx.Response.StatusCode() != 500 || !strings.Contains(string(ctx.Response.Body()), `"code":50000`) {
+		t.Fatalf("expected json envelope, got %d %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	select {
+	case event := <-events:
+
+		if event.Message != "boom" || event.User != "7" || event.Method != "GET" || !strings.Contains(event.URL, "/orders?id=1&token=***&code=***") || event.Headers["Authorization"] != "" {
+			t.Fatalf("unexpected request context: %+v", event)
+		}
+
+		if len(event.Frames) == 0 || !strings.Contains(event.Frames[0].Function, "TestRecoveryReportsPanic") {
+			t.Fatalf("expected stack to start at the panicking function, got %+v", event.Frames)
+		}
+
+	case <-time.After(time.Second):
+		t.Fatal("expected panic to be reported")
+	}
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/contracts/database"
+	"github.com/herhe-com/framework/facades"
+	"github.com/redis/go-redis/v9"
+)
+
+type fakeRedis struct {
+	client *redis.Client
+}
+
+func (f fakeRedis) Default() *redis.Client {
+	return f.client
+}
+
+func (f fakeRedis) Channel(name string) (*redis.Client, error) {
+	return f.client, nil
+}
+
+func useRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	facades.Register[database.Redis](fakeRedis{client: redis.NewClient(&redis.Options{Addr: server.Addr()})})
+}
+
+func request(method, path string, prepare func(ctx *app.RequestContext)) *app.RequestContext {
+
+	ctx := app.NewContext(0)
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(path)
+
+	if prepare != nil {
+		prepare(ctx)
+	}
+
+	return ctx
+}
+
+func TestSecureHeadersAndNonce(t *testing.T) {
+	setup(t, map[string]any{
+		"server.security.csp": "default-src 'self'; script-src 'self' 'nonce-{nonce}'",
+	})
+
+	handler := Secure()
+
+	ctx := request("GET", "/", nil)
+	handler(context.Background(), ctx)
+
+	nonce := Nonce(ctx)
+
+	if nonce == "" || !strings.Contains(string(ctx.Response.Header.Peek("Content-Security-Policy")), "'nonce-"+nonce+"'") {
+		t.Fatalf("expected nonce in csp, got %q", ctx.Response.Header.Peek("Content-Security-Policy"))
+	}
+
+	if string(ctx.Response.Header.Peek("X-Frame-Options")) != "DENY" || len(ctx.Response.Header.Peek("Strict-Transport-Security")) > 0 {
+		t.Fatal("expected frame options and no hsts on plain http")
+	}
+
+	ctx = request("GET", "/", func(ctx *app.RequestContext) {
+		ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+	})
+	handler(context.Background(), ctx)
+
+	if hsts := string(ctx.Response.Header.Peek("Strict-Transport-Security")); hsts != "max-age=31536000" {
+		t.Fatalf("expected hsts behind https proxy, got %q", hsts)
+	}
+
+	if Nonce(ctx) == nonce {
+		t.Fatal("expected a new nonce per request")
+	}
+}
+
+func TestCSRFSynchronizerToken(t *testing.T) {
+	setup(t, map[string]any{
+		"app.name":                       "framework",
+		"server.security.csrf.session":   "sid",
+		"server.security.csrf.exempt":    []string{"/webhooks"},
+		"server.security.csrf.same_site": "strict",
+	})
+	useRedis(t)
+
+	handler := CSRF()
+	c := context.Background()
+
+	ctx := request("GET", "/admin", func(ctx *app.RequestContext) {
+		ctx.Request.Header.SetCookie("sid", "s1")
+	})
+	handler(c, ctx)
+
+	token := CSRFToken(ctx)
+
+	if token == "" || string(ctx.Response.Header.Peek("X-CSRF-Token")) != token || !strings.Contains(string(ctx.Response.Header.Peek("Set-Cookie")), "csrf_token="+token) {
+		t.Fatalf("expected token issued in cookie and header, got %q", ctx.Response.Header.Peek("Set-Cookie"))
+	}
+
+	post := func(sid, cookie, header string, prepare func(ctx *app.RequestContext)) *app.RequestContext {
+		ctx := request("POST", "/admin/users", func(ctx *app.RequestContext) {
+			ctx.Request.Header.SetCookie("sid", sid)
+			ctx.Request.Header.SetCookie("csrf_token", cookie)
+			if header != "" {
+				ctx.Request.Header.Set("X-CSRF-Token", header)
+			}
+			if prepare != nil {
+				prepare(ctx)
+			}
+		})
+		handler(c, ctx)
+		return ctx
+	}
+
+	if ctx = post("s1", token, token, nil); ctx.IsAborted() {
+		t.Fatal("expected matching token to pass")
+	}
+
+	if ctx = post("s1", token, "", nil); !ctx.IsAborted() {
+		t.Fatal("expected missing header to be rejected")
+	}
+
+	// 令牌与会话绑定
+	if ctx = post("s2", token, token, nil); !ctx.IsAborted() {
+		t.Fatal("expected token from another session to be rejected")
+	}
+
+	// 没有经过服务端签发的令牌
+	forged := strings.Repeat("A", 43)
+
+	if ctx = post("s1", forged, forged, nil); !ctx.IsAborted() {
+		t.Fatal("expected forged token to be rejected")
+	}
+
+	if ctx = post("s1", "", "", func(ctx *app.RequestContext) {
+		ctx.Request.Header.Set("Authorization", "Bearer abc")
+	}); ctx.IsAborted() {
+		t.Fatal("expected bearer request to skip csrf")
+	}
+
+	ctx = request("POST", "/webhooks/pay", nil)
+	handler(c, ctx)
+
+	if ctx.IsAborted() {
+		t.Fatal("expected exempt path to skip csrf")
+	}
+
+	// 按路径段匹配，/webhooks 不能放行 /webhooksx
+	ctx = request("POST", "/webhooksx/pay", nil)
+	handler(c, ctx)
+
+	if !ctx.IsAborted() || ctx.Response.StatusCode() != 403 {
+		t.Fatalf("expected sibling path to be checked, got %d", ctx.Response.StatusCode())
+	}
+}
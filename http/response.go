@@ -42,6 +42,34 @@ func Forbidden(ctx *app.RequestContext) {
 	})
 }
 
+func MFARequired(ctx *app.RequestContext) {
+	ctx.JSON(http.StatusForbidden, response.Response[any]{
+		Code:    40310,
+		Message: "Second factor required",
+	})
+}
+
+func TooManyRequests(ctx *app.RequestContext) {
+	ctx.JSON(http.StatusTooManyRequests, response.Response[any]{
+		Code:    42900,
+		Message: "Too many requests",
+	})
+}
+
+func InternalServerError(ctx *app.RequestContext) {
+	ctx.JSON(http.StatusInternalServerError, response.Response[any]{
+		Code:    50000,
+		Message: "Internal Server Error",
+	})
+}
+
+func Conflict(ctx *app.RequestContext, message string) {
+	ctx.JSON(http.StatusConflict, response.Response[any]{
+		Code:    40900,
+		Message: message,
+	})
+}
+
 func NotFound(ctx *app.RequestContext, message string) {
 	ctx.JSON(http.StatusOK, response.Response[any]{
 		Code:    40400,
@@ -0,0 +1,255 @@
+package route
+
+import (
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+
+	hertz "github.com/cloudwego/hertz/pkg/route"
+	"github.com/herhe-com/framework/contracts/auth"
+	"github.com/herhe-com/framework/facades"
+	"github.com/samber/lo"
+)
+
+// Report 路由权限与 auth.permissions 的比对结果
+type Report struct {
+	Routes      []Route           `json:"routes"`      // 已在 Hertz 中注册并声明了权限的路由
+	Permissions []auth.Permission `json:"permissions"` // 由路由生成的权限树
+	Missing     []string          `json:"missing"`     // 路由使用了但权限树中不存在的权限码
+	Unused      []string          `json:"unused"`      // 权限树中存在但没有路由使用的权限码
+}
+
+// Inspect
+//
+//	@Description: 遍历 Hertz 已注册的路由，生成权限树并与 auth.permissions 比对
+//	@param engine	已完成路由注册的 Hertz 引擎
+func Inspect(engine *hertz.Engine) Report {
+
+	registered := make(map[string]bool)
+
+	for _, item := range engine.Routes() {
+		registered[item.Method+" "+item.Path] = true
+	}
+
+	routes := lo.Filter(Routes(), func(item Route, index int) bool {
+		return registered[item.Method+" "+item.Path]
+	})
+
+	used := lo.Uniq(lo.Map(routes, func(item Route, index int) string {
+		return item.Code
+	}))
+
+	leaves := make([]string, 0)
+
+	for _, item := range flatten(configured(), "") {
+		if len(item.Children) == 0 {
+			leaves = append(leaves, item.Code)
+		}
+	}
+
+	missing, unused := lo.Difference(used, leaves)
+
+	sort.Strings(missing)
+	sort.Strings(unused)
+
+	return Report{
+		Routes:      routes,
+		Permissions: Generate(routes),
+		Missing:     missing,
+		Unused:      unused,
+	}
+}
+
+// Generate
+//
+//	@Description: 按权限码的层级（以 . 分隔）将路由生成 auth.Permission 树，目录名称、适用平台沿用 auth.permissions 中的配置
+//	@param routes	声明了权限的路由
+func Generate(routes []Route) []auth.Permission {
+
+	existing := make(map[string]auth.Permission)
+	order := make(map[string]int)
+
+	for index, item := range flatten(configured(), "") {
+		existing[item.Code] = item
+		order[item.Code] = index
+	}
+
+	names := make(map[string]string)
+
+	for _, item := range routes {
+		if _, ok := names[item.Code]; !ok || names[item.Code] == "" {
+			names[item.Code] = item.Name
+		}
+	}
+
+	codes := lo.Keys(names)
+
+	// 已配置的权限保持原有顺序，新权限排在后面
+	sort.Slice(codes, func(i, j int) bool {
+
+		a, oka := order[codes[i]]
+		b, okb := order[codes[j]]
+
+		if oka && okb {
+			return a < b
+		} else if oka != okb {
+			return oka
+		}
+
+		return codes[i] < codes[j]
+	})
+
+	root := &node{}
+
+	for _, code := range codes {
+
+		current := root
+
+		segments := strings.Split(code, ".")
+
+		for index := range segments {
+			current = current.child(strings.Join(segments[:index+1], "."), segments[index], existing)
+		}
+
+		if names[code] != "" {
+			current.permission.Name = names[code]
+		}
+	}
+
+	return root.permissions()
+}
+
+// Source
+//
+//	@Description: 生成权限树的 Go 源码
+//	@param pkg	包名
+//	@param name	变量名
+func Source(pkg, name string, permissions []auth.Permission) ([]byte, error) {
+
+	var builder strings.Builder
+
+	builder.WriteString("// Code generated by \"permission routes\"; DO NOT EDIT.\n\n")
+	builder.WriteString("package " + pkg + "\n\n")
+	builder.WriteString("import \"github.com/herhe-com/framework/contracts/auth\"\n\n")
+	builder.WriteString("var " + name + " = ")
+
+	write(&builder, permissions)
+
+	builder.WriteString("\n")
+
+	return format.Source([]byte(builder.String()))
+}
+
+type node struct {
+	permission auth.Permission
+	children   []*node
+	index      map[string]*node
+}
+
+func (n *node) child(code, segment string, existing map[string]auth.Permission) *node {
+
+	if n.index == nil {
+		n.index = make(map[string]*node)
+	}
+
+	if item, ok := n.index[segment]; ok {
+		return item
+	}
+
+	permission := auth.Permission{Code: segment, Name: segment}
+
+	if item, ok := existing[code]; ok {
+		permission.Name = item.Name
+		permission.Common = item.Common
+		permission.Platforms = item.Platforms
+	}
+
+	item := &node{permission: permission}
+
+	n.index[segment] = item
+	n.children = append(n.children, item)
+
+	return item
+}
+
+func (n *node) permissions() []auth.Permission {
+
+	if len(n.children) == 0 {
+		return nil
+	}
+
+	permissions := make([]auth.Permission, 0, len(n.children))
+
+	for _, item := range n.children {
+
+		permission := item.permission
+		permission.Children = item.permissions()
+
+		// 目录节点不需要适用平台
+		if len(permission.Children) > 0 {
+			permission.Common = false
+			permission.Platforms = nil
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	return permissions
+}
+
+func configured() []auth.Permission {
+	permissions, _ := facades.Config().Get("auth.permissions").([]auth.Permission)
+	return permissions
+}
+
+// flatten 展开权限配置，Code 为完整的权限码
+func flatten(permissions []auth.Permission, prefix string) (results []auth.Permission) {
+
+	for _, item := range permissions {
+
+		code := item.Code
+
+		if prefix != "" {
+			code = prefix + "." + item.Code
+		}
+
+		children := item.Children
+
+		item.Code = code
+		results = append(results, item)
+		results = append(results, flatten(children, code)...)
+	}
+
+	return results
+}
+
+func write(builder *strings.Builder, permissions []auth.Permission) {
+
+	builder.WriteString("[]auth.Permission{\n")
+
+	for _, item := range permissions {
+
+		builder.WriteString(fmt.Sprintf("{Code: %q, Name: %q", item.Code, item.Name))
+
+		if item.Common {
+			builder.WriteString(", Common: true")
+		}
+
+		if len(item.Platforms) > 0 {
+			builder.WriteString(", Platforms: []uint16{" + strings.Join(lo.Map(item.Platforms, func(platform uint16, index int) string {
+				return strconv.Itoa(int(platform))
+			}), ", ") + "}")
+		}
+
+		if len(item.Children) > 0 {
+			builder.WriteString(", Children: ")
+			write(builder, item.Children)
+		}
+
+		builder.WriteString("},\n")
+	}
+
+	builder.WriteString("}")
+}
@@ -0,0 +1,128 @@
+package route
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	hertz "github.com/cloudwego/hertz/pkg/route"
+	"github.com/herhe-com/framework/http/middleware"
+)
+
+// Route 声明了权限的路由
+type Route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+}
+
+var (
+	mutex    sync.RWMutex
+	declared = make(map[string]Route)
+)
+
+// Handle
+//
+//	@Description: 注册路由并声明权限，路由会自动挂载 middleware.Permission(code)
+//	@param router	Hertz 路由或路由组
+//	@param method	请求方法
+//	@param relative	相对路径
+//	@param code	权限码，如 user.list
+//	@param name	权限名称
+//	@param handlers	处理函数，位于权限校验之后
+func Handle(router hertz.IRoutes, method, relative, code, name string, handlers ...app.HandlerFunc) hertz.IRoutes {
+
+	declare(Route{
+		Method: method,
+		Path:   join(router, relative),
+		Code:   code,
+		Name:   name,
+	})
+
+	return router.Handle(method, relative, append([]app.HandlerFunc{middleware.Permission(code)}, handlers...)...)
+}
+
+func GET(router hertz.IRoutes, relative, code, name string, handlers ...app.HandlerFunc) hertz.IRoutes {
+	return Handle(router, consts.MethodGet, relative, code, name, handlers...)
+}
+
+func POST(router hertz.IRoutes, relative, code, name string, handlers ...app.HandlerFunc) hertz.IRoutes {
+	return Handle(router, consts.MethodPost, relative, code, name, handlers...)
+}
+
+func PUT(router hertz.IRoutes, relative, code, name string, handlers ...app.HandlerFunc) hertz.IRoutes {
+	return Handle(router, consts.MethodPut, relative, code, name, handlers...)
+}
+
+func PATCH(router hertz.IRoutes, relative, code, name string, handlers ...app.HandlerFunc) hertz.IRoutes {
+	return Handle(router, consts.MethodPatch, relative, code, name, handlers...)
+}
+
+func DELETE(router hertz.IRoutes, relative, code, name string, handlers ...app.HandlerFunc) hertz.IRoutes {
+	return Handle(router, consts.MethodDelete, relative, code, name, handlers...)
+}
+
+// Routes 已声明权限的路由，按路径、方法排序
+func Routes() []Route {
+
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	routes := make([]Route, 0, len(declared))
+
+	for _, item := range declared {
+		routes = append(routes, item)
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path == routes[j].Path {
+			return routes[i].Method < routes[j].Method
+		}
+		return routes[i].Path < routes[j].Path
+	})
+
+	return routes
+}
+
+// Reset 清空已声明的路由
+func Reset() {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	declared = make(map[string]Route)
+}
+
+func declare(route Route) {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	declared[route.Method+" "+route.Path] = route
+}
+
+// join 与 Hertz 拼接路由组路径的规则保持一致
+func join(router hertz.IRoutes, relative string) string {
+
+	base := "/"
+
+	if group, ok := router.(interface{ BasePath() string }); ok {
+		base = group.BasePath()
+	}
+
+	if relative == "" {
+		return base
+	}
+
+	final := path.Join(base, relative)
+
+	if strings.HasSuffix(relative, "/") && !strings.HasSuffix(final, "/") {
+		final += "/"
+	}
+
+	return final
+}
@@ -0,0 +1,140 @@
+package route
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/herhe-com/framework/contracts/auth"
+	contractconfig "github.com/herhe-com/framework/contracts/config"
+	"github.com/herhe-com/framework/facades"
+)
+
+type fakeConfig struct {
+	values map[string]any
+}
+
+func (f fakeConfig) Env(key string, defaultValue ...any) any {
+	return f.Get(key, defaultValue...)
+}
+
+func (f fakeConfig) Add(name string, configuration map[string]any) {}
+
+func (f fakeConfig) Set(key string, configuration any) {
+	f.values[key] = configuration
+}
+
+func (f fakeConfig) Get(key string, defaultValue ...any) any {
+	if value, ok := f.values[key]; ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetString(key string, defaultValue ...string) string {
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return ""
+}
+
+func (f fakeConfig) GetStrings(key string, defaultValue ...[]string) []string {
+	return nil
+}
+
+func (f fakeConfig) GetMaps(key string, defaultValue ...map[string]any) map[string]any {
+	return nil
+}
+
+func (f fakeConfig) GetInt(key string, defaultValue ...int) int {
+	return 0
+}
+
+func (f fakeConfig) GetInt64(key string, defaultValue ...int64) int64 {
+	return 0
+}
+
+func (f fakeConfig) GetBool(key string, defaultValue ...bool) bool {
+	return false
+}
+
+func (f fakeConfig) IsSet(key string) bool {
+	_, ok := f.values[key]
+	return ok
+}
+
+func handler(c context.Context, ctx *app.RequestContext) {}
+
+func TestInspectGeneratesTreeAndReportsDrift(t *testing.T) {
+	original := facades.Container()
+	facades.SetContainer(&facades.Services{})
+	t.Cleanup(func() {
+		facades.SetContainer(original)
+		Reset()
+	})
+
+	facades.Register[contractconfig.Application](fakeConfig{values: map[string]any{
+		"auth.permissions": []auth.Permission{
+			{Code: "user", Name: "用户管理", Children: []auth.Permission{
+				{Code: "list", Name: "用户列表", Platforms: []uint16{999}},
+				{Code: "delete", Name: "删除用户"},
+			}},
+		},
+	}})
+
+	serv := server.New()
+
+	group := serv.Group("/admin/users")
+
+	GET(group, "", "user.list", "", handler)
+	POST(group, "/:id/disable", "user.disable", "禁用用户", handler)
+	GET(serv, "/orders", "order.list", "订单列表", handler)
+
+	// 没有注册到 Hertz 的声明不参与比对
+	declare(Route{Method: "GET", Path: "/ghost", Code: "ghost.list"})
+
+	report := Inspect(serv.Engine)
+
+	if len(report.Routes) != 3 {
+		t.Fatalf("expected 3 routes, got %+v", report.Routes)
+	}
+
+	if report.Routes[0].Path != "/admin/users" || report.Routes[1].Path != "/admin/users/:id/disable" {
+		t.Fatalf("expected group paths to be joined, got %+v", report.Routes)
+	}
+
+	if strings.Join(report.Missing, ",") != "order.list,user.disable" {
+		t.Fatalf("unexpected missing codes: %v", report.Missing)
+	}
+
+	if strings.Join(report.Unused, ",") != "user.delete" {
+		t.Fatalf("unexpected unused codes: %v", report.Unused)
+	}
+
+	if len(report.Permissions) != 2 || report.Permissions[0].Code != "user" || report.Permissions[0].Name != "用户管理" {
+		t.Fatalf("expected configured directory first, got %+v", report.Permissions)
+	}
+
+	list := report.Permissions[0].Children[0]
+
+	if list.Code != "list" || list.Name != "用户列表" || len(list.Platforms) != 1 {
+		t.Fatalf("expected configured leaf to keep name and platforms, got %+v", list)
+	}
+
+	source, err := Source("config", "Permissions", report.Permissions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(source), `{Code: "disable", Name: "禁用用户"}`) {
+		t.Fatalf("unexpected source:\n%s", source)
+	}
+}
@@ -0,0 +1,258 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/network"
+	"github.com/cloudwego/hertz/pkg/protocol/http1/resp"
+	"github.com/herhe-com/framework/contracts/ai"
+	"github.com/herhe-com/framework/contracts/http/response"
+	"github.com/herhe-com/framework/facades"
+)
+
+var ErrStreamClosed = errors.New("sse stream is closed")
+
+// Stream SSE 连接，写入失败（客户端断开）或处理函数返回后关闭
+type Stream struct {
+	writer      network.ExtWriter
+	mutex       sync.Mutex
+	done        chan struct{}
+	closed      bool
+	lastEventID string
+}
+
+// streamWriter 处理函数返回后 Hertz 调用 Finalize 结束分块响应，此时关闭连接，避免心跳继续写入
+type streamWriter struct {
+	network.ExtWriter
+	stream *Stream
+}
+
+func (w *streamWriter) Finalize() error {
+
+	w.stream.Close()
+
+	return w.ExtWriter.Finalize()
+}
+
+// SSE
+//
+//	@Description: 开始 SSE 响应，按 server.sse.heartbeat（默认 15 秒）发送心跳，处理函数返回后自动关闭
+//	@param c	请求的上下文，开启 server.WithSenseClientDisconnection 后客户端断开时立即关闭
+func SSE(c context.Context, ctx *app.RequestContext) *Stream {
+
+	ctx.SetStatusCode(200)
+	ctx.Response.Header.SetContentType("text/event-stream; charset=utf-8")
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+	ctx.Response.Header.Set("Connection", "keep-alive")
+	ctx.Response.Header.Set("X-Accel-Buffering", "no")
+
+	// 浏览器重连时通过请求头携带，EventSource 的 polyfill 一般使用查询参数
+	id := string(ctx.GetHeader("Last-Event-ID"))
+
+	if id == "" {
+		id = ctx.Query("last_event_id")
+	}
+
+	stream := &Stream{writer: resp.NewChunkedBodyWriter(&ctx.Response, ctx.GetWriter()), done: make(chan struct{}), lastEventID: id}
+
+	ctx.Response.HijackWriter(&streamWriter{ExtWriter: stream.writer, stream: stream})
+
+	interval := time.Duration(facades.Config().GetInt("server.sse.heartbeat", 15)) * time.Second
+
+	go stream.heartbeat(c, interval)
+
+	return stream
+}
+
+// LastEventID 客户端重连时最后收到的事件 ID，用于断点续传
+func (s *Stream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done 连接关闭或客户端断开时关闭
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Retry 设置客户端断开后的重连间隔
+func (s *Stream) Retry(duration time.Duration) error {
+	return s.write(fmt.Appendf(nil, "retry: %d\n\n", duration.Milliseconds()))
+}
+
+// Send 写入一个事件，data 为字符串时原样发送，其他类型编码为 JSON
+func (s *Stream) Send(id any, event string, data any) error {
+
+	var buffer bytes.Buffer
+
+	if id != nil && fmt.Sprint(id) != "" {
+		buffer.WriteString("id: " + singleLine(fmt.Sprint(id)) + "\n")
+	}
+
+	if event != "" {
+		buffer.WriteString("event: " + singleLine(event) + "\n")
+	}
+
+	content, ok := data.(string)
+
+	if !ok {
+
+		value, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+
+		content = string(value)
+	}
+
+	// 多行数据每行都需要 data: 前缀
+	for _, line := range strings.Split(content, "\n") {
+		buffer.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+
+	buffer.WriteString("\n")
+
+	return s.write(buffer.Bytes())
+}
+
+// Close 停止心跳，之后的写入返回 ErrStreamClosed，等待正在进行的写入完成后返回
+func (s *Stream) Close() {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.close()
+}
+
+// close 调用时需要持有 mutex
+func (s *Stream) close() {
+
+	if s.closed {
+		return
+	}
+
+	s.closed = true
+
+	close(s.done)
+}
+
+func (s *Stream) write(data []byte) error {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrStreamClosed
+	}
+
+	_, err := s.writer.Write(data)
+
+	if err == nil {
+		err = s.writer.Flush()
+	}
+
+	if err != nil {
+		s.close()
+	}
+
+	return err
+}
+
+func (s *Stream) heartbeat(c context.Context, interval time.Duration) {
+
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-c.Done():
+			s.Close()
+			return
+		case <-ticker.C:
+			// 注释行，浏览器会忽略，用于保持连接和检测断开
+			if s.write([]byte(": ping\n\n")) != nil {
+				return
+			}
+		}
+	}
+}
+
+// Emit 以 response.Event 的格式写入事件，Timestamp 为空时使用当前时间
+func Emit[T any](stream *Stream, event response.Event[T]) error {
+
+	if event.Timestamp == "" {
+		event.Timestamp = time.Now().Format(time.RFC3339)
+	}
+
+	return stream.Send(event.ID, event.Event, event)
+}
+
+// Pipe
+//
+//	@Description: 把 AI 的流式响应转发到浏览器：每个分片发送 message 事件，结束时发送 done 事件，出错时发送 error 事件
+//	@param chunks	ai.Driver 的 Stream 返回的 channel，客户端断开后会在后台读完，避免生产者阻塞
+func Pipe(c context.Context, ctx *app.RequestContext, chunks chan *ai.StreamResponse) error {
+
+	stream := SSE(c, ctx)
+	defer stream.Close()
+
+	defer func() {
+		go func() {
+			for range chunks {
+			}
+		}()
+	}()
+
+	sequence := 0
+
+	for {
+		select {
+		case <-stream.Done():
+			return ErrStreamClosed
+		case chunk, ok := <-chunks:
+
+			if !ok {
+				return Emit(stream, response.Event[string]{Event: "done"})
+			}
+
+			sequence++
+
+			if chunk.Error != nil {
+				_ = Emit(stream, response.Event[string]{ID: sequence, Event: "error", Data: chunk.Error.Error()})
+				return chunk.Error
+			}
+
+			for _, choice := range chunk.Choices {
+
+				if choice.Delta.Content == "" {
+					continue
+				}
+
+				if err := Emit(stream, response.Event[string]{ID: sequence, Event: "message", Data: choice.Delta.Content}); err != nil {
+					return err
+				}
+			}
+
+			if chunk.Done {
+				return Emit(stream, response.Event[string]{ID: sequence, Event: "done"})
+			}
+		}
+	}
+}
+
+func singleLine(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
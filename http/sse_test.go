@@ -0,0 +1,123 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/test/mock"
+	"github.com/cloudwego/hertz/pkg/network"
+	"github.com/herhe-com/framework/contracts/ai"
+	"github.com/herhe-com/framework/contracts/http/response"
+)
+
+func sseContext(conn network.Conn) *app.RequestContext {
+	ctx := app.NewContext(0)
+	ctx.SetConn(conn)
+	ctx.Request.SetRequestURI("/events")
+	ctx.Request.Header.Set("Last-Event-ID", "41")
+	return ctx
+}
+
+func TestSSEWritesEvents(t *testing.T) {
+	setup(t)
+
+	conn := mock.NewConn("")
+	ctx := sseContext(conn)
+
+	stream := SSE(context.Background(), ctx)
+
+	if stream.LastEventID() != "41" {
+		t.Fatalf("expected last event id, got %q", stream.LastEventID())
+	}
+
+	if err := Emit(stream, response.Event[map[string]int]{ID: 42, Event: "order", Data: map[string]int{"id": 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stream.Send(nil, "", "line 1\nline 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	stream.Close()
+
+	if err := stream.Send(nil, "", "late"); !errors.Is(err, ErrStreamClosed) {
+		t.Fatalf("expected closed stream, got %v", err)
+	}
+
+	output, _ := conn.WriterRecorder().Peek(conn.WriterRecorder().WroteLen())
+	text := string(output)
+
+	for _, expected := range []string{"Content-Type: text/event-stream", "id: 42\r\n", "event: order\r\n", `data: {"id":42,"event":"order","data":{"id":1}`, "data: line 1\ndata: line 2\n\n"} {
+		if !strings.Contains(strings.ReplaceAll(text, "\r\n", "\n"), strings.ReplaceAll(expected, "\r\n", "\n")) {
+			t.Fatalf("expected %q in output:\n%s", expected, text)
+		}
+	}
+}
+
+func TestSSEClosesWhenHandlerReturns(t *testing.T) {
+	setup(t)
+
+	ctx := sseContext(mock.NewConn(""))
+
+	stream := SSE(context.Background(), ctx)
+
+	// 处理函数返回后 Hertz 调用 Finalize
+	if err := ctx.Response.GetHijackWriter().Finalize(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-stream.Done():
+	default:
+		t.Fatal("expected stream to be closed after the handler returns")
+	}
+
+	if err := stream.Send(nil, "", "late"); !errors.Is(err, ErrStreamClosed) {
+		t.Fatalf("expected closed stream, got %v", err)
+	}
+}
+
+func TestSSEDetectsDisconnect(t *testing.T) {
+	setup(t)
+
+	ctx := sseContext(mock.NewBrokenConn(""))
+
+	chunks := make(chan *ai.StreamResponse)
+
+	go func() {
+		defer close(chunks)
+		for range 3 {
+			chunks <- &ai.StreamResponse{Choices: []ai.StreamChoice{{Delta: ai.MessageDelta{Content: "hi"}}}}
+		}
+	}()
+
+	if err := Pipe(context.Background(), ctx, chunks); err == nil {
+		t.Fatal("expected write to a closed connection to fail")
+	}
+}
+
+func TestPipeForwardsChunks(t *testing.T) {
+	setup(t)
+
+	conn := mock.NewConn("")
+	ctx := sseContext(conn)
+
+	chunks := make(chan *ai.StreamResponse, 3)
+	chunks <- &ai.StreamResponse{Choices: []ai.StreamChoice{{Delta: ai.MessageDelta{Content: "Hel"}}}}
+	chunks <- &ai.StreamResponse{Choices: []ai.StreamChoice{{Delta: ai.MessageDelta{Content: "lo"}}}}
+	chunks <- &ai.StreamResponse{Done: true}
+
+	if err := Pipe(context.Background(), ctx, chunks); err != nil {
+		t.Fatal(err)
+	}
+
+	output, _ := conn.WriterRecorder().Peek(conn.WriterRecorder().WroteLen())
+	text := string(output)
+
+	if !strings.Contains(text, `"data":"Hel"`) || !strings.Contains(text, `"data":"lo"`) || !strings.Contains(text, "event: done") {
+		t.Fatalf("unexpected output:\n%s", text)
+	}
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/herhe-com/framework/auth"
+)
+
+// KeyFunc 从请求中生成限流对象
+type KeyFunc func(c context.Context, ctx *app.RequestContext) string
+
+// ByIP 按客户端 IP 限流
+func ByIP(c context.Context, ctx *app.RequestContext) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// ByRoute 按路由限流，与其他 KeyFunc 组合使用时每个路由分别计算
+func ByRoute(c context.Context, ctx *app.RequestContext) string {
+
+	path := ctx.FullPath()
+
+	if path == "" {
+		path = string(ctx.URI().Path())
+	}
+
+	return "route:" + string(ctx.Request.Header.Method()) + ":" + path
+}
+
+// ByUser 按登录用户限流，未登录时按客户端 IP
+func ByUser(c context.Context, ctx *app.RequestContext) string {
+
+	if id := auth.ID(ctx); id != "" {
+		return "user:" + id
+	}
+
+	return ByIP(c, ctx)
+}
+
+// ByAPIKey 按请求头中的 API Key 限流，只保存摘要，没有 API Key 时按客户端 IP
+func ByAPIKey(header string) KeyFunc {
+
+	return func(c context.Context, ctx *app.RequestContext) string {
+
+		value := ctx.GetHeader(header)
+
+		if len(value) == 0 {
+			return ByIP(c, ctx)
+		}
+
+		sum := sha256.Sum256(value)
+
+		return "key:" + hex.EncodeToString(sum[:16])
+	}
+}
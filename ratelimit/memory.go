@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// store 没有 Redis 时使用的本机内存限流，多节点部署时各节点分别计算
+type store struct {
+	mutex   sync.Mutex
+	entries map[string]*entry
+	sweep   time.Time
+}
+
+type entry struct {
+	count   int64       // 固定窗口的计数
+	times   []time.Time // 滑动窗口的请求时间
+	tokens  float64     // 令牌桶剩余令牌
+	at      time.Time   // 固定窗口的开始时间、令牌桶的补充时间
+	expires time.Time
+}
+
+var memory = &store{entries: make(map[string]*entry)}
+
+func (s *store) allow(key string, limit Limit, n int64, now time.Time) (*Result, error) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.clean(now)
+
+	item, ok := s.entries[key]
+
+	// 固定窗口、令牌桶过期后重新开始，滑动窗口按时间逐条移除
+	if !ok || (!now.Before(item.expires) && limit.Algorithm != SlidingWindow) {
+		item = &entry{at: now, tokens: float64(limit.Rate)}
+		s.entries[key] = item
+	}
+
+	result := &Result{Limit: limit.Rate}
+
+	switch limit.Algorithm {
+	case FixedWindow:
+
+		reset := item.at.Add(limit.Period).Sub(now)
+
+		result.ResetAfter = reset
+
+		if item.count+n > limit.Rate {
+			result.RetryAfter = reset
+		} else {
+			item.count += n
+			result.Allowed = true
+		}
+
+		result.Remaining = limit.Rate - item.count
+		item.expires = item.at.Add(limit.Period)
+
+	case SlidingWindow:
+
+		start := now.Add(-limit.Period)
+
+		for len(item.times) > 0 && !item.times[0].After(start) {
+			item.times = item.times[1:]
+		}
+
+		count := int64(len(item.times))
+
+		if count+n > limit.Rate {
+
+			result.RetryAfter = limit.Period
+
+			if n <= limit.Rate {
+				result.RetryAfter = item.times[count+n-limit.Rate-1].Add(limit.Period).Sub(now)
+			}
+		} else {
+
+			for i := int64(0); i < n; i++ {
+				item.times = append(item.times, now)
+			}
+
+			count += n
+			result.Allowed = true
+		}
+
+		result.Remaining = limit.Rate - count
+
+		if len(item.times) > 0 {
+			result.ResetAfter = item.times[0].Add(limit.Period).Sub(now)
+		}
+
+		item.expires = now.Add(limit.Period)
+
+	case TokenBucket:
+
+		rate := float64(limit.Rate) / float64(limit.Period)
+
+		item.tokens = math.Min(float64(limit.Rate), item.tokens+float64(now.Sub(item.at))*rate)
+		item.at = now
+
+		if item.tokens >= float64(n) {
+			item.tokens -= float64(n)
+			result.Allowed = true
+		} else if n > limit.Rate {
+			result.RetryAfter = limit.Period
+		} else {
+			result.RetryAfter = time.Duration(math.Ceil((float64(n) - item.tokens) / rate))
+		}
+
+		result.Remaining = int64(item.tokens)
+		result.ResetAfter = time.Duration(math.Ceil((float64(limit.Rate) - item.tokens) / rate))
+		item.expires = now.Add(limit.Period)
+
+	default:
+		return nil, fmt.Errorf("rate limit algorithm %s is not supported", limit.Algorithm)
+	}
+
+	return result, nil
+}
+
+func (s *store) reset(key string) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.entries, key)
+}
+
+// clean 每分钟清理一次过期的记录
+func (s *store) clean(now time.Time) {
+
+	if now.Before(s.sweep) {
+		return
+	}
+
+	for key, item := range s.entries {
+		if !now.Before(item.expires) {
+			delete(s.entries, key)
+		}
+	}
+
+	s.sweep = now.Add(time.Minute)
+}
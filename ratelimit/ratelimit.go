@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support/util"
+)
+
+type Algorithm string
+
+const (
+	FixedWindow   Algorithm = "fixed_window"   // 固定窗口，实现简单，窗口边界可能出现两倍突发
+	SlidingWindow Algorithm = "sliding_window" // 滑动窗口日志，精确但每次请求都会记录
+	TokenBucket   Algorithm = "token_bucket"   // 令牌桶，按速率补充，允许不超过容量的突发
+)
+
+// Limit 限流规则，令牌桶的容量为 Rate，每个 Period 补满
+type Limit struct {
+	Algorithm Algorithm
+	Rate      int64
+	Period    time.Duration
+}
+
+// PerSecond 每秒 rate 次的固定窗口规则
+func PerSecond(rate int64) Limit {
+	return Limit{Algorithm: FixedWindow, Rate: rate, Period: time.Second}
+}
+
+// PerMinute 每分钟 rate 次的固定窗口规则
+func PerMinute(rate int64) Limit {
+	return Limit{Algorithm: FixedWindow, Rate: rate, Period: time.Minute}
+}
+
+// PerHour 每小时 rate 次的固定窗口规则
+func PerHour(rate int64) Limit {
+	return Limit{Algorithm: FixedWindow, Rate: rate, Period: time.Hour}
+}
+
+// Result 限流结果
+type Result struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	RetryAfter time.Duration // 被拒绝时，多久之后可以重试
+	ResetAfter time.Duration // 多久之后恢复到满额
+}
+
+var ErrInvalidLimit = errors.New("rate limit requires a positive rate and period")
+
+// Allow 消耗一次额度
+func Allow(c context.Context, key string, limit Limit) (*Result, error) {
+	return AllowN(c, key, limit, 1)
+}
+
+// AllowN
+//
+//	@Description: 消耗 n 次额度，如按 token 数量限制 AI 调用；优先使用 Redis，没有 Redis 或 Redis 出错时使用本机内存
+//	@param key	限流对象，如用户 ID、API Key、队列名称
+//	@param limit	限流规则
+//	@param n	本次消耗的额度
+func AllowN(c context.Context, key string, limit Limit, n int64) (*Result, error) {
+
+	if limit.Rate <= 0 || limit.Period <= 0 || n <= 0 {
+		return nil, ErrInvalidLimit
+	}
+
+	if limit.Algorithm == "" {
+		limit.Algorithm = FixedWindow
+	}
+
+	name := util.Keys("ratelimit", limit.Algorithm, key)
+
+	if cache, ok := facades.OptionalRedis(); ok {
+
+		result, err := allowWithRedis(c, cache.Default(), name, limit, n)
+
+		if err == nil {
+			return result, nil
+		}
+
+		hlog.CtxWarnf(c, "rate limit falls back to memory: %v", err)
+	}
+
+	return memory.allow(name, limit, n, now())
+}
+
+// Wait 阻塞直到获得额度或 context 结束，用于队列消费者、后台任务
+func Wait(c context.Context, key string, limit Limit) error {
+	return WaitN(c, key, limit, 1)
+}
+
+// WaitN 阻塞直到获得 n 次额度或 context 结束
+func WaitN(c context.Context, key string, limit Limit, n int64) error {
+
+	if n > limit.Rate {
+		return ErrInvalidLimit
+	}
+
+	for {
+
+		result, err := AllowN(c, key, limit, n)
+		if err != nil {
+			return err
+		}
+
+		if result.Allowed {
+			return nil
+		}
+
+		retry := result.RetryAfter
+
+		if retry <= 0 {
+			retry = 10 * time.Millisecond
+		}
+
+		timer := time.NewTimer(retry)
+
+		select {
+		case <-c.Done():
+			timer.Stop()
+			return c.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Reset 清空限流对象的记录，如管理员解除限制
+func Reset(c context.Context, key string, limit Limit) error {
+
+	if limit.Algorithm == "" {
+		limit.Algorithm = FixedWindow
+	}
+
+	name := util.Keys("ratelimit", limit.Algorithm, key)
+
+	memory.reset(name)
+
+	if cache, ok := facades.OptionalRedis(); ok {
+		return cache.Default().Del(c, name).Err()
+	}
+
+	return nil
+}
+
+// now 当前时间，测试时替换
+var now = time.Now
@@ -0,0 +1,214 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	contractconfig "github.com/herhe-com/framework/contracts/config"
+	"github.com/herhe-com/framework/facades"
+)
+
+type fakeConfig struct {
+	values map[string]any
+}
+
+func (f fakeConfig) Env(key string, defaultValue ...any) any {
+	return f.Get(key, defaultValue...)
+}
+
+func (f fakeConfig) Add(name string, configuration map[string]any) {}
+
+func (f fakeConfig) Set(key string, configuration any) {
+	f.values[key] = configuration
+}
+
+func (f fakeConfig) Get(key string, defaultValue ...any) any {
+	if value, ok := f.values[key]; ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetString(key string, defaultValue ...string) string {
+	if value, ok := f.values[key]; ok {
+		return fmt.Sprint(value)
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return ""
+}
+
+func (f fakeConfig) GetStrings(key string, defaultValue ...[]string) []string {
+	return nil
+}
+
+func (f fakeConfig) GetMaps(key string, defaultValue ...map[string]any) map[string]any {
+	return nil
+}
+
+func (f fakeConfig) GetInt(key string, defaultValue ...int) int {
+	return 0
+}
+
+func (f fakeConfig) GetInt64(key string, defaultValue ...int64) int64 {
+	return 0
+}
+
+func (f fakeConfig) GetBool(key string, defaultValue ...bool) bool {
+	return false
+}
+
+func (f fakeConfig) IsSet(key string) bool {
+	_, ok := f.values[key]
+	return ok
+}
+
+func setup(t *testing.T) *time.Time {
+	original := facades.Container()
+	facades.SetContainer(&facades.Services{})
+	t.Cleanup(func() {
+		facades.SetContainer(original)
+	})
+
+	facades.Register[contractconfig.Application](fakeConfig{values: map[string]any{"app.name": "framework"}})
+
+	current := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	now = func() time.Time {
+		return current
+	}
+
+	memory = &store{entries: make(map[string]*entry)}
+
+	t.Cleanup(func() {
+		now = time.Now
+	})
+
+	return &current
+}
+
+func TestFixedWindowResetsAfterPeriod(t *testing.T) {
+	current := setup(t)
+
+	limit := PerMinute(2)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if result, _ := Allow(ctx, "u1", limit); !result.Allowed {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+	}
+
+	*current = current.Add(20 * time.Second)
+
+	result, err := Allow(ctx, "u1", limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter != 40*time.Second {
+		t.Fatalf("expected denial until window ends, got %+v", result)
+	}
+
+	*current = current.Add(40 * time.Second)
+
+	if result, _ = Allow(ctx, "u1", limit); !result.Allowed || result.Remaining != 1 {
+		t.Fatalf("expected new window, got %+v", result)
+	}
+}
+
+func TestSlidingWindowRetriesWhenOldestExpires(t *testing.T) {
+	current := setup(t)
+
+	limit := Limit{Algorithm: SlidingWindow, Rate: 2, Period: time.Minute}
+	ctx := context.Background()
+
+	_, _ = Allow(ctx, "u1", limit)
+	*current = current.Add(30 * time.Second)
+	_, _ = Allow(ctx, "u1", limit)
+	*current = current.Add(10 * time.Second)
+
+	result, _ := Allow(ctx, "u1", limit)
+
+	if result.Allowed || result.RetryAfter != 20*time.Second {
+		t.Fatalf("expected retry when first request leaves window, got %+v", result)
+	}
+
+	*current = current.Add(20 * time.Second)
+
+	if result, _ = Allow(ctx, "u1", limit); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected request allowed after oldest expired, got %+v", result)
+	}
+}
+
+func TestTokenBucketRefillsAtRate(t *testing.T) {
+	current := setup(t)
+
+	limit := Limit{Algorithm: TokenBucket, Rate: 10, Period: 10 * time.Second}
+	ctx := context.Background()
+
+	if result, _ := AllowN(ctx, "ai", limit, 10); !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected burst up to capacity, got %+v", result)
+	}
+
+	result, _ := AllowN(ctx, "ai", limit, 3)
+
+	if result.Allowed || result.RetryAfter != 3*time.Second {
+		t.Fatalf("expected 3s until three tokens refill, got %+v", result)
+	}
+
+	*current = current.Add(3 * time.Second)
+
+	if result, _ = AllowN(ctx, "ai", limit, 3); !result.Allowed {
+		t.Fatalf("expected tokens refilled, got %+v", result)
+	}
+
+	if result, _ = AllowN(ctx, "ai", limit, 11); result.Allowed {
+		t.Fatal("expected request larger than capacity to be denied")
+	}
+}
+
+func TestResetAndWait(t *testing.T) {
+	current := setup(t)
+
+	limit := PerSecond(1)
+	ctx := context.Background()
+
+	_, _ = Allow(ctx, "queue", limit)
+
+	if err := Reset(ctx, "queue", limit); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, _ := Allow(ctx, "queue", limit); !result.Allowed {
+		t.Fatal("expected reset to clear the window")
+	}
+
+	// 时间不前进时一直拿不到额度，直到 context 超时
+	timeout, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+
+	if err := Wait(timeout, "queue", limit); err == nil {
+		t.Fatal("expected wait to stop with context")
+	}
+
+	*current = current.Add(time.Second)
+
+	if err := Wait(ctx, "queue", limit); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Allow(ctx, "queue", Limit{Rate: 0, Period: time.Second}); err != ErrInvalidLimit {
+		t.Fatalf("expected invalid limit error, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 脚本统一返回 {是否允许, 剩余额度, 重试等待毫秒, 恢复满额毫秒}
+
+var fixedWindowScript = redis.NewScript(`
+	local limit = tonumber(ARGV[1])
+	local period = tonumber(ARGV[2])
+	local n = tonumber(ARGV[3])
+
+	local current = redis.call("INCRBY", KEYS[1], n)
+	if current == n then
+		redis.call("PEXPIRE", KEYS[1], period)
+	end
+
+	local ttl = redis.call("PTTL", KEYS[1])
+	if ttl < 0 then
+		redis.call("PEXPIRE", KEYS[1], period)
+		ttl = period
+	end
+
+	if current > limit then
+		-- 被拒绝的请求不占用额度
+		current = redis.call("DECRBY", KEYS[1], n)
+		return {0, math.max(limit - current, 0), ttl, ttl}
+	end
+
+	return {1, limit - current, 0, ttl}
+`)
+
+var slidingWindowScript = redis.NewScript(`
+	local limit = tonumber(ARGV[1])
+	local period = tonumber(ARGV[2])
+	local n = tonumber(ARGV[3])
+
+	local time = redis.call("TIME")
+	local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+	redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - period)
+
+	local count = redis.call("ZCARD", KEYS[1])
+
+	if count + n > limit then
+		local retry = period
+		local index = count + n - limit - 1
+		if n <= limit and index < count then
+			-- 等到足够多的旧记录移出窗口
+			local entry = redis.call("ZRANGE", KEYS[1], index, index, "WITHSCORES")
+			retry = tonumber(entry[2]) + period - now
+		end
+		local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+		local reset = period
+		if oldest[2] then
+			reset = tonumber(oldest[2]) + period - now
+		end
+		return {0, limit - count, retry, reset}
+	end
+
+	for i = 1, n do
+		redis.call("ZADD", KEYS[1], now, ARGV[4] .. ":" .. i)
+	end
+	redis.call("PEXPIRE", KEYS[1], period)
+
+	local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+
+	return {1, limit - count - n, 0, tonumber(oldest[2]) + period - now}
+`)
+
+var tokenBucketScript = redis.NewScript(`
+	local capacity = tonumber(ARGV[1])
+	local period = tonumber(ARGV[2])
+	local n = tonumber(ARGV[3])
+	local rate = capacity / period
+
+	local time = redis.call("TIME")
+	local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+	local bucket = redis.call("HMGET", KEYS[1], "tokens", "at")
+	local tokens = tonumber(bucket[1]) or capacity
+	local at = tonumber(bucket[2]) or now
+
+	tokens = math.min(capacity, tokens + math.max(now - at, 0) * rate)
+
+	local allowed = 0
+	local retry = 0
+
+	if tokens >= n then
+		tokens = tokens - n
+		allowed = 1
+	elseif n > capacity then
+		retry = period
+	else
+		retry = math.ceil((n - tokens) / rate)
+	end
+
+	redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "at", now)
+	redis.call("PEXPIRE", KEYS[1], period)
+
+	return {allowed, math.floor(tokens), retry, math.ceil((capacity - tokens) / rate)}
+`)
+
+func allowWithRedis(c context.Context, client *redis.Client, key string, limit Limit, n int64) (*Result, error) {
+
+	var script *redis.Script
+
+	args := []any{limit.Rate, limit.Period.Milliseconds(), n}
+
+	switch limit.Algorithm {
+	case FixedWindow:
+		script = fixedWindowScript
+	case SlidingWindow:
+
+		// 同一毫秒的多次请求需要不同的成员
+		nonce := make([]byte, 8)
+
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+
+		script = slidingWindowScript
+		args = append(args, hex.EncodeToString(nonce))
+	case TokenBucket:
+		script = tokenBucketScript
+	default:
+		return nil, fmt.Errorf("rate limit algorithm %s is not supported", limit.Algorithm)
+	}
+
+	values, err := script.Run(c, client, []string{key}, args...).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit result: %v", values)
+	}
+
+	return &Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit.Rate,
+		Remaining:  max(values[1], 0),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
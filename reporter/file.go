@@ -0,0 +1,19 @@
+package reporter
+
+import (
+	"context"
+
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support/file"
+)
+
+// FileReporter 以 JSON Lines 格式追加到 reporter.file，相对路径基于项目根目录
+type FileReporter struct {
+	lines file.Lines
+}
+
+var files = &FileReporter{}
+
+func (r *FileReporter) Report(c context.Context, event *Event) error {
+	return r.lines.Append(facades.Config().GetString("reporter.file", "storage/logs/error.log"), event)
+}
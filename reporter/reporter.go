@@ -0,0 +1,122 @@
+package reporter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"runtime"
+	"time"
+
+	"github.com/herhe-com/framework/support/registry"
+)
+
+const (
+	ReporterOfFile   = "file"
+	ReporterOfSentry = "sentry"
+)
+
+// Event 上报的错误事件
+type Event struct {
+	ID        string            `json:"id"`
+	Level     string            `json:"level"` // error、fatal
+	Type      string            `json:"type"`  // 错误或 panic 值的类型
+	Message   string            `json:"message"`
+	Frames    []Frame           `json:"frames,omitempty"` // 调用栈，最新的调用在前
+	Stack     string            `json:"stack,omitempty"`  // runtime/debug.Stack 的原始内容
+	Method    string            `json:"method,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	User      string            `json:"user,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Frame 调用栈中的一帧
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Reporter 错误事件的上报方式
+type Reporter interface {
+	Report(c context.Context, event *Event) error
+}
+
+var reporters = registry.New[Reporter]("reporter.reporters", "error reporter", map[string]Reporter{
+	ReporterOfFile:   files,
+	ReporterOfSentry: SentryReporter{},
+})
+
+// Extend 注册自定义上报方式，名称可以在 reporter.reporters 中使用，同名时覆盖内置上报方式
+func Extend(name string, reporter Reporter) {
+	reporters.Extend(name, reporter)
+}
+
+// ReporterOf 按名称获取上报方式
+func ReporterOf(name string) (Reporter, error) {
+	return reporters.Of(name)
+}
+
+// Enabled 是否配置了上报方式
+func Enabled() bool {
+	return reporters.Enabled()
+}
+
+// Capture
+//
+//	@Description: 把错误事件发送到 reporter.reporters 中的全部上报方式
+//	@param event	错误事件，ID、Level、CreatedAt 为空时自动填充
+func Capture(c context.Context, event *Event) error {
+
+	if !reporters.Enabled() {
+		return nil
+	}
+
+	if event.ID == "" {
+
+		id := make([]byte, 16)
+
+		if _, err := rand.Read(id); err != nil {
+			return err
+		}
+
+		event.ID = hex.EncodeToString(id)
+	}
+
+	if event.Level == "" {
+		event.Level = "error"
+	}
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	return reporters.Each(func(reporter Reporter) error {
+		return reporter.Report(c, event)
+	})
+}
+
+// Frames 当前调用栈，skip 为跳过的层数，0 为调用 Frames 的函数
+func Frames(skip int) []Frame {
+
+	pcs := make([]uintptr, 64)
+	count := runtime.Callers(skip+2, pcs)
+
+	frames := runtime.CallersFrames(pcs[:count])
+	items := make([]Frame, 0, count)
+
+	for {
+
+		frame, more := frames.Next()
+
+		items = append(items, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+
+		if !more {
+			break
+		}
+	}
+
+	return items
+}
@@ -0,0 +1,157 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/herhe-com/framework/facades"
+)
+
+// SentryReporter 通过 Sentry 的 store 接口上报，兼容 GlitchTip 等实现了该接口的服务，读取 reporter.sentry.*
+type SentryReporter struct {
+}
+
+func (SentryReporter) Report(c context.Context, event *Event) error {
+
+	endpoint, auth, err := sentryEndpoint(facades.Config().GetString("reporter.sentry.dsn"))
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(sentryEvent(event))
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(facades.Config().GetInt("reporter.sentry.timeout", 5)) * time.Second
+
+	c, cancel := context.WithTimeout(c, timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(c, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Sentry-Auth", auth)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusMultipleChoices {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("sentry responded with %d: %s", response.StatusCode, message)
+	}
+
+	return nil
+}
+
+// sentryEndpoint 解析 DSN：{scheme}://{key}[:{secret}]@{host}[/{path}]/{project}
+func sentryEndpoint(dsn string) (endpoint string, auth string, err error) {
+
+	if dsn == "" {
+		return "", "", errors.New("reporter.sentry.dsn is required")
+	}
+
+	value, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", err
+	}
+
+	key := value.User.Username()
+	secret, _ := value.User.Password()
+
+	index := strings.LastIndex(value.Path, "/")
+
+	if key == "" || index < 0 || value.Path[index+1:] == "" {
+		return "", "", errors.New("invalid sentry dsn")
+	}
+
+	endpoint = fmt.Sprintf("%s://%s%s/api/%s/store/", value.Scheme, value.Host, value.Path[:index], value.Path[index+1:])
+
+	auth = "Sentry sentry_version=7, sentry_client=herhe-framework/1.0, sentry_key=" + key
+
+	if secret != "" {
+		auth += ", sentry_secret=" + secret
+	}
+
+	return endpoint, auth, nil
+}
+
+// sentryEvent 转换为 Sentry 的事件格式，调用栈按从旧到新排列
+func sentryEvent(event *Event) map[string]any {
+
+	frames := make([]map[string]any, 0, len(event.Frames))
+
+	for index := len(event.Frames) - 1; index >= 0; index-- {
+
+		frame := event.Frames[index]
+
+		frames = append(frames, map[string]any{
+			"function": frame.Function,
+			"abs_path": frame.File,
+			"filename": frame.File,
+			"lineno":   frame.Line,
+			"in_app":   !strings.HasPrefix(frame.Function, "runtime.") && !strings.Contains(frame.Function, "cloudwego/hertz"),
+		})
+	}
+
+	environment := "production"
+
+	if facades.Config().GetBool("app.debug") {
+		environment = "development"
+	}
+
+	host, _ := os.Hostname()
+
+	data := map[string]any{
+		"event_id":    event.ID,
+		"timestamp":   event.CreatedAt.UTC().Format(time.RFC3339),
+		"level":       event.Level,
+		"platform":    "go",
+		"logger":      facades.Config().GetString("app.name"),
+		"server_name": host,
+		"environment": facades.Config().GetString("reporter.sentry.environment", environment),
+		"release":     facades.Config().GetString("reporter.sentry.release", facades.Config().GetString("app.version")),
+		"message":     event.Message,
+		"tags":        event.Tags,
+		"exception": map[string]any{
+			"values": []map[string]any{{
+				"type":       event.Type,
+				"value":      event.Message,
+				"stacktrace": map[string]any{"frames": frames},
+			}},
+		},
+	}
+
+	if event.URL != "" {
+		data["request"] = map[string]any{
+			"url":     event.URL,
+			"method":  event.Method,
+			"headers": event.Headers,
+		}
+	}
+
+	if event.User != "" || event.IP != "" {
+		data["user"] = map[string]any{
+			"id":         event.User,
+			"ip_address": event.IP,
+		}
+	}
+
+	return data
+}
@@ -9,22 +9,33 @@ import (
 	"github.com/h2non/filetype"
 )
 
+// HeaderSize 识别文件类型需要读取的文件头长度，Office 文档需要 8K
+const HeaderSize = 8192
+
+var ErrUnknownExtension = errors.New("unknown file extension")
+
 // Extension Supported types: https://github.com/h2non/filetype#supported-types
 func Extension(file string, originalWhenUnknown ...bool) (string, error) {
 	buf, _ := os.ReadFile(file)
-	kind, err := filetype.Match(buf)
+
+	extension, err := ExtensionOf(buf)
+
+	if errors.Is(err, ErrUnknownExtension) && len(originalWhenUnknown) > 0 && originalWhenUnknown[0] {
+		return ClientOriginalExtension(file), nil
+	}
+
+	return extension, err
+}
+
+// ExtensionOf 按文件头识别类型，用于上传等只能读取一部分内容的场景，head 至少为 HeaderSize 或完整的文件
+func ExtensionOf(head []byte) (string, error) {
+	kind, err := filetype.Match(head)
 	if err != nil {
 		return "", err
 	}
 
 	if kind == filetype.Unknown {
-		if len(originalWhenUnknown) > 0 {
-			if originalWhenUnknown[0] {
-				return ClientOriginalExtension(file), nil
-			}
-		}
-
-		return "", errors.New("unknown file extension")
+		return "", ErrUnknownExtension
 	}
 
 	return kind.Extension, nil
@@ -0,0 +1,52 @@
+package file
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/herhe-com/framework/facades"
+)
+
+// Lines 以 JSON Lines 格式追加写入文件，用于审计日志、错误上报等文件输出，同一个 Lines 的并发写入按行串行
+type Lines struct {
+	mutex sync.Mutex
+}
+
+// Append
+//
+//	@Description: 把 value 序列化为一行 JSON 追加到文件末尾，目录不存在时自动创建
+//	@param path	文件路径，相对路径基于项目根目录
+//	@param value	写入的内容
+func (l *Lines) Append(path string, value any) error {
+
+	if !filepath.IsAbs(path) {
+		if root, ok := facades.Get[facades.RootPath](); ok {
+			path = filepath.Join(string(root), path)
+		}
+	}
+
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = file.Write(append(body, '\n'))
+
+	return err
+}
@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/herhe-com/framework/facades"
+)
+
+// Registry 按名称注册的输出方式，如审计日志的存储方式、错误的上报方式，配置项中列出的名称按顺序启用
+type Registry[T any] struct {
+	key      string
+	kind     string
+	builtins map[string]T
+	items    sync.Map
+}
+
+// New
+//
+//	@Description: 创建注册表
+//	@param key	启用的名称所在的配置项，如 audit.sinks
+//	@param kind	错误信息中的类型名称，如 audit sink
+//	@param builtins	内置的输出方式
+func New[T any](key, kind string, builtins map[string]T) *Registry[T] {
+	return &Registry[T]{key: key, kind: kind, builtins: builtins}
+}
+
+// Extend 注册自定义输出方式，同名时覆盖内置输出方式
+func (r *Registry[T]) Extend(name string, item T) {
+	r.items.Store(name, item)
+}
+
+// Forget 移除自定义输出方式，同名的内置输出方式重新生效
+func (r *Registry[T]) Forget(name string) {
+	r.items.Delete(name)
+}
+
+// Of 按名称获取输出方式
+func (r *Registry[T]) Of(name string) (item T, err error) {
+
+	if value, ok := r.items.Load(name); ok {
+		return value.(T), nil
+	}
+
+	if item, ok := r.builtins[name]; ok {
+		return item, nil
+	}
+
+	return item, fmt.Errorf("%s %s is not supported", r.kind, name)
+}
+
+// Names 配置中启用的名称
+func (r *Registry[T]) Names() []string {
+	return facades.Config().GetStrings(r.key)
+}
+
+// Enabled 是否配置了输出方式
+func (r *Registry[T]) Enabled() bool {
+	return len(r.Names()) > 0
+}
+
+// Each 依次调用全部启用的输出方式，某个失败时继续调用其余的，返回合并后的错误
+func (r *Registry[T]) Each(fn func(item T) error) error {
+
+	var errs []error
+
+	for _, name := range r.Names() {
+
+		item, err := r.Of(name)
+
+		if err == nil {
+			err = fn(item)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
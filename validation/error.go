@@ -1,6 +1,9 @@
 package validation
 
 import (
+	"reflect"
+	"strings"
+
 	"github.com/go-playground/validator/v10"
 )
 
@@ -30,3 +33,72 @@ func Error(err validator.ValidationErrors) (message string) {
 
 	return message
 }
+
+// Fields 按 JSON 字段名分组的验证错误，嵌套字段为 items[0].name 格式
+//
+//	@param obj	被验证的结构体或其指针，用于读取 json 标签
+func Fields(err validator.ValidationErrors, obj any) (messages map[string][]string) {
+
+	messages = make(map[string][]string)
+
+	root := reflect.TypeOf(obj)
+
+	for _, item := range err {
+
+		key := jsonPath(root, item.StructNamespace())
+
+		messages[key] = append(messages[key], item.Translate(trans))
+	}
+
+	return messages
+}
+
+// jsonPath 把 Request.Items[0].Name 转换为 items[0].name，匿名嵌入且没有 json 标签的结构体不占层级
+func jsonPath(t reflect.Type, namespace string) string {
+
+	segments := strings.Split(namespace, ".")
+
+	names := make([]string, 0, len(segments))
+
+	for _, segment := range segments[1:] {
+
+		name, index, indexed := strings.Cut(segment, "[")
+
+		if indexed {
+			index = "[" + index
+		}
+
+		for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map) {
+			t = t.Elem()
+		}
+
+		if t == nil || t.Kind() != reflect.Struct {
+			names = append(names, segment)
+			continue
+		}
+
+		field, ok := t.FieldByName(name)
+
+		if !ok {
+			t = nil
+			names = append(names, segment)
+			continue
+		}
+
+		t = field.Type
+
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if field.Anonymous && tag == "" {
+			continue
+		}
+
+		if tag == "" || tag == "-" {
+			tag = field.Name
+		}
+
+		names = append(names, tag+index)
+	}
+
+	return strings.Join(names, ".")
+}
@@ -9,6 +9,7 @@ import (
 
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	"github.com/herhe-com/framework/auth/password"
 	"github.com/herhe-com/framework/contracts/validation"
 	"github.com/herhe-com/framework/facades"
 )
@@ -59,6 +60,13 @@ func register(vd *validator.Validate, trans ut.Translator, language string) (err
 
 func rules() []validation.Rule {
 
+	// 配置了 auth.password 时使用密码策略，只在注册时读取一次
+	var policy *password.Policy
+
+	if facades.Config().IsSet("auth.password") {
+		policy = password.NewPolicy()
+	}
+
 	return []validation.Rule{
 		{
 			Tag:         "captcha",
@@ -102,8 +110,17 @@ func rules() []validation.Rule {
 			},
 		},
 		{
-			Tag:         "password",
-			Pattern:     PatternOfPassword,
+			Tag: "password",
+			Valid: func(fl validator.FieldLevel) bool {
+
+				// 未配置密码策略时沿用默认的正则规则
+				if policy != nil {
+					return policy.Valid(fl.Field().String())
+				}
+
+				ok, _ := regexp.MatchString(PatternOfPassword, fl.Field().String())
+				return ok
+			},
 			Translation: "{0} must be a valid password",
 			Translations: map[string]string{
 				"zh": "{0}必须是一个有效的密码",
@@ -0,0 +1,243 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	contractauth "github.com/herhe-com/framework/contracts/auth"
+	"github.com/herhe-com/framework/contracts/http/response"
+	"github.com/herhe-com/framework/ratelimit"
+	"github.com/herhe-com/framework/reporter"
+)
+
+// Client 一个已经认证的 WebSocket 连接
+type Client struct {
+	ID           string
+	User         string
+	Platform     uint16
+	Organization string
+	Claims       *contractauth.Claims
+
+	conn    *Conn
+	send    chan []byte
+	done    chan struct{}
+	stopped chan struct{} // 写入 goroutine 退出时关闭
+	once    sync.Once
+	status  int
+	reason  string
+}
+
+// Send 推送给当前连接，格式与 websocket.Send 相同
+func (c *Client) Send(event string, data any) error {
+
+	message, err := json.Marshal(response.Event[any]{Event: event, Data: data, Timestamp: time.Now().Format(time.RFC3339)})
+	if err != nil {
+		return err
+	}
+
+	if !c.enqueue(message) {
+		return ErrClosed
+	}
+
+	return nil
+}
+
+// Done 连接断开时关闭
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close 正常关闭连接
+func (c *Client) Close() {
+	c.close(CloseNormal, "")
+}
+
+// close 只记录关闭原因并通知写入 goroutine，关闭帧由写入 goroutine 发送，
+// 推送在 Redis 订阅的 goroutine 中执行，不能在这里等待阻塞的写入
+func (c *Client) close(status int, reason string) {
+	c.once.Do(func() {
+		c.status, c.reason = status, reason
+		close(c.done)
+	})
+}
+
+// enqueue 写入发送队列，队列满时说明客户端读取过慢，直接断开，避免拖慢其他连接
+func (c *Client) enqueue(message []byte) bool {
+
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- message:
+		return true
+	default:
+		c.close(ClosePolicyViolation, "slow consumer")
+		return false
+	}
+}
+
+// serve 在 Hijack 的连接上读写，返回后 Hertz 关闭连接
+func (c *Client) serve(ctx context.Context, conn net.Conn, config Config, options options) {
+
+	c.conn = newConn(conn, options.maxMessage)
+	c.conn.SetWriteTimeout(options.writeTimeout)
+	c.stopped = make(chan struct{})
+
+	clients.add(c)
+
+	if err := presence(ctx, c, options.ping*2); err != nil {
+		hlog.CtxWarnf(ctx, "websocket presence: %v", err)
+	}
+
+	defer func() {
+
+		c.close(CloseGoingAway, "")
+
+		// 等待写入 goroutine 发送关闭帧，写入有超时，不会一直阻塞
+		<-c.stopped
+
+		clients.remove(c)
+
+		if err := absence(ctx, c); err != nil {
+			hlog.CtxWarnf(ctx, "websocket presence: %v", err)
+		}
+
+		if config.OnClose != nil {
+			c.recover(ctx, func() {
+				config.OnClose(ctx, c)
+			})
+		}
+	}()
+
+	go c.write(ctx, options.ping)
+
+	if config.OnConnect != nil {
+		c.recover(ctx, func() {
+			config.OnConnect(ctx, c)
+		})
+	}
+
+	for {
+
+		// 客户端每个心跳周期都会回复 pong，两个周期没有数据时视为断开
+		_ = c.conn.SetReadTimeout(options.ping * 2)
+
+		opcode, message, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if options.limit.Rate > 0 {
+
+			result, err := ratelimit.Allow(ctx, "websocket:"+c.ID, options.limit)
+
+			if err != nil {
+				hlog.CtxErrorf(ctx, "websocket rate limit: %v", err)
+			} else if !result.Allowed {
+				_ = c.Send("error", response.Response[any]{Code: 42900, Message: "Too many requests"})
+				continue
+			}
+		}
+
+		if config.OnMessage != nil {
+			c.recover(ctx, func() {
+				config.OnMessage(ctx, c, opcode, message)
+			})
+		}
+	}
+}
+
+// write 发送队列中的消息和心跳，token 过期后断开；连接关闭时在这里发送关闭帧并关闭底层连接，读取随之结束
+func (c *Client) write(ctx context.Context, ping time.Duration) {
+
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(ping)
+	defer ticker.Stop()
+
+	var expired <-chan time.Time
+
+	if c.Claims != nil && c.Claims.ExpiresAt != nil {
+
+		timer := time.NewTimer(time.Until(c.Claims.ExpiresAt.Time))
+		defer timer.Stop()
+
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case <-c.done:
+			_ = c.conn.WriteClose(c.status, c.reason)
+			_ = c.conn.Close()
+			return
+		case message := <-c.send:
+
+			if err := c.conn.WriteMessage(TextMessage, message); err != nil {
+				c.close(CloseGoingAway, "")
+				_ = c.conn.Close()
+				return
+			}
+		case <-ticker.C:
+
+			if err := c.conn.WriteMessage(PingMessage, nil); err != nil {
+				c.close(CloseGoingAway, "")
+				_ = c.conn.Close()
+				return
+			}
+
+			if err := presence(ctx, c, ping*2); err != nil {
+				hlog.CtxWarnf(ctx, "websocket presence: %v", err)
+			}
+		case <-expired:
+			c.close(ClosePolicyViolation, "token expired")
+		}
+	}
+}
+
+// recover 回调中的 panic 不能影响其他连接，记录后断开当前连接
+func (c *Client) recover(ctx context.Context, callback func()) {
+
+	defer func() {
+
+		value := recover()
+
+		if value == nil {
+			return
+		}
+
+		stack := debug.Stack()
+
+		hlog.CtxErrorf(ctx, "[WebSocket] panic recovered: %v\nuser=%s connection=%s\n%s", value, c.User, c.ID, stack)
+
+		if reporter.Enabled() {
+			go func() {
+				event := &reporter.Event{
+					Level:   "fatal",
+					Type:    fmt.Sprintf("%T", value),
+					Message: fmt.Sprint(value),
+					Stack:   string(stack),
+					User:    c.User,
+					Tags:    map[string]string{"websocket": c.ID},
+				}
+
+				if err := reporter.Capture(ctx, event); err != nil {
+					hlog.CtxErrorf(ctx, "failed to report panic: %v", err)
+				}
+			}()
+		}
+
+		c.close(CloseInternalError, "internal error")
+	}()
+
+	callback()
+}
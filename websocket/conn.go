@@ -0,0 +1,311 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 帧类型
+const (
+	ContinuationMessage = 0x0
+	TextMessage         = 0x1
+	BinaryMessage       = 0x2
+	CloseMessage        = 0x8
+	PingMessage         = 0x9
+	PongMessage         = 0xA
+)
+
+// 关闭状态码
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+var (
+	ErrClosed          = errors.New("websocket connection is closed")
+	ErrMessageTooLarge = errors.New("websocket message is too large")
+	errProtocol        = errors.New("websocket protocol error")
+)
+
+// Conn 服务端的 WebSocket 连接，只实现 RFC 6455 的基础帧，不支持扩展（如 permessage-deflate）
+type Conn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	limit   int64
+	timeout time.Duration // 写入超时，客户端停止读取时写入不会一直阻塞
+	mutex   sync.Mutex
+	closed  bool
+}
+
+func newConn(conn net.Conn, limit int64) *Conn {
+	return &Conn{conn: conn, reader: bufio.NewReader(conn), limit: limit}
+}
+
+// ReadMessage
+//
+//	@Description: 读取一条完整的消息，自动合并分片、回复 ping 和 close
+//	@return opcode	TextMessage 或 BinaryMessage
+//	@return err	对方关闭连接时返回 ErrClosed
+func (c *Conn) ReadMessage() (opcode int, message []byte, err error) {
+
+	opcode = -1
+
+	for {
+
+		fin, code, payload, err := c.readFrame()
+		if err != nil {
+			return c.fail(err)
+		}
+
+		switch code {
+		case PingMessage:
+
+			if err = c.write(PongMessage, payload); err != nil {
+				return -1, nil, err
+			}
+
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+
+			status := CloseNormal
+
+			if len(payload) >= 2 {
+				status = int(binary.BigEndian.Uint16(payload))
+			}
+
+			_ = c.WriteClose(status, "")
+
+			return -1, nil, ErrClosed
+		case TextMessage, BinaryMessage:
+
+			// 上一条消息的分片还没有结束
+			if opcode != -1 {
+				return c.fail(errProtocol)
+			}
+
+			opcode = code
+		case ContinuationMessage:
+
+			if opcode == -1 {
+				return c.fail(errProtocol)
+			}
+		default:
+			return c.fail(errProtocol)
+		}
+
+		if c.limit > 0 && int64(len(message)+len(payload)) > c.limit {
+			return c.fail(ErrMessageTooLarge)
+		}
+
+		message = append(message, payload...)
+
+		if fin {
+			break
+		}
+	}
+
+	if opcode == TextMessage && !utf8.Valid(message) {
+		_ = c.WriteClose(CloseInvalidPayload, "invalid utf-8")
+		return -1, nil, errProtocol
+	}
+
+	return opcode, message, nil
+}
+
+// WriteMessage 写入一条不分片的消息
+func (c *Conn) WriteMessage(opcode int, data []byte) error {
+	return c.write(opcode, data)
+}
+
+// WriteClose 发送关闭帧，之后的写入返回 ErrClosed
+func (c *Conn) WriteClose(status int, reason string) error {
+
+	payload := binary.BigEndian.AppendUint16(nil, uint16(status))
+
+	// 控制帧的数据不能超过 125 字节
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+
+	err := c.write(CloseMessage, append(payload, reason...))
+
+	c.mutex.Lock()
+	c.closed = true
+	c.mutex.Unlock()
+
+	return err
+}
+
+// Close 关闭底层连接
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// SetWriteTimeout 每次写入的超时时间，0 为不限制
+func (c *Conn) SetWriteTimeout(timeout time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.timeout = timeout
+}
+
+// SetReadTimeout 读取下一帧的超时时间，Hertz 的连接按每次读取计算
+func (c *Conn) SetReadTimeout(timeout time.Duration) error {
+
+	if conn, ok := c.conn.(interface{ SetReadTimeout(time.Duration) error }); ok {
+		return conn.SetReadTimeout(timeout)
+	}
+
+	if timeout <= 0 {
+		return c.conn.SetReadDeadline(time.Time{})
+	}
+
+	return c.conn.SetReadDeadline(time.Now().Add(timeout))
+}
+
+// fail 协议错误时按 RFC 6455 发送对应的关闭状态码
+func (c *Conn) fail(err error) (int, []byte, error) {
+
+	switch {
+	case errors.Is(err, ErrMessageTooLarge):
+		_ = c.WriteClose(CloseMessageTooBig, "message too big")
+	case errors.Is(err, errProtocol):
+		_ = c.WriteClose(CloseProtocolError, "protocol error")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		err = ErrClosed
+	}
+
+	if errors.Is(err, io.EOF) {
+		err = ErrClosed
+	}
+
+	return -1, nil, err
+}
+
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+
+	header := make([]byte, 2)
+
+	if _, err = io.ReadFull(c.reader, header); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0f)
+
+	// 没有协商扩展，RSV 必须为 0；客户端发送的帧必须掩码
+	if header[0]&0x70 != 0 || header[1]&0x80 == 0 {
+		return false, 0, nil, errProtocol
+	}
+
+	length := int64(header[1] & 0x7f)
+
+	switch length {
+	case 126:
+
+		extended := make([]byte, 2)
+
+		if _, err = io.ReadFull(c.reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+
+		length = int64(binary.BigEndian.Uint16(extended))
+	case 127:
+
+		extended := make([]byte, 8)
+
+		if _, err = io.ReadFull(c.reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+
+		length = int64(binary.BigEndian.Uint64(extended))
+	}
+
+	if opcode >= CloseMessage && (!fin || length > 125) {
+		return false, 0, nil, errProtocol
+	}
+
+	if length < 0 || (c.limit > 0 && length > c.limit) {
+		return false, 0, nil, ErrMessageTooLarge
+	}
+
+	mask := make([]byte, 4)
+
+	if _, err = io.ReadFull(c.reader, mask); err != nil {
+		return false, 0, nil, err
+	}
+
+	payload = make([]byte, length)
+
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+
+	for index := range payload {
+		payload[index] ^= mask[index%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+func (c *Conn) write(opcode int, data []byte) error {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+
+	frame := make([]byte, 0, len(data)+10)
+	frame = append(frame, 0x80|byte(opcode))
+
+	// 服务端发送的帧不掩码
+	switch length := len(data); {
+	case length <= 125:
+		frame = append(frame, byte(length))
+	case length <= 0xffff:
+		frame = binary.BigEndian.AppendUint16(append(frame, 126), uint16(length))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, 127), uint64(length))
+	}
+
+	if c.timeout > 0 {
+		if err := c.deadline(c.timeout); err != nil {
+			return err
+		}
+	}
+
+	if _, err := c.conn.Write(append(frame, data...)); err != nil {
+		return err
+	}
+
+	// Hertz 的连接写入后需要 Flush
+	if flusher, ok := c.conn.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+
+	return nil
+}
+
+// deadline 设置写入超时，Hertz 的连接按每次写入计算
+func (c *Conn) deadline(timeout time.Duration) error {
+
+	if conn, ok := c.conn.(interface{ SetWriteTimeout(time.Duration) error }); ok {
+		return conn.SetWriteTimeout(timeout)
+	}
+
+	return c.conn.SetWriteDeadline(time.Now().Add(timeout))
+}
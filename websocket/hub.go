@@ -0,0 +1,273 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/herhe-com/framework/contracts/http/response"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/redis/go-redis/v9"
+	"github.com/samber/lo"
+)
+
+// Target 推送对象，多个条件同时满足才会收到，全部为空时推送给所有连接
+type Target struct {
+	Users        []string `json:"users,omitempty"`
+	Platform     uint16   `json:"platform,omitempty"`
+	Organization string   `json:"organization,omitempty"`
+}
+
+// envelope 通过 Redis 在节点之间转发的消息，message 已经编码好，各节点直接写入连接
+type envelope struct {
+	Target  Target          `json:"target"`
+	Message json.RawMessage `json:"message"`
+}
+
+type hub struct {
+	mutex   sync.RWMutex
+	clients map[string]*Client            // 连接 ID -> 连接
+	users   map[string]map[string]*Client // 用户 ID -> 连接 ID -> 连接
+
+	subscription sync.Mutex
+	client       *redis.Client
+	pubsub       *redis.PubSub
+}
+
+var (
+	resubscribeDelay    = 100 * time.Millisecond
+	maxResubscribeDelay = 30 * time.Second
+)
+
+var clients = &hub{clients: make(map[string]*Client), users: make(map[string]map[string]*Client)}
+
+// KeyOfChannel 节点之间转发消息的 Redis 频道
+func KeyOfChannel() string {
+	return util.Keys("websocket", "channel")
+}
+
+// Send
+//
+//	@Description: 推送消息，有 Redis 时通过发布订阅转发到所有节点，可以在任意节点或队列消费者中调用
+//	@param target	推送对象
+//	@param event	事件名称，客户端按事件名称处理
+//	@param data	事件数据，编码为 JSON
+func Send(c context.Context, target Target, event string, data any) error {
+
+	message, err := json.Marshal(response.Event[any]{Event: event, Data: data, Timestamp: time.Now().Format(time.RFC3339)})
+	if err != nil {
+		return err
+	}
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		clients.dispatch(target, message)
+		return nil
+	}
+
+	payload, err := json.Marshal(envelope{Target: target, Message: message})
+	if err != nil {
+		return err
+	}
+
+	return cache.Default().Publish(c, KeyOfChannel(), payload).Err()
+}
+
+// ToUsers 推送给指定用户的全部连接
+func ToUsers(c context.Context, users []string, event string, data any) error {
+
+	if len(users) == 0 {
+		return nil
+	}
+
+	return Send(c, Target{Users: users}, event, data)
+}
+
+// ToOrganization 推送给平台下某个组织的全部连接
+func ToOrganization(c context.Context, platform uint16, organization string, event string, data any) error {
+	return Send(c, Target{Platform: platform, Organization: organization}, event, data)
+}
+
+// ToPlatform 推送给某个平台的全部连接
+func ToPlatform(c context.Context, platform uint16, event string, data any) error {
+	return Send(c, Target{Platform: platform}, event, data)
+}
+
+// Broadcast 推送给所有连接
+func Broadcast(c context.Context, event string, data any) error {
+	return Send(c, Target{}, event, data)
+}
+
+func (h *hub) add(client *Client) {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.clients[client.ID] = client
+
+	if h.users[client.User] == nil {
+		h.users[client.User] = make(map[string]*Client)
+	}
+
+	h.users[client.User][client.ID] = client
+}
+
+func (h *hub) remove(client *Client) {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	delete(h.clients, client.ID)
+
+	if connections, ok := h.users[client.User]; ok {
+
+		delete(connections, client.ID)
+
+		if len(connections) == 0 {
+			delete(h.users, client.User)
+		}
+	}
+}
+
+// local 本节点上用户的连接数
+func (h *hub) local(user string) int {
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return len(h.users[user])
+}
+
+// dispatch 写入本节点上符合条件的连接
+func (h *hub) dispatch(target Target, message []byte) {
+
+	h.mutex.RLock()
+
+	matched := make([]*Client, 0)
+
+	if len(target.Users) > 0 {
+		for _, user := range lo.Uniq(target.Users) {
+			for _, client := range h.users[user] {
+				matched = append(matched, client)
+			}
+		}
+	} else {
+		for _, client := range h.clients {
+			matched = append(matched, client)
+		}
+	}
+
+	h.mutex.RUnlock()
+
+	for _, client := range matched {
+
+		if target.Platform > 0 && client.Platform != target.Platform {
+			continue
+		}
+
+		if target.Organization != "" && client.Organization != target.Organization {
+			continue
+		}
+
+		client.enqueue(message)
+	}
+}
+
+// listen 订阅 Redis 频道，切换了 Redis 连接时重新订阅
+func (h *hub) listen(client *redis.Client) error {
+
+	h.subscription.Lock()
+	defer h.subscription.Unlock()
+
+	if h.client == client {
+		return nil
+	}
+
+	if h.pubsub != nil {
+		_ = h.pubsub.Close()
+	}
+
+	h.client, h.pubsub = nil, nil
+
+	pubsub := client.Subscribe(context.Background(), KeyOfChannel())
+
+	// 等待订阅确认，保证返回后不会漏掉其他节点的消息
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		_ = pubsub.Close()
+		return err
+	}
+
+	h.client, h.pubsub = client, pubsub
+
+	go h.receive(client, pubsub)
+
+	return nil
+}
+
+// receive 转发其他节点的消息，订阅意外关闭时重新订阅，切换 Redis 连接关闭的旧订阅直接退出
+func (h *hub) receive(client *redis.Client, pubsub *redis.PubSub) {
+
+	for message := range pubsub.Channel() {
+
+		var item envelope
+
+		if err := json.Unmarshal([]byte(message.Payload), &item); err != nil {
+			hlog.Warnf("websocket message cannot be decoded: %v", err)
+			continue
+		}
+
+		h.dispatch(item.Target, item.Message)
+	}
+
+	h.subscription.Lock()
+
+	current := h.pubsub == pubsub
+
+	if current {
+		h.client, h.pubsub = nil, nil
+	}
+
+	h.subscription.Unlock()
+
+	if !current {
+		return
+	}
+
+	hlog.Warnf("websocket subscription is closed, resubscribing")
+
+	h.resubscribe(client)
+}
+
+// resubscribe 按指数退避重新订阅，期间新的连接已经完成订阅时停止
+func (h *hub) resubscribe(client *redis.Client) {
+
+	delay := resubscribeDelay
+
+	for {
+
+		h.subscription.Lock()
+		subscribed := h.pubsub != nil
+		h.subscription.Unlock()
+
+		if subscribed {
+			return
+		}
+
+		err := h.listen(client)
+
+		// 应用退出时 Redis 连接已经关闭，不再重试
+		if err == nil || errors.Is(err, redis.ErrClosed) {
+			return
+		}
+
+		hlog.Warnf("websocket resubscribe: %v, retry in %s", err, delay)
+
+		time.Sleep(delay)
+
+		delay = min(delay*2, maxResubscribeDelay)
+	}
+}
@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/support/util"
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyOfPresence 用户在线连接的有序集合，成员为连接 ID，分数为过期时间
+func KeyOfPresence(user string) string {
+	return util.Keys("websocket", "presence", user)
+}
+
+// Online
+//
+//	@Description: 查询用户是否在线，有 Redis 时统计所有节点的连接，否则只统计本节点
+//	@param users	用户 ID
+//	@return map	用户 ID -> 是否在线
+func Online(c context.Context, users ...string) (map[string]bool, error) {
+
+	counts, err := Connections(c, users...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]bool, len(counts))
+
+	for user, count := range counts {
+		results[user] = count > 0
+	}
+
+	return results, nil
+}
+
+// Connections 用户当前的连接数，同一用户可以在多个设备、多个标签页同时连接
+func Connections(c context.Context, users ...string) (map[string]int64, error) {
+
+	results := make(map[string]int64, len(users))
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+
+		for _, user := range users {
+			results[user] = int64(clients.local(user))
+		}
+
+		return results, nil
+	}
+
+	// 节点异常退出时来不及删除连接，按过期时间统计
+	expired := strconv.FormatInt(time.Now().Unix(), 10)
+
+	commands := make(map[string]*redis.IntCmd, len(users))
+
+	_, err := cache.Default().Pipelined(c, func(pipe redis.Pipeliner) error {
+
+		for _, user := range users {
+			commands[user] = pipe.ZCount(c, KeyOfPresence(user), "("+expired, "+inf")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for user, command := range commands {
+		results[user] = command.Val()
+	}
+
+	return results, nil
+}
+
+// presence 连接建立和心跳时续期，TTL 需要大于心跳间隔
+func presence(c context.Context, client *Client, ttl time.Duration) error {
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return nil
+	}
+
+	key := KeyOfPresence(client.User)
+	now := time.Now()
+
+	_, err := cache.Default().TxPipelined(c, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(c, key, redis.Z{Score: float64(now.Add(ttl).Unix()), Member: client.ID})
+		pipe.ZRemRangeByScore(c, key, "-inf", strconv.FormatInt(now.Unix(), 10))
+		pipe.Expire(c, key, ttl)
+		return nil
+	})
+
+	return err
+}
+
+// absence 连接断开时移除
+func absence(c context.Context, client *Client) error {
+
+	cache, ok := facades.OptionalRedis()
+	if !ok {
+		return nil
+	}
+
+	return cache.Default().ZRem(c, KeyOfPresence(client.User), client.ID).Err()
+}
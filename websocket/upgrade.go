@@ -0,0 +1,246 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	"github.com/cloudwego/hertz/pkg/network"
+	"github.com/herhe-com/framework/auth"
+	contractauth "github.com/herhe-com/framework/contracts/auth"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/http"
+	"github.com/herhe-com/framework/ratelimit"
+)
+
+// ProtocolOfBearer 浏览器不能设置 WebSocket 的请求头，可以通过子协议传递 token：new WebSocket(url, ["bearer", token])
+const ProtocolOfBearer = "bearer"
+
+// Config 连接的回调，回调在连接自己的 goroutine 中执行，panic 时断开当前连接
+type Config struct {
+	OnConnect func(c context.Context, client *Client)
+	OnMessage func(c context.Context, client *Client, opcode int, message []byte)
+	OnClose   func(c context.Context, client *Client)
+
+	// Limit 每个连接接收消息的频率，默认读取 websocket.limit，超出时回复 error 事件并丢弃消息
+	Limit *ratelimit.Limit
+}
+
+type options struct {
+	ping         time.Duration
+	writeTimeout time.Duration
+	maxMessage   int64
+	buffer       int
+	limit        ratelimit.Limit
+}
+
+// Upgrade
+//
+//	@Description: 认证后升级为 WebSocket 连接，token 依次从 Authorization 请求头、bearer 子协议中读取，开启 websocket.query_token 后再读取 token 查询参数
+//	@param configs	连接的回调
+func Upgrade(configs ...Config) app.HandlerFunc {
+
+	var config Config
+
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+
+	return func(c context.Context, ctx *app.RequestContext) {
+
+		key, ok := handshake(ctx)
+		if !ok {
+			ctx.Abort()
+			http.BadRequest(ctx, "websocket handshake is required")
+			return
+		}
+
+		token, protocol := credentials(ctx)
+
+		claims, ok := authenticate(c, ctx, token)
+		if !ok {
+			ctx.Abort()
+			http.Unauthorized(ctx)
+			return
+		}
+
+		if cache, exists := facades.OptionalRedis(); exists {
+			if err := clients.listen(cache.Default()); err != nil {
+				hlog.CtxErrorf(c, "websocket subscribe: %v", err)
+				ctx.Abort()
+				http.InternalServerError(ctx)
+				return
+			}
+		}
+
+		id := make([]byte, 16)
+
+		if _, err := rand.Read(id); err != nil {
+			ctx.Abort()
+			http.InternalServerError(ctx)
+			return
+		}
+
+		options := configure(config)
+
+		client := &Client{
+			ID:       hex.EncodeToString(id),
+			User:     claims.Subject,
+			Platform: auth.Platform(ctx),
+			Claims:   claims,
+			send:     make(chan []byte, options.buffer),
+			done:     make(chan struct{}),
+		}
+
+		if organization := auth.Organization(ctx); organization.Valid {
+			client.Organization = organization.String
+		}
+
+		ctx.SetStatusCode(101)
+		ctx.Response.Header.SetNoDefaultContentType(true)
+		ctx.Response.Header.Set("Upgrade", "websocket")
+		ctx.Response.Header.Set("Connection", "Upgrade")
+		ctx.Response.Header.Set("Sec-WebSocket-Accept", accept(key))
+
+		if protocol {
+			ctx.Response.Header.Set("Sec-WebSocket-Protocol", ProtocolOfBearer)
+		}
+
+		// 请求结束后 RequestContext 会被复用，连接中只使用上面复制出来的值
+		serve := context.WithoutCancel(c)
+
+		ctx.Hijack(func(conn network.Conn) {
+			client.serve(serve, conn, config, options)
+		})
+	}
+}
+
+func configure(config Config) options {
+
+	cfg := facades.Config()
+
+	result := options{
+		ping:         time.Duration(cfg.GetInt("websocket.ping", 30)) * time.Second,
+		writeTimeout: time.Duration(cfg.GetInt("websocket.write_timeout", 10)) * time.Second,
+		maxMessage:   cfg.GetInt64("websocket.max_message", 65536),
+		buffer:       cfg.GetInt("websocket.buffer", 256),
+		limit: ratelimit.Limit{
+			Algorithm: ratelimit.TokenBucket,
+			Rate:      cfg.GetInt64("websocket.limit.rate", 20),
+			Period:    time.Duration(cfg.GetInt("websocket.limit.period", 1)) * time.Second,
+		},
+	}
+
+	if config.Limit != nil {
+		result.limit = *config.Limit
+	}
+
+	if result.ping <= 0 {
+		result.ping = 30 * time.Second
+	}
+
+	if result.writeTimeout <= 0 {
+		result.writeTimeout = 10 * time.Second
+	}
+
+	if result.buffer <= 0 {
+		result.buffer = 256
+	}
+
+	return result
+}
+
+// handshake 校验 RFC 6455 的握手请求，返回 Sec-WebSocket-Key
+func handshake(ctx *app.RequestContext) (string, bool) {
+
+	if !ctx.IsGet() || !strings.EqualFold(string(ctx.GetHeader("Upgrade")), "websocket") || string(ctx.GetHeader("Sec-WebSocket-Version")) != "13" {
+		return "", false
+	}
+
+	upgrade := false
+
+	for _, item := range strings.Split(string(ctx.GetHeader("Connection")), ",") {
+		if strings.EqualFold(strings.TrimSpace(item), "upgrade") {
+			upgrade = true
+		}
+	}
+
+	key := string(ctx.GetHeader("Sec-WebSocket-Key"))
+
+	if decoded, err := base64.StdEncoding.DecodeString(key); !upgrade || err != nil || len(decoded) != 16 {
+		return "", false
+	}
+
+	return key, true
+}
+
+// accept 握手响应的 Sec-WebSocket-Accept
+func accept(key string) string {
+
+	hash := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// credentials 读取 token，protocol 为 true 时需要在响应中确认 bearer 子协议
+func credentials(ctx *app.RequestContext) (token string, protocol bool) {
+
+	if token = string(ctx.GetHeader(auth.JwtOfAuthorization)); token != "" {
+		return token, false
+	}
+
+	protocols := strings.Split(string(ctx.GetHeader("Sec-WebSocket-Protocol")), ",")
+
+	for index, item := range protocols {
+		if strings.TrimSpace(item) == ProtocolOfBearer && index+1 < len(protocols) {
+			return strings.TrimSpace(protocols[index+1]), true
+		}
+	}
+
+	// 查询参数会出现在访问日志和代理日志中，需要明确开启
+	if facades.Config().GetBool("websocket.query_token") {
+		return ctx.Query("token"), false
+	}
+
+	return "", false
+}
+
+// authenticate 校验 token 并与 Jwt 中间件一样通过 auth.Authenticate 写入上下文，再执行 auth.callback.jwt 由应用写入组织等信息
+func authenticate(c context.Context, ctx *app.RequestContext, token string) (*contractauth.Claims, bool) {
+
+	// 路由已经使用 Jwt 中间件并通过了校验
+	if !auth.Check(ctx) {
+
+		if token == "" {
+			return nil, false
+		}
+
+		var claims contractauth.Claims
+
+		// 过期的 token 不在这里刷新，客户端需要先通过接口刷新后重新连接
+		if _, err := auth.CheckJWToken(&claims, token); err != nil {
+			return nil, false
+		}
+
+		auth.Authenticate(ctx, &claims)
+		auth.Callback(c, ctx, "jwt")
+	}
+
+	claims := auth.Claims(ctx)
+
+	if claims == nil || claims.Subject == "" {
+		return nil, false
+	}
+
+	if _, ok := facades.OptionalRedis(); ok && auth.CheckBlacklist(c, auth.BlacklistOfJwtName(ctx)) {
+		return nil, false
+	}
+
+	return claims, true
+}
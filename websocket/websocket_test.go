@@ -0,0 +1,566 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/herhe-com/framework/auth"
+	contractconfig "github.com/herhe-com/framework/contracts/config"
+	"github.com/herhe-com/framework/contracts/database"
+	"github.com/herhe-com/framework/contracts/http/response"
+	"github.com/herhe-com/framework/facades"
+	"github.com/herhe-com/framework/ratelimit"
+	"github.com/redis/go-redis/v9"
+)
+
+type fakeConfig struct {
+	values map[string]any
+}
+
+func (f fakeConfig) Env(key string, defaultValue ...any) any {
+	return f.Get(key, defaultValue...)
+}
+
+func (f fakeConfig) Add(name string, configuration map[string]any) {}
+
+func (f fakeConfig) Set(key string, configuration any) {}
+
+func (f fakeConfig) Get(key string, defaultValue ...any) any {
+	if value, ok := f.values[key]; ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetString(key string, defaultValue ...string) string {
+	if value, ok := f.values[key]; ok {
+		return fmt.Sprint(value)
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return ""
+}
+
+func (f fakeConfig) GetStrings(key string, defaultValue ...[]string) []string {
+	if value, ok := f.values[key].([]string); ok {
+		return value
+	}
+
+	return nil
+}
+
+func (f fakeConfig) GetMaps(key string, defaultValue ...map[string]any) map[string]any {
+	return nil
+}
+
+func (f fakeConfig) GetInt(key string, defaultValue ...int) int {
+	if value, ok := f.values[key].(int); ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return 0
+}
+
+func (f fakeConfig) GetInt64(key string, defaultValue ...int64) int64 {
+	if value, ok := f.values[key].(int64); ok {
+		return value
+	}
+
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+
+	return 0
+}
+
+func (f fakeConfig) GetBool(key string, defaultValue ...bool) bool {
+	value, _ := f.values[key].(bool)
+	return value
+}
+
+func (f fakeConfig) IsSet(key string) bool {
+	_, ok := f.values[key]
+	return ok
+}
+
+type fakeRedis struct {
+	client *redis.Client
+}
+
+func (f fakeRedis) Default() *redis.Client {
+	return f.client
+}
+
+func (f fakeRedis) Channel(name string) (*redis.Client, error) {
+	return f.client, nil
+}
+
+func setup(t *testing.T, values map[string]any) {
+	original := facades.Container()
+	facades.SetContainer(&facades.Services{})
+	t.Cleanup(func() {
+		facades.SetContainer(original)
+	})
+
+	configuration := map[string]any{
+		"app.name":   "framework",
+		"jwt.sub":    "api",
+		"jwt.secret": "test-secret",
+	}
+
+	for key, value := range values {
+		configuration[key] = value
+	}
+
+	facades.Register[contractconfig.Application](fakeConfig{values: configuration})
+
+	cache := miniredis.RunT(t)
+	facades.Register[database.Redis](fakeRedis{client: redis.NewClient(&redis.Options{Addr: cache.Addr()})})
+}
+
+// listen 启动 Hertz 服务并返回地址
+func listen(t *testing.T, handler app.HandlerFunc) string {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := server.New(server.WithListener(ln))
+	h.GET("/ws", handler)
+
+	go h.Run()
+
+	t.Cleanup(func() {
+		_ = h.Shutdown(context.Background())
+	})
+
+	return ln.Addr().String()
+}
+
+type peer struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dial 发起握手并返回响应头
+func dial(t *testing.T, address string, headers map[string]string) (*peer, string) {
+
+	var conn net.Conn
+	var err error
+
+	for range 50 {
+		if conn, err = net.Dial("tcp", address); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	request := "GET /ws HTTP/1.1\r\nHost: " + address + "\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+
+	for key, value := range headers {
+		request += key + ": " + value + "\r\n"
+	}
+
+	if _, err = conn.Write([]byte(request + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	reader := bufio.NewReader(conn)
+
+	var header strings.Builder
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if line == "\r\n" {
+			break
+		}
+
+		header.WriteString(line)
+	}
+
+	return &peer{conn: conn, reader: reader}, header.String()
+}
+
+// write 客户端发送的帧需要掩码
+func (p *peer) write(t *testing.T, fin bool, opcode byte, payload []byte) {
+
+	first := opcode
+
+	if fin {
+		first |= 0x80
+	}
+
+	mask := []byte{1, 2, 3, 4}
+	frame := append([]byte{first, 0x80 | byte(len(payload))}, mask...)
+
+	for index, item := range payload {
+		frame = append(frame, item^mask[index%4])
+	}
+
+	if _, err := p.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (p *peer) read(t *testing.T) (byte, []byte) {
+
+	header := make([]byte, 2)
+
+	if _, err := io.ReadFull(p.reader, header); err != nil {
+		t.Fatal(err)
+	}
+
+	length := int(header[1] & 0x7f)
+
+	if length == 126 {
+		extended := make([]byte, 2)
+		_, _ = io.ReadFull(p.reader, extended)
+		length = int(binary.BigEndian.Uint16(extended))
+	}
+
+	payload := make([]byte, length)
+
+	if _, err := io.ReadFull(p.reader, payload); err != nil {
+		t.Fatal(err)
+	}
+
+	return header[0] & 0x0f, payload
+}
+
+func (p *peer) event(t *testing.T) response.Event[json.RawMessage] {
+
+	for {
+		opcode, payload := p.read(t)
+
+		// 跳过服务端的心跳
+		if opcode == PingMessage {
+			continue
+		}
+
+		var event response.Event[json.RawMessage]
+
+		if opcode != TextMessage || json.Unmarshal(payload, &event) != nil {
+			t.Fatalf("expected event, got opcode %d %q", opcode, payload)
+		}
+
+		return event
+	}
+}
+
+func TestUpgradeFanOutAndPresence(t *testing.T) {
+	setup(t, nil)
+
+	token, err := auth.NewJWToken("user-1", 5, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address := listen(t, Upgrade(Config{
+		OnMessage: func(c context.Context, client *Client, opcode int, message []byte) {
+			_ = client.Send("echo", string(message))
+		},
+		Limit: &ratelimit.Limit{Rate: 2, Period: time.Minute},
+	}))
+
+	p, header := dial(t, address, map[string]string{"Sec-WebSocket-Protocol": "bearer, " + token})
+
+	if lower := strings.ToLower(header); !strings.HasPrefix(header, "HTTP/1.1 101") || !strings.Contains(lower, "sec-websocket-accept: s3pplmbitxaq9kygzzhzrbk+xoo=") || !strings.Contains(lower, "sec-websocket-protocol: bearer") {
+		t.Fatalf("expected switching protocols, got %q", header)
+	}
+
+	p.write(t, true, TextMessage, []byte("hello"))
+
+	if event := p.event(t); event.Event != "echo" || string(event.Data) != `"hello"` {
+		t.Fatalf("expected echo, got %+v", event)
+	}
+
+	c := context.Background()
+
+	online, err := Online(c, "user-1", "user-2")
+	if err != nil || !online["user-1"] || online["user-2"] {
+		t.Fatalf("expected only user-1 online, got %v %v", online, err)
+	}
+
+	// 发给其他用户、其他平台的消息不会收到，按发送顺序到达的第一条就是发给 user-1 的
+	_ = ToUsers(c, []string{"user-2"}, "notice", "other")
+	_ = ToPlatform(c, 9, "notice", "other")
+	_ = ToUsers(c, []string{"user-1"}, "notice", "mine")
+
+	if event := p.event(t); event.Event != "notice" || string(event.Data) != `"mine"` {
+		t.Fatalf("expected notice for user-1, got %+v", event)
+	}
+
+	p.write(t, true, PingMessage, []byte("ping"))
+
+	if opcode, payload := p.read(t); opcode != PongMessage || string(payload) != "ping" {
+		t.Fatalf("expected pong, got %d %q", opcode, payload)
+	}
+
+	p.write(t, true, TextMessage, []byte("second"))
+	_ = p.event(t)
+
+	p.write(t, true, TextMessage, []byte("third"))
+
+	if event := p.event(t); event.Event != "error" || !strings.Contains(string(event.Data), "42900") {
+		t.Fatalf("expected rate limit error, got %+v", event)
+	}
+
+	p.write(t, true, CloseMessage, binary.BigEndian.AppendUint16(nil, CloseNormal))
+
+	if opcode, _ := p.read(t); opcode != CloseMessage {
+		t.Fatalf("expected close reply, got %d", opcode)
+	}
+
+	for range 50 {
+		if online, _ = Online(c, "user-1"); !online["user-1"] {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatal("expected user-1 offline after close")
+}
+
+func TestUpgradeRejectsRequests(t *testing.T) {
+	setup(t, nil)
+
+	handler := Upgrade()
+
+	ctx := app.NewContext(0)
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI("/ws?token=invalid")
+	ctx.Request.Header.Set("Upgrade", "websocket")
+	ctx.Request.Header.Set("Connection", "keep-alive, Upgrade")
+	ctx.Request.Header.Set("Sec-WebSocket-Version", "13")
+	ctx.Request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+
+	handler(context.Background(), ctx)
+
+	if ctx.Hijacked() || !strings.Contains(string(ctx.Response.Body()), "40100") {
+		t.Fatalf("expected unauthorized, got %d %s", ctx.Response.StatusCode(), ctx.Response.Body())
+	}
+
+	ctx = app.NewContext(0)
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI("/ws")
+
+	handler(context.Background(), ctx)
+
+	if ctx.Hijacked() || !strings.Contains(string(ctx.Response.Body()), "40000") {
+		t.Fatalf("expected bad request for plain http, got %s", ctx.Response.Body())
+	}
+}
+
+func TestCredentialsIgnoreQueryTokenByDefault(t *testing.T) {
+	setup(t, nil)
+
+	ctx := app.NewContext(0)
+	ctx.Request.SetRequestURI("/ws?token=secret")
+
+	if token, _ := credentials(ctx); token != "" {
+		t.Fatalf("expected query token to be ignored, got %q", token)
+	}
+
+	setup(t, map[string]any{"websocket.query_token": true})
+
+	if token, _ := credentials(ctx); token != "secret" {
+		t.Fatalf("expected query token when enabled, got %q", token)
+	}
+}
+
+func TestHubResubscribesAfterSubscriptionCloses(t *testing.T) {
+	setup(t, nil)
+
+	cache, _ := facades.OptionalRedis()
+
+	h := &hub{clients: make(map[string]*Client), users: make(map[string]map[string]*Client)}
+
+	if err := h.listen(cache.Default()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		h.subscription.Lock()
+		pubsub := h.pubsub
+		h.client, h.pubsub = nil, nil
+		h.subscription.Unlock()
+
+		if pubsub != nil {
+			_ = pubsub.Close()
+		}
+	})
+
+	h.subscription.Lock()
+	closed := h.pubsub
+	h.subscription.Unlock()
+
+	_ = closed.Close()
+
+	client := &Client{ID: "1", User: "user-1", send: make(chan []byte, 1), done: make(chan struct{})}
+	h.add(client)
+
+	payload, _ := json.Marshal(envelope{Message: json.RawMessage(`{"event":"ping"}`)})
+
+	for range 100 {
+
+		h.subscription.Lock()
+		resubscribed := h.pubsub != nil && h.pubsub != closed
+		h.subscription.Unlock()
+
+		if resubscribed {
+
+			if err := cache.Default().Publish(context.Background(), KeyOfChannel(), payload).Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case message := <-client.send:
+				if string(message) != `{"event":"ping"}` {
+					t.Fatalf("unexpected message %s", message)
+				}
+				return
+			case <-time.After(2 * time.Second):
+				t.Fatal("expected message after resubscribing")
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatal("expected hub to resubscribe")
+}
+
+func TestConnFrames(t *testing.T) {
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	conn := newConn(server, 8)
+	p := &peer{conn: client, reader: bufio.NewReader(client)}
+
+	type result struct {
+		opcode  int
+		message []byte
+		err     error
+	}
+
+	results := make(chan result, 1)
+
+	read := func() {
+		opcode, message, err := conn.ReadMessage()
+		results <- result{opcode, message, err}
+	}
+
+	// 分片之间可以穿插控制帧
+	go read()
+
+	p.write(t, false, TextMessage, []byte("hel"))
+	p.write(t, true, PingMessage, nil)
+
+	if opcode, _ := p.read(t); opcode != PongMessage {
+		t.Fatalf("expected pong between fragments, got %d", opcode)
+	}
+
+	p.write(t, true, ContinuationMessage, []byte("lo"))
+
+	if item := <-results; item.err != nil || item.opcode != TextMessage || string(item.message) != "hello" {
+		t.Fatalf("expected merged message, got %+v", item)
+	}
+
+	// 超过长度限制时以 1009 关闭
+	go read()
+
+	p.write(t, true, BinaryMessage, []byte("too large message"))
+
+	if opcode, payload := p.read(t); opcode != CloseMessage || binary.BigEndian.Uint16(payload) != CloseMessageTooBig {
+		t.Fatalf("expected close 1009, got %d %v", opcode, payload)
+	}
+
+	if item := <-results; item.err != ErrMessageTooLarge {
+		t.Fatalf("expected message too large, got %v", item.err)
+	}
+
+	if err := conn.WriteMessage(TextMessage, []byte("late")); err != ErrClosed {
+		t.Fatalf("expected writes after close to fail, got %v", err)
+	}
+}
+
+func TestSlowConsumerDoesNotBlockEnqueue(t *testing.T) {
+
+	server, client := net.Pipe()
+	defer client.Close()
+
+	conn := newConn(server, 1024)
+	conn.SetWriteTimeout(50 * time.Millisecond)
+
+	c := &Client{ID: "1", User: "user-1", conn: conn, send: make(chan []byte, 1), done: make(chan struct{}), stopped: make(chan struct{})}
+
+	go c.write(context.Background(), time.Hour)
+
+	// 对端不读取，第一条消息阻塞在写入，第二条填满队列，第三条触发断开
+	finished := make(chan struct{})
+
+	go func() {
+		for range 3 {
+			c.enqueue([]byte("message"))
+			time.Sleep(10 * time.Millisecond)
+		}
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("expected enqueue not to block on a stalled peer")
+	}
+
+	select {
+	case <-c.done:
+	default:
+		t.Fatal("expected slow consumer to be closed")
+	}
+
+	select {
+	case <-c.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("expected writer to give up after write timeout")
+	}
+}